@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+)
+
+func TestAPIErrorsAreTyped(t *testing.T) {
+	testCases := []struct {
+		name           string
+		statusCode     int
+		body           string
+		isNotFound     bool
+		isUnauthorized bool
+		isRateLimited  bool
+		message        string
+	}{
+		{
+			name:       "Missing section",
+			statusCode: http.StatusNotFound,
+			body:       `{"errors":[{"message":"section: Unknown object: 123","help":"For more information on API status codes..."}]}`,
+			isNotFound: true,
+			message:    "section: Unknown object: 123",
+		},
+		{
+			name:           "Expired token",
+			statusCode:     http.StatusUnauthorized,
+			body:           `{"errors":[{"message":"Not Authorized"}]}`,
+			isUnauthorized: true,
+			message:        "Not Authorized",
+		},
+		{
+			name:          "Rate limited",
+			statusCode:    http.StatusTooManyRequests,
+			body:          `{"errors":[{"message":"You have made too many requests recently."}]}`,
+			isRateLimited: true,
+			message:       "too many requests",
+		},
+		{
+			name:       "Non-JSON body",
+			statusCode: http.StatusBadRequest,
+			body:       "bad request",
+			message:    "bad request",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.statusCode)
+				w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			client := &asana.Client{Client: server.Client(), Token: "dummy_token", BaseURL: server.URL}
+			err := client.MoveTaskToSection(context.Background(), asana.SectionMove{SectionGID: "section_1", TaskGID: "task_1"})
+
+			var apiErr *asana.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Expected an *asana.APIError, got %T: %v", err, err)
+			}
+			if apiErr.StatusCode != tc.statusCode {
+				t.Errorf("Expected status %d, got %d", tc.statusCode, apiErr.StatusCode)
+			}
+			if apiErr.Path != "/sections/section_1/addTask" {
+				t.Errorf("Expected request path to be recorded, got '%s'", apiErr.Path)
+			}
+			if !strings.Contains(err.Error(), tc.message) {
+				t.Errorf("Expected error to mention %q, got %q", tc.message, err.Error())
+			}
+
+			if asana.IsNotFound(err) != tc.isNotFound {
+				t.Errorf("IsNotFound: expected %v", tc.isNotFound)
+			}
+			if asana.IsUnauthorized(err) != tc.isUnauthorized {
+				t.Errorf("IsUnauthorized: expected %v", tc.isUnauthorized)
+			}
+			if asana.IsRateLimited(err) != tc.isRateLimited {
+				t.Errorf("IsRateLimited: expected %v", tc.isRateLimited)
+			}
+		})
+	}
+}
+
+func TestErrorHintForExpiredToken(t *testing.T) {
+	err := &asana.APIError{StatusCode: http.StatusUnauthorized, Method: http.MethodGet, Path: "/users/me"}
+	hint := core.ErrorHint(err)
+	if !strings.Contains(hint, "developer-console") {
+		t.Errorf("Expected hint to point at the developer console, got %q", hint)
+	}
+	if !strings.Contains(hint, "auth add") || !strings.Contains(hint, "login") || !strings.Contains(hint, "--profile") {
+		t.Errorf("Expected hint to mention stored credentials, got %q", hint)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/auth"
+	"github.com/dackerman/asana-tasks-sorter/internal/output"
+	"github.com/dackerman/asana-tasks-sorter/internal/ui"
+)
+
+// loginTimeout is how long login waits for the user to grant access in the browser
+const loginTimeout = 5 * time.Minute
+
+// runAuth manages the credentials stored for each profile
+func runAuth(args []string, store auth.Store, profile string, timeout time.Duration) {
+	if len(args) == 0 {
+		exitWithError(output.FormatText, nil, fmt.Errorf("auth needs a subcommand: add, list or remove"))
+	}
+	switch args[0] {
+	case "add":
+		runAuthAdd(args[1:], store, profile, timeout)
+	case "list":
+		runAuthList(store, profile)
+	case "remove":
+		runAuthRemove(args[1:], store, profile)
+	default:
+		exitWithError(output.FormatText, nil, fmt.Errorf("unknown auth subcommand '%s': use add, list or remove", args[0]))
+	}
+}
+
+// runLogin signs in with an Asana OAuth app; it is short for auth add --oauth
+func runLogin(args []string, store auth.Store, profile string, timeout time.Duration) {
+	runAuthAdd(append([]string{"--oauth"}, args...), store, profile, timeout)
+}
+
+// runAuthAdd stores a personal access token, or the tokens of an OAuth login, for a profile
+func runAuthAdd(args []string, store auth.Store, profile string, timeout time.Duration) {
+	addFlags := flag.NewFlagSet("auth add", flag.ExitOnError)
+	profileFlag := addFlags.String("profile", profile, "Name of the profile to store the credentials for")
+	oauth := addFlags.Bool("oauth", false, "Log in through an Asana OAuth app in the browser instead of pasting a personal access token")
+	clientID := addFlags.String("client-id", os.Getenv("ASANA_CLIENT_ID"), "Client ID of your Asana OAuth app (default $ASANA_CLIENT_ID)")
+	clientSecret := addFlags.String("client-secret", os.Getenv("ASANA_CLIENT_SECRET"), "Client secret of your Asana OAuth app, if it has one (default $ASANA_CLIENT_SECRET)")
+	redirectURL := addFlags.String("redirect-url", auth.DefaultRedirectURL, "Redirect URL registered for the app; the sorter listens on it during login")
+	addFlags.Parse(args)
+
+	if err := auth.ValidateProfile(*profileFlag); err != nil {
+		exitWithError(output.FormatText, nil, err)
+	}
+
+	var creds auth.Credentials
+	if *oauth {
+		if *clientID == "" {
+			exitWithError(output.FormatText, nil, fmt.Errorf("login needs the client ID of an Asana OAuth app: pass --client-id or set ASANA_CLIENT_ID"))
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		ctx, cancel := context.WithTimeout(ctx, loginTimeout)
+		defer cancel()
+
+		conf := auth.Config{ClientID: *clientID, ClientSecret: *clientSecret, RedirectURL: *redirectURL}
+		token, conf, err := auth.Login(ctx, conf, func(authURL string) {
+			fmt.Println(ui.Info("Opening your browser to log in to Asana. If it doesn't open, visit:"))
+			fmt.Println(authURL)
+			openBrowser(authURL)
+		})
+		if err != nil {
+			exitWithError(output.FormatText, nil, err)
+		}
+		creds = auth.NewCredentials(conf, token)
+	} else {
+		fmt.Printf("Personal access token for profile '%s': ", *profileFlag)
+		line, err := readSecret()
+		if err != nil && line == "" {
+			exitWithError(output.FormatText, nil, fmt.Errorf("failed to read the token: %w", err))
+		}
+		creds.AccessToken = strings.TrimSpace(line)
+		if creds.AccessToken == "" {
+			exitWithError(output.FormatText, nil, fmt.Errorf("no token given"))
+		}
+	}
+
+	// Check the token works before storing it, and remember whose it is
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	user, err := asana.NewClient(creds.AccessToken).GetCurrentUser(ctx)
+	if err != nil {
+		exitWithError(output.FormatText, nil, fmt.Errorf("the token doesn't work: %w", err))
+	}
+	creds.User = user.Name
+
+	if err := store.Save(*profileFlag, creds); err != nil {
+		exitWithError(output.FormatText, nil, err)
+	}
+	fmt.Printf("%s %s %s %s\n", ui.Success("Stored credentials of"), ui.Important(user.Name),
+		ui.Success("as profile"), ui.Important(*profileFlag))
+	fmt.Println(ui.Info("Credentials are kept in " + store.Description()))
+}
+
+// readSecret reads a line from stdin, without echoing it when stdin is a terminal
+func readSecret() (string, error) {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 && stty("-echo") == nil {
+		done := make(chan struct{})
+		defer func() {
+			close(done)
+			stty("echo")
+			fmt.Println()
+		}()
+
+		// Turn echo back on if the user gives up with Ctrl-C
+		interrupted := make(chan os.Signal, 1)
+		signal.Notify(interrupted, os.Interrupt)
+		defer signal.Stop(interrupted)
+		go func() {
+			select {
+			case <-interrupted:
+				stty("echo")
+				fmt.Println()
+				os.Exit(1)
+			case <-done:
+			}
+		}()
+	}
+	return bufio.NewReader(os.Stdin).ReadString('\n')
+}
+
+// stty changes the settings of the terminal on stdin
+func stty(setting string) error {
+	cmd := exec.Command("stty", setting)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
+
+// runAuthList shows the stored profiles, marking the one in use
+func runAuthList(store auth.Store, profile string) {
+	profiles, err := store.Profiles()
+	if err != nil {
+		exitWithError(output.FormatText, nil, err)
+	}
+	if len(profiles) == 0 {
+		fmt.Println(ui.Info("No profiles stored; add one with 'asana-tasks-sorter auth add'"))
+		return
+	}
+
+	for _, name := range profiles {
+		marker := " "
+		if name == profile {
+			marker = "*"
+		}
+		creds, err := store.Load(name)
+		if err != nil {
+			fmt.Printf("%s %s  %s\n", marker, name, ui.Error(err.Error()))
+			continue
+		}
+		kind := "personal access token"
+		if creds.IsOAuth() {
+			kind = "OAuth"
+		}
+		fmt.Printf("%s %s  %s (%s)\n", marker, ui.Important(name), creds.User, kind)
+	}
+	fmt.Println(ui.Info("Stored in " + store.Description()))
+	if os.Getenv("ASANA_ACCESS_TOKEN") != "" {
+		fmt.Println(ui.Info("ASANA_ACCESS_TOKEN is set and is used instead of any profile"))
+	}
+}
+
+// runAuthRemove deletes the credentials of a profile
+func runAuthRemove(args []string, store auth.Store, profile string) {
+	removeFlags := flag.NewFlagSet("auth remove", flag.ExitOnError)
+	profileFlag := removeFlags.String("profile", profile, "Name of the profile to remove")
+	removeFlags.Parse(args)
+	if removeFlags.NArg() > 0 {
+		*profileFlag = removeFlags.Arg(0)
+	}
+
+	err := store.Delete(*profileFlag)
+	if errors.Is(err, auth.ErrNoCredentials) {
+		exitWithError(output.FormatText, nil, fmt.Errorf("there is no profile '%s'", *profileFlag))
+	}
+	if err != nil {
+		exitWithError(output.FormatText, nil, err)
+	}
+	fmt.Printf("%s %s\n", ui.Success("Removed profile"), ui.Important(*profileFlag))
+}
+
+// newTokenSource returns where the client gets its access token: the
+// ASANA_ACCESS_TOKEN environment variable if it is set, otherwise the
+// credentials stored for the profile. The store is only opened when needed,
+// since looking in the keyring may ask the user to unlock it.
+func newTokenSource(openStore func() auth.Store, profile string) (asana.TokenSource, error) {
+	if accessToken := os.Getenv("ASANA_ACCESS_TOKEN"); accessToken != "" {
+		return asana.StaticToken(accessToken), nil
+	}
+
+	store := openStore()
+	creds, err := store.Load(profile)
+	if errors.Is(err, auth.ErrNoCredentials) && profile == auth.DefaultProfile {
+		// Credentials saved by versions before profiles become the default profile
+		legacyPath := auth.LegacyCredentialsPath()
+		imported, importErr := auth.ImportLegacyCredentials(store, legacyPath)
+		if importErr != nil {
+			return nil, importErr
+		}
+		if imported {
+			fmt.Fprintf(os.Stderr, "Moved the credentials in %s to profile '%s' in %s\n", legacyPath, profile, store.Description())
+			creds, err = store.Load(profile)
+		}
+	}
+	if errors.Is(err, auth.ErrNoCredentials) {
+		if profile == auth.DefaultProfile {
+			return nil, fmt.Errorf("ASANA_ACCESS_TOKEN environment variable is not set and no credentials are stored; run 'asana-tasks-sorter auth add' or 'asana-tasks-sorter login'")
+		}
+		return nil, fmt.Errorf("no credentials stored for profile '%s'; run 'asana-tasks-sorter auth add --profile %s'", profile, profile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !creds.IsOAuth() {
+		return asana.StaticToken(creds.AccessToken), nil
+	}
+	return auth.NewTokenSource(creds, func(refreshed auth.Credentials) error {
+		return store.Save(profile, refreshed)
+	}), nil
+}
+
+// openBrowser tries to open a URL in the user's browser; failures are ignored
+// since the URL is also printed
+func openBrowser(url string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err == nil {
+		go cmd.Wait()
+	}
+}
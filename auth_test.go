@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/auth"
+)
+
+func TestClientRefreshesTokenOnUnauthorized(t *testing.T) {
+	var mu sync.Mutex
+	refreshes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth_token" {
+			r.ParseForm()
+			if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "refresh_1" {
+				t.Errorf("Unexpected token request: %v", r.Form)
+			}
+			mu.Lock()
+			refreshes++
+			mu.Unlock()
+			fmt.Fprint(w, `{"access_token":"fresh","expires_in":3600,"token_type":"bearer"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"errors":[{"message":"Not Authorized"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"gid":"user_1","name":"Test User"}}`)
+	}))
+	defer server.Close()
+
+	t.Setenv("ASANA_ACCESS_TOKEN", "")
+	path := filepath.Join(t.TempDir(), "asana-tasks-sorter", "credentials.enc")
+	store := auth.NewFileStore(path)
+	creds := auth.Credentials{ClientID: "client_1", TokenURL: server.URL + "/oauth_token", AccessToken: "expired", RefreshToken: "refresh_1"}
+	if err := store.Save("work", creds); err != nil {
+		t.Fatalf("Error saving credentials: %v", err)
+	}
+
+	tokenSource, err := newTokenSource(func() auth.Store { return store }, "work")
+	if err != nil {
+		t.Fatalf("Error getting token source: %v", err)
+	}
+	client := asana.NewClientWithTokenSource(tokenSource)
+	client.BaseURL = server.URL
+
+	// Concurrent requests with the rejected token share a single refresh
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetCurrentUser(context.Background()); err != nil {
+				t.Errorf("Expected the request to succeed after a refresh, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if refreshes != 1 {
+		t.Errorf("Expected a single refresh, got %d", refreshes)
+	}
+
+	refreshed, err := store.Load("work")
+	if err != nil {
+		t.Fatalf("Error loading refreshed credentials: %v", err)
+	}
+	if refreshed.AccessToken != "fresh" || refreshed.RefreshToken != "refresh_1" {
+		t.Errorf("Expected the new access token and the old refresh token to be saved, got %+v", refreshed)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Error checking credentials file: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("Expected the credentials file to be readable only by the user, got %v", mode)
+	}
+
+	// A personal access token can't be refreshed, so the 401 is returned as is
+	static := asana.NewClient("expired")
+	static.BaseURL = server.URL
+	if _, err := static.GetCurrentUser(context.Background()); !asana.IsUnauthorized(err) {
+		t.Errorf("Expected an unauthorized error for a static token, got %v", err)
+	}
+}
+
+func TestLoginWithPKCE(t *testing.T) {
+	var challenge string
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "authorization_code" || r.Form.Get("code") != "code_1" {
+			t.Errorf("Unexpected token request: %v", r.Form)
+		}
+		if auth.Challenge(r.Form.Get("code_verifier")) != challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant","error_description":"code verifier doesn't match"}`)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"access_1","refresh_token":"refresh_1","expires_in":3600}`)
+	}))
+	defer tokenServer.Close()
+
+	conf := auth.Config{ClientID: "client_1", RedirectURL: "http://127.0.0.1:0/callback", TokenURL: tokenServer.URL}
+	token, conf, err := auth.Login(context.Background(), conf, func(authURL string) {
+		// Play the browser: Asana redirects back with a code and the state it was given
+		parsed, err := url.Parse(authURL)
+		if err != nil {
+			t.Errorf("Invalid authorization URL: %v", err)
+			return
+		}
+		query := parsed.Query()
+		if query.Get("code_challenge_method") != "S256" {
+			t.Errorf("Expected an S256 challenge, got %s", authURL)
+		}
+		challenge = query.Get("code_challenge")
+
+		// A redirect with the wrong state is ignored
+		resp, err := http.Get(query.Get("redirect_uri") + "?code=forged&state=wrong")
+		if err != nil {
+			t.Errorf("Error following redirect: %v", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected a redirect with the wrong state to be rejected, got %d", resp.StatusCode)
+		}
+
+		resp, err = http.Get(query.Get("redirect_uri") + "?code=code_1&state=" + url.QueryEscape(query.Get("state")))
+		if err != nil {
+			t.Errorf("Error following redirect: %v", err)
+			return
+		}
+		resp.Body.Close()
+	})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if token.AccessToken != "access_1" || token.RefreshToken != "refresh_1" || token.ExpiresAt.IsZero() {
+		t.Errorf("Unexpected token: %+v", token)
+	}
+	if conf.RedirectURL == "http://127.0.0.1:0/callback" {
+		t.Errorf("Expected the redirect URL to name the port that was listened on")
+	}
+}
+
+func TestFileStoreEncryptsProfiles(t *testing.T) {
+	t.Setenv(auth.PassphraseEnv, "")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "credentials.enc")
+	store := auth.NewFileStore(path)
+
+	if _, err := store.Load("work"); !errors.Is(err, auth.ErrNoCredentials) {
+		t.Fatalf("Expected ErrNoCredentials before anything is stored, got %v", err)
+	}
+	if err := store.Save("work", auth.Credentials{User: "Work Me", AccessToken: "work_token"}); err != nil {
+		t.Fatalf("Error saving credentials: %v", err)
+	}
+	if err := store.Save("personal", auth.Credentials{User: "Personal Me", AccessToken: "personal_token"}); err != nil {
+		t.Fatalf("Error saving credentials: %v", err)
+	}
+
+	// Neither the credentials file nor its key can be read by others, and the tokens don't appear in the file
+	for _, file := range []string{path, path + ".key"} {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatalf("Error checking %s: %v", file, err)
+		}
+		if mode := info.Mode().Perm(); mode != 0600 {
+			t.Errorf("Expected %s to be readable only by the user, got %v", file, mode)
+		}
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "work_token") {
+		t.Errorf("Expected the token to be encrypted, got %s", data)
+	}
+
+	profiles, err := auth.NewFileStore(path).Profiles()
+	if err != nil || len(profiles) != 2 || profiles[0] != "personal" || profiles[1] != "work" {
+		t.Fatalf("Expected both profiles, got %v, %v", profiles, err)
+	}
+	if err := store.Delete("personal"); err != nil {
+		t.Fatalf("Error deleting profile: %v", err)
+	}
+	if err := store.Delete("personal"); !errors.Is(err, auth.ErrNoCredentials) {
+		t.Errorf("Expected ErrNoCredentials deleting a missing profile, got %v", err)
+	}
+
+	// With a passphrase the key is derived from it instead, and a wrong one can't decrypt the file
+	t.Setenv(auth.PassphraseEnv, "correct horse")
+	if err := store.Save("work", auth.Credentials{AccessToken: "work_token"}); err != nil {
+		t.Fatalf("Error saving credentials: %v", err)
+	}
+	if creds, err := store.Load("work"); err != nil || creds.AccessToken != "work_token" {
+		t.Errorf("Expected the passphrase to decrypt the file, got %+v, %v", creds, err)
+	}
+	t.Setenv(auth.PassphraseEnv, "battery staple")
+	if _, err := store.Load("work"); err == nil {
+		t.Errorf("Expected a wrong passphrase to fail")
+	}
+}
+
+func TestEnvironmentTokenTakesPrecedence(t *testing.T) {
+	store := auth.NewFileStore(filepath.Join(t.TempDir(), "credentials.enc"))
+	if err := store.Save("work", auth.Credentials{AccessToken: "work_token"}); err != nil {
+		t.Fatalf("Error saving credentials: %v", err)
+	}
+	openStore := func() auth.Store { return store }
+	ctx := context.Background()
+
+	t.Setenv("ASANA_ACCESS_TOKEN", "env_token")
+	source, err := newTokenSource(openStore, "work")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if token, _ := source.Token(ctx); token != "env_token" {
+		t.Errorf("Expected the environment token, got '%s'", token)
+	}
+
+	t.Setenv("ASANA_ACCESS_TOKEN", "")
+	source, err = newTokenSource(openStore, "work")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if token, _ := source.Token(ctx); token != "work_token" {
+		t.Errorf("Expected the profile's token, got '%s'", token)
+	}
+	if _, err := newTokenSource(openStore, "personal"); err == nil || !strings.Contains(err.Error(), "auth add --profile personal") {
+		t.Errorf("Expected an error naming the missing profile, got %v", err)
+	}
+}
+
+func TestLegacyCredentialsAreImported(t *testing.T) {
+	t.Setenv(auth.PassphraseEnv, "")
+	t.Setenv("ASANA_ACCESS_TOKEN", "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	legacyPath := auth.LegacyCredentialsPath()
+	if err := os.MkdirAll(filepath.Dir(legacyPath), 0700); err != nil {
+		t.Fatalf("Error creating config directory: %v", err)
+	}
+	legacy := `{"client_id": "client", "redirect_url": "http://localhost:8734/callback", "access_token": "old_token", "refresh_token": "refresh"}`
+	if err := os.WriteFile(legacyPath, []byte(legacy), 0600); err != nil {
+		t.Fatalf("Error writing credentials: %v", err)
+	}
+	store := auth.NewFileStore(auth.DefaultStorePath())
+	openStore := func() auth.Store { return store }
+
+	// Only the default profile picks up the old credentials
+	if _, err := newTokenSource(openStore, "work"); err == nil {
+		t.Errorf("Expected no credentials for another profile")
+	}
+	if _, err := newTokenSource(openStore, auth.DefaultProfile); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	creds, err := store.Load(auth.DefaultProfile)
+	if err != nil || creds.AccessToken != "old_token" || !creds.IsOAuth() || creds.ClientID != "client" {
+		t.Errorf("Expected the old credentials in the default profile, got %+v, %v", creds, err)
+	}
+	if _, err := os.Stat(legacyPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the unencrypted credentials file to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+)
+
+// batchServer is a stand-in for the Asana API that serves batch and single addTask requests
+type batchServer struct {
+	// batchStatus, if set, fails every batch request with this status
+	batchStatus int
+	// failTasks maps task GIDs to the status their addTask action fails with
+	failTasks map[string]int
+
+	mu             sync.Mutex
+	batchRequests  int
+	singleRequests int
+	moved          map[string]string
+}
+
+func (s *batchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.moved == nil {
+		s.moved = make(map[string]string)
+	}
+
+	if r.URL.Path == "/batch" {
+		s.batchRequests++
+		if s.batchStatus != 0 {
+			w.WriteHeader(s.batchStatus)
+			w.Write([]byte(`{"errors":[{"message":"batch unavailable"}]}`))
+			return
+		}
+
+		var body struct {
+			Data struct {
+				Actions []struct {
+					Method       string            `json:"method"`
+					RelativePath string            `json:"relative_path"`
+					Data         map[string]string `json:"data"`
+				} `json:"actions"`
+			} `json:"data"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		var results []map[string]interface{}
+		for _, action := range body.Data.Actions {
+			taskGID := action.Data["task"]
+			if status := s.failTasks[taskGID]; status != 0 {
+				results = append(results, map[string]interface{}{
+					"status_code": status,
+					"body":        map[string]interface{}{"errors": []map[string]string{{"message": "cannot move " + taskGID}}},
+				})
+				continue
+			}
+			s.moved[taskGID] = sectionFromPath(action.RelativePath)
+			results = append(results, map[string]interface{}{"status_code": 200, "body": map[string]interface{}{"data": map[string]interface{}{}}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": results})
+		return
+	}
+
+	s.singleRequests++
+	var body struct {
+		Data map[string]string `json:"data"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	s.moved[body.Data["task"]] = sectionFromPath(r.URL.Path)
+	w.Write([]byte(`{"data":{}}`))
+}
+
+// sectionFromPath extracts the section GID from a /sections/{gid}/addTask path
+func sectionFromPath(path string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(path, "/sections/"), "/addTask")
+}
+
+func newBatchTestClient(server *httptest.Server) *asana.Client {
+	return &asana.Client{
+		Client:  server.Client(),
+		Token:   "dummy_token",
+		BaseURL: server.URL,
+	}
+}
+
+func TestMoveTasksToSectionsMapsResults(t *testing.T) {
+	handler := &batchServer{failTasks: map[string]int{"task_2": http.StatusNotFound}}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	errs, err := newBatchTestClient(server).MoveTasksToSections(context.Background(), []asana.SectionMove{
+		{SectionGID: "section_1", TaskGID: "task_1"},
+		{SectionGID: "section_2", TaskGID: "task_2"},
+		{SectionGID: "section_1", TaskGID: "task_3"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(errs) != 3 || errs[0] != nil || errs[2] != nil {
+		t.Fatalf("Expected only the second move to fail, got %v", errs)
+	}
+	if !asana.IsNotFound(errs[1]) {
+		t.Errorf("Expected a not found error for task_2, got %v", errs[1])
+	}
+	if handler.moved["task_3"] != "section_1" {
+		t.Errorf("Expected task_3 to be moved to section_1, got %q", handler.moved["task_3"])
+	}
+}
+
+func TestExecuteTaskMovesInBatches(t *testing.T) {
+	handler := &batchServer{failTasks: map[string]int{"task_4": http.StatusForbidden}}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	moves := newTestMoves(25)
+	results, err := core.ExecuteTaskMoves(context.Background(), newBatchTestClient(server), moves, 2, 10, core.NopReporter{})
+	if !asana.IsForbidden(err) {
+		t.Fatalf("Expected the failed action's error, got %v", err)
+	}
+	if handler.batchRequests != 3 || handler.singleRequests != 0 {
+		t.Errorf("Expected 3 batch requests and no single requests, got %d and %d", handler.batchRequests, handler.singleRequests)
+	}
+	if len(results) != len(moves) {
+		t.Fatalf("Expected %d results, got %d", len(moves), len(results))
+	}
+	for i, result := range results {
+		if result.Move.Task.GID != moves[i].Task.GID {
+			t.Errorf("Result %d: expected %s, got %s", i, moves[i].Task.GID, result.Move.Task.GID)
+		}
+		if (result.Err != nil) != (i == 4) {
+			t.Errorf("Result %d: unexpected error %v", i, result.Err)
+		}
+	}
+}
+
+func TestExecuteTaskMovesFallsBackToSingleRequests(t *testing.T) {
+	handler := &batchServer{batchStatus: http.StatusBadRequest}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	reporter := &core.RecordingReporter{}
+	moves := newTestMoves(5)
+	results, err := core.ExecuteTaskMoves(context.Background(), newBatchTestClient(server), moves, 1, 10, reporter)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 5 || len(handler.moved) != 5 {
+		t.Errorf("Expected all 5 tasks to be moved, got %d results and %d moved", len(results), len(handler.moved))
+	}
+	if handler.batchRequests != 1 || handler.singleRequests != 5 {
+		t.Errorf("Expected 1 batch request and 5 single requests, got %d and %d", handler.batchRequests, handler.singleRequests)
+	}
+	if got := len(reporter.EventsOfType(core.EventBatchFailed)); got != 1 {
+		t.Errorf("Expected 1 batch_failed event, got %d", got)
+	}
+}
+
+func TestKeepExistingMovesIntoOneSectionAreBatched(t *testing.T) {
+	handler := &batchServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	now := time.Date(2023, 4, 15, 12, 0, 0, 0, time.UTC)
+	tasks := []asana.Task{orderTestTask("existing", "Due later", 30)}
+	for _, gid := range []string{"new_1", "new_2", "new_3", "new_4"} {
+		tasks = append(tasks, orderTestTask(gid, "Recently assigned", 28))
+	}
+	sectionNameToGID := map[string]string{"Due later": "gid_Due later", "Recently assigned": "gid_Recently assigned"}
+
+	config := core.DefaultSectionConfig()
+	moves := core.CalculateTaskMoves(tasks, config, sectionNameToGID, map[string]bool{}, now)
+	moves = core.OrderTaskMoves(tasks, moves, config, map[string]bool{}, now)
+	if len(moves) != 4 {
+		t.Fatalf("Expected 4 moves, got %+v", moves)
+	}
+
+	results, err := core.ExecuteTaskMoves(context.Background(), newBatchTestClient(server), moves, 4, 10, core.NopReporter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+	if handler.batchRequests != 1 || handler.singleRequests != 0 {
+		t.Errorf("Expected 1 batch request and no single requests, got %d and %d", handler.batchRequests, handler.singleRequests)
+	}
+}
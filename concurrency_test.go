@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+)
+
+// fakeMoveAPI is an asana.API that only supports moving tasks
+type fakeMoveAPI struct {
+	asana.API
+
+	failures map[string]error
+	// onMove, if set, is called as each move starts
+	onMove func(move asana.SectionMove)
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	calls       int
+}
+
+func (f *fakeMoveAPI) MoveTaskToSection(ctx context.Context, move asana.SectionMove) error {
+	if f.onMove != nil {
+		f.onMove(move)
+	}
+	f.mu.Lock()
+	f.calls++
+	f.inFlight++
+	if f.inFlight > f.maxInFlight {
+		f.maxInFlight = f.inFlight
+	}
+	f.mu.Unlock()
+
+	// Finish later moves first to shake out ordering problems
+	var index int
+	fmt.Sscanf(move.TaskGID, "task_%d", &index)
+	time.Sleep(time.Duration(10-index%10) * time.Millisecond)
+
+	f.mu.Lock()
+	f.inFlight--
+	f.mu.Unlock()
+
+	return f.failures[move.TaskGID]
+}
+
+// newTestMoves creates count moves for tasks named task_0, task_1, ...
+func newTestMoves(count int) []core.TaskMove {
+	moves := make([]core.TaskMove, count)
+	for i := range moves {
+		moves[i] = core.TaskMove{
+			Task:        asana.Task{GID: fmt.Sprintf("task_%d", i), Name: fmt.Sprintf("Task %d", i)},
+			SectionGID:  "section_1",
+			SectionName: "Section 1",
+		}
+	}
+	return moves
+}
+
+func TestExecuteTaskMovesConcurrently(t *testing.T) {
+	notFound := &asana.APIError{StatusCode: http.StatusNotFound, Method: http.MethodPost, Path: "/sections/section_1/addTask"}
+	client := &fakeMoveAPI{failures: map[string]error{
+		"task_3": notFound,
+		"task_7": errors.New("connection reset"),
+	}}
+	reporter := &core.RecordingReporter{}
+
+	moves := newTestMoves(12)
+	results, err := core.ExecuteTaskMoves(context.Background(), client, moves, 4, 1, reporter)
+
+	// Per-task errors are aggregated rather than counted
+	var moveErrs *core.MoveErrors
+	if !errors.As(err, &moveErrs) {
+		t.Fatalf("Expected *core.MoveErrors, got %T: %v", err, err)
+	}
+	if len(moveErrs.Failed) != 2 || moveErrs.Failed[0].Move.Task.GID != "task_3" || moveErrs.Failed[1].Move.Task.GID != "task_7" {
+		t.Errorf("Expected task_3 and task_7 to fail, got %+v", moveErrs.Failed)
+	}
+	if !asana.IsNotFound(err) {
+		t.Error("Expected the aggregated error to expose the underlying API error")
+	}
+
+	// Results and events keep the planned order
+	if len(results) != len(moves) {
+		t.Fatalf("Expected %d results, got %d", len(moves), len(results))
+	}
+	for i, result := range results {
+		if result.Move.Task.GID != moves[i].Task.GID {
+			t.Errorf("Result %d: expected %s, got %s", i, moves[i].Task.GID, result.Move.Task.GID)
+		}
+	}
+	started := reporter.EventsOfType(core.EventMoveStarted)
+	for i, event := range started {
+		if event.Move.Task.GID != moves[i].Task.GID {
+			t.Errorf("Event %d: expected %s, got %s", i, moves[i].Task.GID, event.Move.Task.GID)
+		}
+	}
+	if got := len(reporter.EventsOfType(core.EventMoveSucceeded)); got != 10 {
+		t.Errorf("Expected 10 move_succeeded events, got %d", got)
+	}
+
+	// The pool is bounded but actually runs in parallel
+	if client.maxInFlight > 4 {
+		t.Errorf("Expected at most 4 moves in flight, got %d", client.maxInFlight)
+	}
+	if client.maxInFlight < 2 {
+		t.Errorf("Expected moves to run in parallel, max in flight was %d", client.maxInFlight)
+	}
+}
+
+func TestExecuteTaskMovesStopsOnUnauthorized(t *testing.T) {
+	unauthorized := &asana.APIError{StatusCode: http.StatusUnauthorized, Method: http.MethodPost, Path: "/sections/section_1/addTask"}
+	client := &fakeMoveAPI{failures: map[string]error{"task_0": unauthorized}}
+
+	moves := newTestMoves(50)
+	results, err := core.ExecuteTaskMoves(context.Background(), client, moves, 1, 1, core.NopReporter{})
+	if !asana.IsUnauthorized(err) {
+		t.Fatalf("Expected an unauthorized error, got %v", err)
+	}
+	if len(results) != 1 || client.calls != 1 {
+		t.Errorf("Expected to stop after the first move, got %d results and %d calls", len(results), client.calls)
+	}
+}
+
+func TestExecuteTaskMovesReportsMovesFinishedAfterCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// task_2 goes after task_0, so both are moved by one worker before task_1
+	moves := newTestMoves(3)
+	moves[2].InsertAfter = "task_0"
+	client := &fakeMoveAPI{onMove: func(move asana.SectionMove) {
+		if move.TaskGID == "task_2" {
+			cancel()
+		}
+	}}
+	reporter := &core.RecordingReporter{}
+
+	results, err := core.ExecuteTaskMoves(ctx, client, moves, 1, 1, reporter)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the skipped move to be reported as cancelled, got %v", err)
+	}
+	if len(results) != 2 || results[0].Move.Task.GID != "task_0" || results[1].Move.Task.GID != "task_2" {
+		t.Fatalf("Expected results for task_0 and task_2, got %+v", results)
+	}
+	succeeded := reporter.EventsOfType(core.EventMoveSucceeded)
+	if len(succeeded) != 2 || succeeded[1].Move.Task.GID != "task_2" {
+		t.Errorf("Expected the move made after the skipped one to be reported, got %d events", len(succeeded))
+	}
+}
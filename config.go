@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/config"
+	"github.com/dackerman/asana-tasks-sorter/internal/output"
+	"github.com/dackerman/asana-tasks-sorter/internal/ui"
+)
+
+// runConfig handles the config subcommands
+func runConfig(args []string, opts config.Options, format string) {
+	if len(args) == 0 {
+		exitWithError(output.FormatText, nil, fmt.Errorf("config needs a subcommand: show or convert"))
+	}
+	switch args[0] {
+	case "show":
+		runConfigShow(opts, format)
+	case "convert":
+		runConfigConvert(args[1:])
+	default:
+		exitWithError(output.FormatText, nil, fmt.Errorf("unknown config subcommand '%s': use show or convert", args[0]))
+	}
+}
+
+// shownSetting is a setting as config show writes it in machine-readable output
+type shownSetting struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Origin string      `json:"origin"`
+}
+
+// runConfigShow prints the effective configuration, merged from its layers,
+// with where each setting came from
+func runConfigShow(opts config.Options, format string) {
+	effective, err := config.Load(opts)
+	if err != nil {
+		exitWithError(format, nil, err)
+	}
+
+	settings := make([]shownSetting, len(effective.Settings))
+	for i, setting := range effective.Settings {
+		settings[i] = shownSetting{Key: setting.Key, Value: setting.Value, Origin: setting.Origin.String()}
+	}
+
+	switch format {
+	case output.FormatJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		files := effective.Files
+		if files == nil {
+			files = []string{}
+		}
+		enc.Encode(struct {
+			Files    []string       `json:"files"`
+			Settings []shownSetting `json:"settings"`
+		}{files, settings})
+		return
+	case output.FormatNDJSON:
+		enc := json.NewEncoder(os.Stdout)
+		for _, setting := range settings {
+			enc.Encode(setting)
+		}
+		return
+	}
+
+	fmt.Println(ui.SectionTitle("Config files:"))
+	if len(effective.Files) == 0 {
+		fmt.Println("  none")
+	}
+	for _, file := range effective.Files {
+		fmt.Printf("  %s\n", file)
+	}
+	fmt.Println()
+
+	fmt.Println(ui.SectionTitle("Settings:"))
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, setting := range settings {
+		value, err := json.Marshal(setting.Value)
+		if err != nil {
+			exitWithError(output.FormatText, nil, err)
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", setting.Key, value, ui.Info(setting.Origin))
+	}
+	w.Flush()
+}
+
+// runConfigConvert translates a config file into another format. The output
+// format comes from --to or the output file's extension; without an output
+// file the result is printed.
+func runConfigConvert(args []string) {
+	convertFlags := flag.NewFlagSet("config convert", flag.ExitOnError)
+	to := convertFlags.String("to", "", "Output format: json, yaml or toml (default: from the output file's extension)")
+	force := convertFlags.Bool("force", false, "Overwrite the output file if it exists")
+	convertFlags.Parse(args)
+
+	if convertFlags.NArg() < 1 || convertFlags.NArg() > 2 {
+		exitWithError(output.FormatText, nil, fmt.Errorf("usage: config convert [--to json|yaml|toml] [--force] input [output]"))
+	}
+	input, outputPath := convertFlags.Arg(0), convertFlags.Arg(1)
+
+	format := *to
+	if format == "" && outputPath != "" {
+		format = config.FormatForPath(outputPath)
+	}
+	if format == "" {
+		exitWithError(output.FormatText, nil, fmt.Errorf("pass --to or an output file to choose the format"))
+	}
+
+	conf, err := config.LoadConfiguration(input)
+	if err != nil {
+		exitWithError(output.FormatText, nil, err)
+	}
+	data, err := config.Encode(conf, format)
+	if err != nil {
+		exitWithError(output.FormatText, nil, err)
+	}
+
+	if outputPath == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if _, err := os.Stat(outputPath); err == nil && !*force {
+		exitWithError(output.FormatText, nil, fmt.Errorf("%s already exists; pass --force to overwrite it", outputPath))
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		exitWithError(output.FormatText, nil, err)
+	}
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		exitWithError(output.FormatText, nil, fmt.Errorf("failed to write %s: %w", outputPath, err))
+	}
+	fmt.Printf("%s %s\n", ui.Success("Wrote"), ui.Important(outputPath))
+}
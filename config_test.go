@@ -0,0 +1,336 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/config"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+)
+
+func TestConfigValidationReportsEveryProblem(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	content := `{
+  "overdue": "Overdue",
+  "dueToday": "Today",
+  "due_this_week": "Overdue",
+  "due_later": "Someday",
+  "no_date": "Inbox",
+  "ignored_sections": ["Someday"],
+  "concurrency": "four",
+  "rules": [
+    {"name": "Errands", "tag": "errand"},
+    {"section": "Someday", "tag": "maybe"}
+  ],
+  "order_sections": true,
+  "order_sections": false
+}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+
+	_, err := config.LoadConfiguration(path)
+	var validationErr *config.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+
+	expected := []struct {
+		line    int
+		message string
+	}{
+		{3, "unknown key 'dueToday' (did you mean 'due_today'?)"},
+		{4, "due_this_week uses section 'Overdue', which is already used by overdue"},
+		{5, "due_later section 'Someday' is also in ignored_sections"},
+		{8, "concurrency must be a whole number"},
+		{10, "has no target section"},
+		{11, "rule 'Someday' moves tasks to section 'Someday', which is also in ignored_sections"},
+		{14, "order_sections is set more than once"},
+	}
+	if len(validationErr.Problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got:\n%v", len(expected), err)
+	}
+	for i, want := range expected {
+		problem := validationErr.Problems[i]
+		if problem.Line != want.line || !strings.Contains(problem.Message, want.message) {
+			t.Errorf("Expected problem #%d on line %d containing %q, got %s", i+1, want.line, want.message, problem)
+		}
+	}
+	if !strings.Contains(err.Error(), path+":3:3: unknown key") {
+		t.Errorf("Expected problems to be reported with file and position, got:\n%v", err)
+	}
+
+	// Omitted settings keep their defaults, but a section name can't be emptied
+	if err := os.WriteFile(path, []byte(`{"overdue": "Late", "due_today": ""}`), 0644); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+	_, err = config.LoadConfiguration(path)
+	if !errors.As(err, &validationErr) || len(validationErr.Problems) != 1 ||
+		!strings.Contains(validationErr.Problems[0].Message, "due_today must name a section") || validationErr.Problems[0].Line != 1 {
+		t.Errorf("Expected an empty due_today to be rejected where it is set, got %v", err)
+	}
+}
+
+func TestConfigSyntaxErrorsArePositioned(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte("{\n  \"overdue\": \"Overdue\"\n  \"due_today\": \"Due today\"\n}"), 0644); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+	_, err := config.LoadConfiguration(path)
+	var validationErr *config.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Problems[0].Line != 3 {
+		t.Errorf("Expected a syntax error on line 3, got %v", err)
+	}
+
+	if _, err := config.LoadConfiguration(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("Expected a missing config file to be an error rather than fall back to defaults")
+	}
+	if _, err := config.LoadConfiguration("sections_config.json"); err != nil {
+		t.Errorf("Expected the example config to be valid, got %v", err)
+	}
+}
+
+func TestYAMLAndTOMLConfigs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.json": `{
+  "overdue": "Overdue",
+  "due_today": "Due today",
+  "due_this_week": "Due within the next 7 days",
+  "due_later": "Due later",
+  "no_date": "Recently assigned",
+  "ignored_sections": ["Doing Now", "Waiting For"],
+  "look_ahead_days": 5,
+  "order_sections": true,
+  "rules": [
+    {"name": "Errands", "section": "Errands", "tag": "errand", "due": {"from": 0, "to": 3}},
+    {"section": "Someday: maybe", "custom_field": {"name": "Priority", "value": "Low"}}
+  ]
+}`,
+		"config.yaml": `# Buckets
+overdue: Overdue
+due_today: "Due today"  # quoted
+due_this_week: Due within the next 7 days
+due_later: 'Due later'
+no_date: Recently assigned
+ignored_sections:
+  - Doing Now    # what I'm working on
+  - Waiting For  # blocked on someone else
+look_ahead_days: 5
+order_sections: true
+rules:
+  - name: Errands
+    section: Errands
+    tag: errand
+    due: {from: 0, to: 3}
+  - section: "Someday: maybe"
+    custom_field:
+      name: Priority
+      value: Low
+`,
+		"config.toml": `# Buckets
+overdue = "Overdue"
+due_today = "Due today" # basic string
+due_this_week = 'Due within the next 7 days'
+due_later = """Due later"""
+no_date = "Recently assigned"
+ignored_sections = [
+  "Doing Now",   # what I'm working on
+  "Waiting For", # blocked on someone else
+]
+look_ahead_days = 5
+order_sections = true
+
+[[rules]]
+name = "Errands"
+section = "Errands"
+tag = "errand"
+due = { from = 0, to = 3 }
+
+[[rules]]
+section = "Someday: maybe"
+custom_field.name = "Priority"
+custom_field.value = "Low"
+`,
+	}
+	loaded := make(map[string]core.SectionConfig)
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Error writing %s: %v", name, err)
+		}
+		conf, err := config.LoadConfiguration(path)
+		if err != nil {
+			t.Fatalf("Error loading %s: %v", name, err)
+		}
+		loaded[name] = conf
+	}
+	if !reflect.DeepEqual(loaded["config.yaml"], loaded["config.json"]) {
+		t.Errorf("Expected the YAML config to match the JSON one, got %+v", loaded["config.yaml"])
+	}
+	if !reflect.DeepEqual(loaded["config.toml"], loaded["config.json"]) {
+		t.Errorf("Expected the TOML config to match the JSON one, got %+v", loaded["config.toml"])
+	}
+
+	// Converting to each format and back gives the same config
+	for _, format := range []string{config.FormatJSON, config.FormatYAML, config.FormatTOML} {
+		data, err := config.Encode(loaded["config.json"], format)
+		if err != nil {
+			t.Fatalf("Error encoding %s: %v", format, err)
+		}
+		path := filepath.Join(dir, "converted."+format)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Error writing %s: %v", path, err)
+		}
+		converted, err := config.LoadConfiguration(path)
+		if err != nil {
+			t.Fatalf("Error loading converted %s config: %v\n%s", format, err, data)
+		}
+		if !reflect.DeepEqual(converted, loaded["config.json"]) {
+			t.Errorf("Expected the %s conversion to round-trip, got:\n%s", format, data)
+		}
+	}
+}
+
+func TestYAMLAndTOMLProblemsArePositioned(t *testing.T) {
+	dir := t.TempDir()
+	testCases := []struct {
+		name    string
+		content string
+		line    int
+		message string
+	}{
+		{"unknown.yaml", "overdue: Overdue\n# comment\n  \nno_dat: Inbox\n", 4, "unknown key 'no_dat'"},
+		{"type.yaml", "overdue: Overdue\nconcurrency: many\n", 2, "concurrency must be a whole number"},
+		{"anchor.yaml", "overdue: &name Overdue\n", 1, "anchors, aliases and tags are not supported"},
+		{"indent.yaml", "overdue: Overdue\n   due_today: Today\n", 2, "unexpected indentation"},
+		{"unknown.toml", "overdue = \"Overdue\"\n\n[[rules]]\nsection = \"Errands\"\ntags = \"errand\"\n", 5, "unknown key 'rules[0].tags'"},
+		{"ignored.toml", "ignored_sections = [\"Overdue\"]\noverdue = \"Overdue\"\n", 2, "overdue section 'Overdue' is also in ignored_sections"},
+		{"syntax.toml", "overdue = \"Overdue\"\ndue_today = Due today\n", 2, "invalid value"},
+		{"date.toml", "overdue = 2024-01-01\n", 1, "dates and times are not supported"},
+		{"leading_zero.toml", "overdue = \"Overdue\"\nconcurrency = 01\n", 2, "invalid value '01'"},
+		{"trailing_dot.toml", "look_ahead_days = 1.\n", 1, "invalid value '1.'"},
+		{"surrogate.toml", "overdue = \"Over\\uD800due\"\n", 1, "invalid escape sequence '\\uD800'"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, tc.name)
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("Error writing config: %v", err)
+			}
+			_, err := config.LoadConfiguration(path)
+			var validationErr *config.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected a validation error, got %v", err)
+			}
+			for _, problem := range validationErr.Problems {
+				if problem.Line == tc.line && strings.Contains(problem.Message, tc.message) {
+					return
+				}
+			}
+			t.Errorf("Expected a problem on line %d containing %q, got:\n%v", tc.line, tc.message, err)
+		})
+	}
+}
+
+func TestConfigLayers(t *testing.T) {
+	dir := t.TempDir()
+	userDir := filepath.Join(dir, "xdg", "asana-tasks-sorter")
+	projectDir := filepath.Join(dir, "project")
+	for _, d := range []string{userDir, projectDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf("Error creating %s: %v", d, err)
+		}
+	}
+	userConfig := filepath.Join(userDir, "config.yaml")
+	files := map[string]string{
+		userConfig: "workspace: Personal\nignored_sections: [Doing Now]\nconcurrency: 2\n",
+		filepath.Join(projectDir, ".asana-sorter.toml"): "workspace = \"Acme\"\ntimezone = \"Europe/Berlin\"\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Error writing %s: %v", path, err)
+		}
+	}
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+	t.Setenv(config.ConfigEnv, "")
+	t.Setenv("ASANA_SORTER_TIMEZONE", "America/New_York")
+	t.Chdir(projectDir)
+
+	effective, err := config.Load(config.Options{Flags: map[string]interface{}{"concurrency": 8}})
+	if err != nil {
+		t.Fatalf("Error loading config: %v", err)
+	}
+	if want := []string{userConfig, ".asana-sorter.toml"}; !reflect.DeepEqual(effective.Files, want) {
+		t.Errorf("Expected the user and project config files to be found, got %v", effective.Files)
+	}
+	conf := effective.Config
+	if conf.Overdue != "Overdue" || conf.Workspace != "Acme" || conf.Timezone != "America/New_York" ||
+		conf.Concurrency != 8 || !reflect.DeepEqual(conf.IgnoredSections, []string{"Doing Now"}) {
+		t.Errorf("Expected later layers to override earlier ones, got %+v", conf)
+	}
+
+	origins := make(map[string]string)
+	for _, setting := range effective.Settings {
+		origins[setting.Key] = setting.Origin.String()
+	}
+	expected := map[string]string{
+		"overdue":          "default",
+		"ignored_sections": userConfig + ":2:1",
+		"workspace":        ".asana-sorter.toml:1:1",
+		"timezone":         "env ASANA_SORTER_TIMEZONE",
+		"concurrency":      "flag --concurrency",
+	}
+	for key, want := range expected {
+		if origins[key] != want {
+			t.Errorf("Expected %s to come from %s, got %q", key, want, origins[key])
+		}
+	}
+
+	// Problems are reported in the layer that set the setting
+	t.Setenv("ASANA_SORTER_CONCURRENCY", "many")
+	if err := os.WriteFile(userConfig, []byte("due_today: Overdue\n"), 0644); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+	_, err = config.Load(config.Options{})
+	var validationErr *config.ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Problems) != 2 {
+		t.Fatalf("Expected two problems, got %v", err)
+	}
+	if problem := validationErr.Problems[0]; problem.File != userConfig || problem.Line != 1 {
+		t.Errorf("Expected the duplicate section to be reported in the user config, got %s", problem)
+	}
+	if problem := validationErr.Problems[1]; problem.File != "env ASANA_SORTER_CONCURRENCY" {
+		t.Errorf("Expected the bad number to be reported in the environment, got %s", problem)
+	}
+	if err := os.WriteFile(userConfig, []byte("due_today: Today\n"), 0644); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+	_, err = config.Load(config.Options{})
+	if err == nil || !strings.HasPrefix(err.Error(), "config has 1 problem\n  env ASANA_SORTER_CONCURRENCY: ") {
+		t.Errorf("Expected a problem in the environment not to be reported as a config file, got %v", err)
+	}
+
+	// --config default and ASANA_SORTER_CONFIG replace the discovered files
+	t.Setenv("ASANA_SORTER_CONCURRENCY", "")
+	if effective, err := config.Load(config.Options{File: "default"}); err != nil || len(effective.Files) != 0 {
+		t.Errorf("Expected --config default to skip config files, got %v, %v", effective, err)
+	}
+	t.Setenv(config.ConfigEnv, filepath.Join(dir, "missing.yaml"))
+	if _, err := config.Load(config.Options{}); err == nil {
+		t.Errorf("Expected a missing %s file to be an error", config.ConfigEnv)
+	}
+
+	// Two project config files are ambiguous
+	t.Setenv(config.ConfigEnv, "")
+	if err := os.WriteFile(".asana-sorter.json", []byte("{}"), 0644); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+	if _, err := config.Discover(); err == nil || !strings.Contains(err.Error(), "keep only one") {
+		t.Errorf("Expected two project config files to be an error, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+	"github.com/dackerman/asana-tasks-sorter/internal/state"
+	"github.com/dackerman/asana-tasks-sorter/internal/webhook"
+)
+
+func TestGetEventsHandlesSyncTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("resource") != "list_1" {
+			t.Errorf("Expected events for list_1, got %s", r.URL.RawQuery)
+		}
+		switch r.URL.Query().Get("sync") {
+		case "", "expired":
+			w.WriteHeader(http.StatusPreconditionFailed)
+			fmt.Fprint(w, `{"errors":[{"message":"Sync token invalid or too old."}],"sync":"fresh"}`)
+		case "fresh":
+			fmt.Fprint(w, `{"data":[{"action":"changed","resource":{"gid":"task_1","resource_type":"task"}}],"sync":"page_2","has_more":true}`)
+		case "page_2":
+			fmt.Fprint(w, `{"data":[{"action":"added","resource":{"gid":"task_2","resource_type":"task"}}],"sync":"latest","has_more":false}`)
+		default:
+			t.Errorf("Unexpected sync token in %s", r.URL.RawQuery)
+		}
+	}))
+	defer server.Close()
+
+	client := asana.NewClient("token")
+	client.BaseURL = server.URL
+	ctx := context.Background()
+
+	for _, token := range []string{"", "expired"} {
+		events, err := client.GetEvents(ctx, "list_1", token)
+		if !errors.Is(err, asana.ErrSyncTokenInvalid) {
+			t.Fatalf("Expected ErrSyncTokenInvalid for token '%s', got %v", token, err)
+		}
+		if events.Sync != "fresh" {
+			t.Errorf("Expected the fresh token with the error, got '%s'", events.Sync)
+		}
+	}
+
+	events, err := client.GetEvents(ctx, "list_1", "fresh")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(events.Events) != 2 || events.Sync != "latest" {
+		t.Errorf("Expected both pages of events and the latest token, got %+v", events)
+	}
+}
+
+// fakeEventsAPI adds a My Tasks list and an events endpoint to fakeSectionsAPI
+type fakeEventsAPI struct {
+	*fakeSectionsAPI
+
+	validToken string
+	events     []asana.Event
+}
+
+func (f *fakeEventsAPI) GetEvents(ctx context.Context, resourceGID, syncToken string) (*asana.Events, error) {
+	if syncToken != f.validToken {
+		return &asana.Events{Sync: "token_1"}, asana.ErrSyncTokenInvalid
+	}
+	return &asana.Events{Events: f.events, Sync: "token_2"}, nil
+}
+
+func (f *fakeEventsAPI) GetTasksFromUserTaskList(ctx context.Context, userTaskListGID string) ([]asana.Task, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var tasks []asana.Task
+	for _, section := range f.sections {
+		for _, gid := range f.contents[section.GID] {
+			task := f.tasks[gid]
+			task.AssigneeSection.Name = section.Name
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+func TestOrganizeTasksSince(t *testing.T) {
+	yesterday := asana.Date(time.Now().AddDate(0, 0, -1))
+	config := core.DefaultSectionConfig()
+	config.BatchSize = 1
+
+	var sections []asana.Section
+	for _, name := range core.RequiredSectionNames(core.EffectiveRules(config)) {
+		sections = append(sections, asana.Section{GID: "gid_" + name, Name: name})
+	}
+	inbox := func(gid string) asana.Task {
+		return asana.Task{GID: gid, Name: gid, DueOn: yesterday, AssigneeSection: asana.AssigneeSection{GID: "gid_Recently assigned"}}
+	}
+	client := &fakeEventsAPI{
+		fakeSectionsAPI: &fakeSectionsAPI{
+			sections: sections,
+			contents: map[string][]string{"gid_Recently assigned": {"task_1"}},
+			tasks:    map[string]asana.Task{"task_1": inbox("task_1")},
+		},
+		validToken: "token_1",
+	}
+	taskList := &core.TaskList{GID: "list_1", Location: time.Local}
+	stateDir := t.TempDir()
+	ctx := context.Background()
+
+	// Without a sync token the whole list is sorted
+	if _, err := runSince(ctx, client, taskList, config, stateDir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	saved, err := state.LoadSyncState(stateDir, "list_1")
+	if err != nil || saved.Token != "token_1" || saved.FullScanAt.IsZero() {
+		t.Fatalf("Expected the fresh token and a full scan to be saved, got %+v, %v", saved, err)
+	}
+	if overdue := client.contents["gid_Overdue"]; len(overdue) != 1 {
+		t.Errorf("Expected the full scan to move task_1, got %v", client.contents)
+	}
+
+	// With a valid token only the tasks in the events are looked at
+	client.tasks["task_2"] = inbox("task_2")
+	client.tasks["task_3"] = inbox("task_3")
+	client.contents["gid_Recently assigned"] = []string{"task_2", "task_3"}
+	client.events = []asana.Event{webhook.TaskChanged("task_2", "due_on")}
+	client.fetched = nil
+
+	result, err := runSince(ctx, client, taskList, config, stateDir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(client.fetched) != 1 || len(result.Workspaces[0].MoveResults) != 1 {
+		t.Errorf("Expected only task_2 to be fetched and moved, fetched %v", client.fetched)
+	}
+	if remaining := client.contents["gid_Recently assigned"]; len(remaining) != 1 || remaining[0] != "task_3" {
+		t.Errorf("Expected the unchanged task to stay, got %v", remaining)
+	}
+	if saved, _ := state.LoadSyncState(stateDir, "list_1"); saved.Token != "token_2" {
+		t.Errorf("Expected the next token to be saved, got %+v", saved)
+	}
+}
+
+// runSince runs an incremental sort with the sync state kept in stateDir
+func runSince(ctx context.Context, client asana.API, taskList *core.TaskList, config core.SectionConfig,
+	stateDir string) (*core.RunResult, error) {
+
+	previous, err := state.LoadSyncState(stateDir, taskList.GID)
+	if err != nil {
+		return nil, err
+	}
+	result, next, err := core.OrganizeTasksSince(ctx, client, taskList, config, previous, core.NopReporter{})
+	if err != nil {
+		return result, err
+	}
+	return result, state.SaveSyncState(stateDir, taskList.GID, next)
+}
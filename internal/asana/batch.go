@@ -0,0 +1,123 @@
+package asana
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// MaxBatchActions is the largest number of actions Asana accepts in one batch request
+const MaxBatchActions = 10
+
+// BatchAction is a single API request sent as part of a batch request
+type BatchAction struct {
+	Method       string      `json:"method"`
+	RelativePath string      `json:"relative_path"`
+	Data         interface{} `json:"data,omitempty"`
+}
+
+// BatchResult is the response to a single action in a batch request
+type BatchResult struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       json.RawMessage   `json:"body"`
+}
+
+// Err returns an APIError for a failed action, or nil if it succeeded
+func (r BatchResult) Err(action BatchAction) error {
+	if r.StatusCode >= 200 && r.StatusCode < 300 {
+		return nil
+	}
+	return newAPIError(r.StatusCode, strings.ToUpper(action.Method), action.RelativePath, r.Body)
+}
+
+// SectionMove identifies a task to move and the section to move it to.
+// Setting InsertBefore or InsertAfter to the GID of a task already in the
+// section places the moved task next to it; otherwise Asana picks the position.
+type SectionMove struct {
+	SectionGID   string
+	TaskGID      string
+	InsertBefore string
+	InsertAfter  string
+}
+
+// data returns the request data for an addTask call
+func (m SectionMove) data() map[string]string {
+	data := map[string]string{
+		"task": m.TaskGID,
+	}
+	if m.InsertBefore != "" {
+		data["insert_before"] = m.InsertBefore
+	}
+	if m.InsertAfter != "" {
+		data["insert_after"] = m.InsertAfter
+	}
+	return data
+}
+
+// Batch sends up to MaxBatchActions requests to Asana in a single call and
+// returns their results in the same order. An error means the batch request
+// as a whole failed; failures of individual actions are reported in their results.
+func (c *Client) Batch(ctx context.Context, actions []BatchAction) ([]BatchResult, error) {
+	if len(actions) == 0 {
+		return nil, nil
+	}
+	if len(actions) > MaxBatchActions {
+		return nil, fmt.Errorf("batch of %d actions exceeds the limit of %d", len(actions), MaxBatchActions)
+	}
+
+	data, err := c.executeRequest(Request{
+		Method: http.MethodPost,
+		Path:   "/batch",
+		Body: map[string]interface{}{
+			"data": map[string]interface{}{
+				"actions": actions,
+			},
+		},
+		Context: ctx,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute batch request: %w", err)
+	}
+
+	var results []BatchResult
+	if err := unmarshalResponse(data, &results); err != nil {
+		return nil, fmt.Errorf("failed to parse batch response: %w", err)
+	}
+	if len(results) != len(actions) {
+		return nil, fmt.Errorf("batch response has %d results for %d actions", len(results), len(actions))
+	}
+
+	return results, nil
+}
+
+// MoveTasksToSections moves up to MaxBatchActions tasks in a single batch
+// request. It returns one error per move, in order, which is nil for moves
+// that succeeded. The second return value is set when the batch request
+// itself failed, in which case none of the moves were made.
+func (c *Client) MoveTasksToSections(ctx context.Context, moves []SectionMove) ([]error, error) {
+	actions := make([]BatchAction, len(moves))
+	for i, move := range moves {
+		actions[i] = BatchAction{
+			Method:       "post",
+			RelativePath: fmt.Sprintf("/sections/%s/addTask", move.SectionGID),
+			Data:         move.data(),
+		}
+	}
+
+	results, err := c.Batch(ctx, actions)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make([]error, len(moves))
+	for i, result := range results {
+		if err := result.Err(actions[i]); err != nil {
+			errs[i] = fmt.Errorf("failed to move task to section: %w", err)
+		}
+	}
+
+	return errs, nil
+}
@@ -4,25 +4,34 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 )
 
 // API constants
 const (
-	BaseURL        = "https://app.asana.com/api/1.0"
-	DefaultTimeout = 10 * time.Second
-	
+	BaseURL         = "https://app.asana.com/api/1.0"
+	DefaultTimeout  = 10 * time.Second
+	DefaultPageSize = 100
+	// MaxPageSize is the largest page Asana's list endpoints accept
+	MaxPageSize = 100
+
 	// Query parameter names
 	QueryCompletedSince = "completed_since"
 	QueryOptFields      = "opt_fields"
 	QueryWorkspace      = "workspace"
-	
+	QueryLimit          = "limit"
+	QueryOffset         = "offset"
+
 	// Standard field sets
-	TaskFields = "name,completed,due_on,due_at,assignee_section,assignee_section.name"
+	UserFields = "name,time_zone"
+	TaskFields = "name,completed,due_on,due_at,start_on,start_at,assignee_section,assignee_section.name," +
+		"tags.name,projects.name,custom_fields.name,custom_fields.display_value"
 )
 
 // Client handles API requests to the Asana API
@@ -30,15 +39,47 @@ type Client struct {
 	Client  *http.Client
 	Token   string
 	BaseURL string
+
+	// TokenSource supplies access tokens and refreshes them when Asana rejects
+	// one. When nil, Token is used as a static token.
+	TokenSource TokenSource
+
+	// PageSize is the number of items requested per page from list endpoints.
+	// Zero means DefaultPageSize.
+	PageSize int
+
+	// Retry controls retries of rate-limited and failed requests.
+	// The zero value disables retries.
+	Retry RetryPolicy
+
+	// rateLimit pauses every request made through the client after Asana rate limits one of them
+	rateLimit rateLimitGate
 }
 
 // NewClient creates a new Asana API client
 func NewClient(token string) *Client {
 	return &Client{
-		Client:  &http.Client{Timeout: DefaultTimeout},
-		Token:   token,
-		BaseURL: BaseURL,
+		Client:   &http.Client{Timeout: DefaultTimeout},
+		Token:    token,
+		BaseURL:  BaseURL,
+		PageSize: DefaultPageSize,
+		Retry:    DefaultRetryPolicy(),
+	}
+}
+
+// NewClientWithTokenSource creates a new Asana API client that gets its access tokens from source
+func NewClientWithTokenSource(source TokenSource) *Client {
+	client := NewClient("")
+	client.TokenSource = source
+	return client
+}
+
+// tokenSource returns the client's token source, falling back to the static Token
+func (c *Client) tokenSource() TokenSource {
+	if c.TokenSource != nil {
+		return c.TokenSource
 	}
+	return StaticToken(c.Token)
 }
 
 // Request represents an API request
@@ -52,13 +93,22 @@ type Request struct {
 
 // Response structs
 type DataContainer struct {
-	Data json.RawMessage `json:"data"`
+	Data     json.RawMessage `json:"data"`
+	NextPage *NextPage       `json:"next_page"`
+}
+
+// NextPage describes the next page of a paginated list response
+type NextPage struct {
+	Offset string `json:"offset"`
+	Path   string `json:"path"`
+	URI    string `json:"uri"`
 }
 
 // Model types
 type User struct {
-	GID  string `json:"gid"`
-	Name string `json:"name"`
+	GID      string `json:"gid"`
+	Name     string `json:"name"`
+	TimeZone string `json:"time_zone,omitempty"`
 }
 
 type Workspace struct {
@@ -83,13 +133,36 @@ type UserTaskList struct {
 	Workspace Workspace `json:"workspace"`
 }
 
+type Tag struct {
+	GID  string `json:"gid"`
+	Name string `json:"name"`
+}
+
+type Project struct {
+	GID  string `json:"gid"`
+	Name string `json:"name"`
+}
+
+// CustomField holds a custom field value on a task. DisplayValue is Asana's
+// string rendering of the value regardless of the field type.
+type CustomField struct {
+	GID          string `json:"gid"`
+	Name         string `json:"name"`
+	DisplayValue string `json:"display_value"`
+}
+
 type Task struct {
 	GID             string          `json:"gid"`
 	Name            string          `json:"name"`
 	Completed       bool            `json:"completed"`
 	DueOn           Date            `json:"due_on,omitempty"`
 	DueAt           time.Time       `json:"due_at,omitempty"`
+	StartOn         Date            `json:"start_on,omitempty"`
+	StartAt         time.Time       `json:"start_at,omitempty"`
 	AssigneeSection AssigneeSection `json:"assignee_section,omitempty"`
+	Tags            []Tag           `json:"tags,omitempty"`
+	Projects        []Project       `json:"projects,omitempty"`
+	CustomFields    []CustomField   `json:"custom_fields,omitempty"`
 }
 
 // Date is a custom type to handle ISO 8601 date strings
@@ -125,6 +198,14 @@ func (d *Date) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalJSON implements the json.Marshaler interface, writing null for a zero date
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(`"` + d.Format("2006-01-02") + `"`), nil
+}
+
 // Time returns the time.Time representation
 func (d Date) Time() time.Time {
 	return time.Time(d)
@@ -149,41 +230,168 @@ const (
 	DueThisWeek
 	DueLater
 	NoDate
+	DueTomorrow
+	NotStarted
+	StartsToday
 )
 
+// DefaultLookAheadDays is the number of days after today counted as "this week"
+const DefaultLookAheadDays = 7
+
+// categoryNames maps each category to the key used for it in configuration files
+var categoryNames = map[TaskCategory]string{
+	Overdue:     "overdue",
+	DueToday:    "due_today",
+	DueTomorrow: "due_tomorrow",
+	DueThisWeek: "due_this_week",
+	DueLater:    "due_later",
+	NoDate:      "no_date",
+	NotStarted:  "not_started",
+	StartsToday: "starts_today",
+}
+
+// String returns the configuration key for the category
+func (c TaskCategory) String() string {
+	if name, ok := categoryNames[c]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseTaskCategory converts a configuration key such as "due_today" into a TaskCategory
+func ParseTaskCategory(name string) (TaskCategory, bool) {
+	for category, categoryName := range categoryNames {
+		if categoryName == name {
+			return category, true
+		}
+	}
+	return 0, false
+}
+
+// CategoryOptions tunes how due dates are mapped to categories.
+// The zero value gives the default rolling 7-day window.
+type CategoryOptions struct {
+	// LookAheadDays is how many days after today count as DueThisWeek in rolling mode
+	LookAheadDays int
+	// CalendarWeek makes DueThisWeek end with the current calendar week instead of a rolling window
+	CalendarWeek bool
+	// WeekStart is the first day of a calendar week
+	WeekStart time.Weekday
+	// DueTomorrow splits tasks due tomorrow out of DueThisWeek
+	DueTomorrow bool
+	// OverdueAsOfNow makes tasks with a due time overdue as soon as that time
+	// passes, rather than only once the day they're due has ended
+	OverdueAsOfNow bool
+	// NotStarted puts tasks whose start date is still in the future in NotStarted, whatever their due date
+	NotStarted bool
+	// StartsToday puts tasks starting today in StartsToday unless they're overdue or due today
+	StartsToday bool
+}
+
+// thisWeekDays returns the last day offset from today that still counts as DueThisWeek
+func (o CategoryOptions) thisWeekDays(now time.Time) int {
+	if o.CalendarWeek {
+		daysIntoWeek := (int(now.Weekday()) - int(o.WeekStart) + 7) % 7
+		return 6 - daysIntoWeek
+	}
+	if o.LookAheadDays > 0 {
+		return o.LookAheadDays
+	}
+	return DefaultLookAheadDays
+}
+
+// DaysBetween returns the number of calendar days from the day of now to the day of date
+func DaysBetween(now, date time.Time) int {
+	// Compare calendar dates in UTC so daylight saving changes don't skew the result
+	nowDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	dueDate := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	return int(dueDate.Sub(nowDate).Hours() / 24)
+}
+
+// DueDate returns the day the task is due in the given location. Tasks with a
+// due time use it, so a task due late in the evening UTC can land on the next
+// day in time zones ahead of UTC.
+func (t *Task) DueDate(loc *time.Location) (time.Time, bool) {
+	if !t.DueAt.IsZero() {
+		return t.DueAt.In(loc), true
+	}
+	if !t.DueOn.IsZero() {
+		return t.DueOn.Time(), true
+	}
+	return time.Time{}, false
+}
+
+// StartDate returns the day the task starts in the given location, preferring the start time if set
+func (t *Task) StartDate(loc *time.Location) (time.Time, bool) {
+	if !t.StartAt.IsZero() {
+		return t.StartAt.In(loc), true
+	}
+	if !t.StartOn.IsZero() {
+		return t.StartOn.Time(), true
+	}
+	return time.Time{}, false
+}
+
 // GetTaskCategory determines the category of a task based on its due date
 func (t *Task) GetTaskCategory(now time.Time) TaskCategory {
-	if t.DueOn.IsZero() {
+	return t.Categorize(now, CategoryOptions{})
+}
+
+// Categorize determines the category of a task based on its due date and the
+// given options. Dates are compared in now's location.
+func (t *Task) Categorize(now time.Time, opts CategoryOptions) TaskCategory {
+	startDays, hasStart := 0, false
+	if startDate, ok := t.StartDate(now.Location()); ok {
+		startDays, hasStart = DaysBetween(now, startDate), true
+	}
+
+	// Tasks that haven't started yet are parked regardless of their due date
+	if opts.NotStarted && hasStart && startDays > 0 {
+		return NotStarted
+	}
+
+	dueDate, ok := t.DueDate(now.Location())
+	if !ok {
+		if opts.StartsToday && hasStart && startDays == 0 {
+			return StartsToday
+		}
 		return NoDate
 	}
 
-	dueDate := t.DueOn.Time()
+	// A due time that has already passed is overdue even on the day it's due
+	if opts.OverdueAsOfNow && !t.DueAt.IsZero() && t.DueAt.Before(now) {
+		return Overdue
+	}
 
-	// Normalize time to start of day for comparison
-	nowDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	dueDateNormalized := time.Date(dueDate.Year(), dueDate.Month(), dueDate.Day(), 0, 0, 0, 0, now.Location())
+	days := DaysBetween(now, dueDate)
 
 	// Tasks due today should always be in the DueToday category
-	if dueDateNormalized.Equal(nowDate) {
+	if days == 0 {
 		return DueToday
 	}
 
 	// Compare dates
-	if dueDateNormalized.Before(nowDate) {
+	if days < 0 {
 		return Overdue
-	} 
-	
-	// For future dates, calculate days difference
-	days := int(dueDateNormalized.Sub(nowDate).Hours() / 24)
-	if days <= 7 {
+	}
+
+	// Surface tasks starting today ahead of the rest of their due-date bucket
+	if opts.StartsToday && hasStart && startDays == 0 {
+		return StartsToday
+	}
+
+	if opts.DueTomorrow && days == 1 {
+		return DueTomorrow
+	}
+
+	// For future dates, check whether the date falls within this week's window
+	if days <= opts.thisWeekDays(now) {
 		return DueThisWeek
-	} 
-	
+	}
+
 	return DueLater
 }
 
-// This section has been moved to internal/core/config.go
-
 // executeRequest is a generic helper method for making HTTP requests to the Asana API
 func (c *Client) executeRequest(req Request) ([]byte, error) {
 	// Use background context if none provided
@@ -191,13 +399,13 @@ func (c *Client) executeRequest(req Request) ([]byte, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	
+
 	// Build URL with query parameters
 	reqURL, err := url.Parse(c.BaseURL + req.Path)
 	if err != nil {
 		return nil, fmt.Errorf("invalid URL: %w", err)
 	}
-	
+
 	// Add query parameters if any
 	if len(req.QueryParams) > 0 {
 		q := reqURL.Query()
@@ -206,51 +414,107 @@ func (c *Client) executeRequest(req Request) ([]byte, error) {
 		}
 		reqURL.RawQuery = q.Encode()
 	}
-	
+
 	// Create request body if any
-	var bodyReader io.Reader
+	var bodyBytes []byte
 	if req.Body != nil {
-		bodyBytes, err := json.Marshal(req.Body)
+		bodyBytes, err = json.Marshal(req.Body)
 		if err != nil {
 			return nil, fmt.Errorf("error creating request body: %w", err)
 		}
-		bodyReader = bytes.NewReader(bodyBytes)
 	}
-	
+
+	// Execute the request, retrying rate limits, server errors and network
+	// failures; see isRetryableStatus for the requests that can't be resent
+	attempts := c.Retry.attempts()
+	refreshed := false
+	for attempt := 1; ; attempt++ {
+		// Hold off while another request is waiting out a rate limit
+		if err := c.rateLimit.wait(ctx); err != nil {
+			return nil, fmt.Errorf("waiting for rate limit: %w", err)
+		}
+
+		token, err := c.tokenSource().Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get access token: %w", err)
+		}
+
+		statusCode, header, respBody, err := c.doRequest(ctx, req.Method, reqURL.String(), token, bodyBytes)
+		if err == nil && statusCode == http.StatusUnauthorized && !refreshed {
+			// The token may have expired; try once more with a fresh one if the source can get one
+			refreshed = true
+			refreshErr := c.tokenSource().Refresh(ctx, token)
+			if refreshErr == nil {
+				attempt--
+				continue
+			}
+			if !errors.Is(refreshErr, ErrCannotRefresh) {
+				return nil, fmt.Errorf("failed to refresh access token: %w", refreshErr)
+			}
+		}
+		if err != nil {
+			if attempt >= attempts || !isRetryableError(ctx, req.Method, err) {
+				return nil, err
+			}
+		} else if statusCode < 200 || statusCode >= 300 {
+			if attempt >= attempts || !isRetryableStatus(req.Method, statusCode) {
+				return nil, newAPIError(statusCode, req.Method, req.Path, respBody)
+			}
+		} else {
+			return respBody, nil
+		}
+
+		// Wait before the next attempt, giving up if the context won't allow it
+		delay := c.Retry.retryDelay(attempt-1, header)
+		if statusCode == http.StatusTooManyRequests {
+			c.rateLimit.pause(delay)
+		}
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			if err != nil {
+				return nil, err
+			}
+			return nil, newAPIError(statusCode, req.Method, req.Path, respBody)
+		}
+	}
+}
+
+// doRequest performs a single HTTP round trip and returns the status code,
+// headers and body of the response
+func (c *Client) doRequest(ctx context.Context, method, reqURL, token string, body []byte) (int, http.Header, []byte, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
 	// Create HTTP request with context
-	httpReq, err := http.NewRequestWithContext(ctx, req.Method, reqURL.String(), bodyReader)
+	httpReq, err := http.NewRequestWithContext(ctx, method, reqURL, bodyReader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return 0, nil, nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
-	
+
 	// Add common headers
-	httpReq.Header.Add("Authorization", "Bearer "+c.Token)
+	httpReq.Header.Add("Authorization", "Bearer "+token)
 	httpReq.Header.Add("Accept", "application/json")
-	
+
 	// Add content-type for requests with bodies
-	if req.Body != nil {
+	if body != nil {
 		httpReq.Header.Add("Content-Type", "application/json")
 	}
-	
+
 	// Execute request
 	resp, err := c.Client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		return 0, nil, nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
 	defer resp.Body.Close()
-	
+
 	// Read response body
-	bodyBytes, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-	
-	// Check status code
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+		return 0, nil, nil, fmt.Errorf("failed to read response body: %w", err)
 	}
-	
-	return bodyBytes, nil
+
+	return resp.StatusCode, resp.Header, respBody, nil
 }
 
 // unmarshalResponse is a helper function to unmarshal the API response
@@ -259,36 +523,83 @@ func unmarshalResponse(data []byte, target interface{}) error {
 	if err := json.Unmarshal(data, &container); err != nil {
 		return fmt.Errorf("failed to unmarshal API response container: %w", err)
 	}
-	
+
 	if err := json.Unmarshal(container.Data, target); err != nil {
 		return fmt.Errorf("failed to unmarshal API response data: %w", err)
 	}
-	
+
 	return nil
 }
 
+// pageSize returns the configured page size, falling back to the default
+func (c *Client) pageSize() int {
+	if c.PageSize <= 0 {
+		return DefaultPageSize
+	}
+	return min(c.PageSize, MaxPageSize)
+}
+
+// getAllPages issues a list request and follows next_page offsets until
+// every page has been read, returning the combined items
+func getAllPages[T any](c *Client, req Request) ([]T, error) {
+	// Copy the query parameters so the caller's map is left untouched
+	params := make(map[string]string, len(req.QueryParams)+2)
+	for key, value := range req.QueryParams {
+		params[key] = value
+	}
+	params[QueryLimit] = strconv.Itoa(c.pageSize())
+	req.QueryParams = params
+
+	var items []T
+	for {
+		data, err := c.executeRequest(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var container DataContainer
+		if err := json.Unmarshal(data, &container); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal API response container: %w", err)
+		}
+
+		var page []T
+		if err := json.Unmarshal(container.Data, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal API response data: %w", err)
+		}
+		items = append(items, page...)
+
+		if container.NextPage == nil || container.NextPage.Offset == "" {
+			return items, nil
+		}
+		params[QueryOffset] = container.NextPage.Offset
+	}
+}
+
 // GetCurrentUser retrieves the current user's information
 func (c *Client) GetCurrentUser(ctx context.Context) (*User, error) {
 	data, err := c.executeRequest(Request{
-		Method:  http.MethodGet,
-		Path:    "/users/me",
+		Method: http.MethodGet,
+		Path:   "/users/me",
+		QueryParams: map[string]string{
+			QueryOptFields: UserFields,
+		},
 		Context: ctx,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current user: %w", err)
 	}
-	
+
 	var user User
 	if err := unmarshalResponse(data, &user); err != nil {
 		return nil, fmt.Errorf("failed to parse user data: %w", err)
 	}
-	
+
 	return &user, nil
 }
 
 // GetWorkspaces retrieves all workspaces the user has access to
 func (c *Client) GetWorkspaces(ctx context.Context) ([]Workspace, error) {
-	data, err := c.executeRequest(Request{
+	workspaces, err := getAllPages[Workspace](c, Request{
 		Method:  http.MethodGet,
 		Path:    "/workspaces",
 		Context: ctx,
@@ -296,20 +607,15 @@ func (c *Client) GetWorkspaces(ctx context.Context) ([]Workspace, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get workspaces: %w", err)
 	}
-	
-	var workspaces []Workspace
-	if err := unmarshalResponse(data, &workspaces); err != nil {
-		return nil, fmt.Errorf("failed to parse workspaces: %w", err)
-	}
-	
+
 	return workspaces, nil
 }
 
 // GetUserTaskList retrieves a user's task list for a specific workspace
 func (c *Client) GetUserTaskList(ctx context.Context, userGID, workspaceGID string) (*UserTaskList, error) {
 	data, err := c.executeRequest(Request{
-		Method:  http.MethodGet,
-		Path:    fmt.Sprintf("/users/%s/user_task_list", userGID),
+		Method: http.MethodGet,
+		Path:   fmt.Sprintf("/users/%s/user_task_list", userGID),
 		QueryParams: map[string]string{
 			QueryWorkspace: workspaceGID,
 		},
@@ -318,18 +624,18 @@ func (c *Client) GetUserTaskList(ctx context.Context, userGID, workspaceGID stri
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user task list: %w", err)
 	}
-	
+
 	var userTaskList UserTaskList
 	if err := unmarshalResponse(data, &userTaskList); err != nil {
 		return nil, fmt.Errorf("failed to parse user task list: %w", err)
 	}
-	
+
 	return &userTaskList, nil
 }
 
 // GetSectionsForProject retrieves all sections in a project
 func (c *Client) GetSectionsForProject(ctx context.Context, projectGID string) ([]Section, error) {
-	data, err := c.executeRequest(Request{
+	sections, err := getAllPages[Section](c, Request{
 		Method:  http.MethodGet,
 		Path:    fmt.Sprintf("/projects/%s/sections", projectGID),
 		Context: ctx,
@@ -337,20 +643,15 @@ func (c *Client) GetSectionsForProject(ctx context.Context, projectGID string) (
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sections for project: %w", err)
 	}
-	
-	var sections []Section
-	if err := unmarshalResponse(data, &sections); err != nil {
-		return nil, fmt.Errorf("failed to parse sections: %w", err)
-	}
-	
+
 	return sections, nil
 }
 
 // GetTasksFromUserTaskList retrieves all incomplete tasks in a user's task list
 func (c *Client) GetTasksFromUserTaskList(ctx context.Context, userTaskListGID string) ([]Task, error) {
-	data, err := c.executeRequest(Request{
-		Method:  http.MethodGet,
-		Path:    fmt.Sprintf("/user_task_lists/%s/tasks", userTaskListGID),
+	tasks, err := getAllPages[Task](c, Request{
+		Method: http.MethodGet,
+		Path:   fmt.Sprintf("/user_task_lists/%s/tasks", userTaskListGID),
 		QueryParams: map[string]string{
 			QueryCompletedSince: "now",
 			QueryOptFields:      TaskFields,
@@ -360,20 +661,37 @@ func (c *Client) GetTasksFromUserTaskList(ctx context.Context, userTaskListGID s
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tasks from user task list: %w", err)
 	}
-	
-	var tasks []Task
-	if err := unmarshalResponse(data, &tasks); err != nil {
-		return nil, fmt.Errorf("failed to parse tasks: %w", err)
-	}
-	
+
 	return tasks, nil
 }
 
+// GetTask retrieves a single task
+func (c *Client) GetTask(ctx context.Context, taskGID string) (*Task, error) {
+	data, err := c.executeRequest(Request{
+		Method: http.MethodGet,
+		Path:   fmt.Sprintf("/tasks/%s", taskGID),
+		QueryParams: map[string]string{
+			QueryOptFields: TaskFields,
+		},
+		Context: ctx,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	var task Task
+	if err := unmarshalResponse(data, &task); err != nil {
+		return nil, fmt.Errorf("failed to parse task: %w", err)
+	}
+
+	return &task, nil
+}
+
 // GetTasksInSection retrieves all incomplete tasks in a section
 func (c *Client) GetTasksInSection(ctx context.Context, sectionGID string) ([]Task, error) {
-	data, err := c.executeRequest(Request{
-		Method:  http.MethodGet,
-		Path:    fmt.Sprintf("/sections/%s/tasks", sectionGID),
+	tasks, err := getAllPages[Task](c, Request{
+		Method: http.MethodGet,
+		Path:   fmt.Sprintf("/sections/%s/tasks", sectionGID),
 		QueryParams: map[string]string{
 			QueryCompletedSince: "now",
 			QueryOptFields:      TaskFields,
@@ -383,21 +701,16 @@ func (c *Client) GetTasksInSection(ctx context.Context, sectionGID string) ([]Ta
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tasks in section: %w", err)
 	}
-	
-	var tasks []Task
-	if err := unmarshalResponse(data, &tasks); err != nil {
-		return nil, fmt.Errorf("failed to parse tasks: %w", err)
-	}
-	
+
 	return tasks, nil
 }
 
 // CreateSection creates a new section in a project
 func (c *Client) CreateSection(ctx context.Context, projectGID, name string) (*Section, error) {
 	data, err := c.executeRequest(Request{
-		Method:  http.MethodPost,
-		Path:    fmt.Sprintf("/projects/%s/sections", projectGID),
-		Body:    map[string]interface{}{
+		Method: http.MethodPost,
+		Path:   fmt.Sprintf("/projects/%s/sections", projectGID),
+		Body: map[string]interface{}{
 			"data": map[string]string{
 				"name": name,
 			},
@@ -407,31 +720,64 @@ func (c *Client) CreateSection(ctx context.Context, projectGID, name string) (*S
 	if err != nil {
 		return nil, fmt.Errorf("failed to create section: %w", err)
 	}
-	
+
 	var section Section
 	if err := unmarshalResponse(data, &section); err != nil {
 		return nil, fmt.Errorf("failed to parse created section: %w", err)
 	}
-	
+
 	return &section, nil
 }
 
-// MoveTaskToSection moves a task to a section
-func (c *Client) MoveTaskToSection(ctx context.Context, sectionGID, taskGID string) error {
+// SectionInsert places a section before or after another section of the same project
+type SectionInsert struct {
+	SectionGID    string
+	BeforeSection string
+	AfterSection  string
+}
+
+// InsertSection moves a section to a new position within a project
+func (c *Client) InsertSection(ctx context.Context, projectGID string, insert SectionInsert) error {
+	data := map[string]string{
+		"section": insert.SectionGID,
+	}
+	if insert.BeforeSection != "" {
+		data["before_section"] = insert.BeforeSection
+	}
+	if insert.AfterSection != "" {
+		data["after_section"] = insert.AfterSection
+	}
+
 	_, err := c.executeRequest(Request{
-		Method:  http.MethodPost,
-		Path:    fmt.Sprintf("/sections/%s/addTask", sectionGID),
-		Body:    map[string]interface{}{
-			"data": map[string]string{
-				"task": taskGID,
-			},
+		Method: http.MethodPost,
+		Path:   fmt.Sprintf("/projects/%s/sections/insert", projectGID),
+		Body: map[string]interface{}{
+			"data": data,
+		},
+		Context: ctx,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reorder section: %w", err)
+	}
+
+	return nil
+}
+
+// MoveTaskToSection moves a task to a section, placing it next to another task
+// in that section when the move names one
+func (c *Client) MoveTaskToSection(ctx context.Context, move SectionMove) error {
+	_, err := c.executeRequest(Request{
+		Method: http.MethodPost,
+		Path:   fmt.Sprintf("/sections/%s/addTask", move.SectionGID),
+		Body: map[string]interface{}{
+			"data": move.data(),
 		},
 		Context: ctx,
 	})
-	
+
 	if err != nil {
 		return fmt.Errorf("failed to move task to section: %w", err)
 	}
-	
+
 	return nil
-}
\ No newline at end of file
+}
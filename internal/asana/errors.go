@@ -0,0 +1,121 @@
+package asana
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrorDetail is a single entry in the errors array of an Asana error response
+type ErrorDetail struct {
+	Message string `json:"message"`
+	Help    string `json:"help,omitempty"`
+	Phrase  string `json:"phrase,omitempty"`
+}
+
+// APIError is returned when the Asana API responds with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Method     string
+	Path       string
+	Errors     []ErrorDetail
+
+	// Body holds the raw response body when it couldn't be parsed as an Asana error
+	Body string
+
+	// Sync is the fresh sync token the events endpoint sends with a 412 response
+	Sync string
+}
+
+// newAPIError builds an APIError from an HTTP response, parsing Asana's error payload if present
+func newAPIError(statusCode int, method, path string, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Method:     method,
+		Path:       path,
+	}
+
+	var payload struct {
+		Errors []ErrorDetail `json:"errors"`
+		Sync   string        `json:"sync"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil && len(payload.Errors) > 0 {
+		apiErr.Errors = payload.Errors
+		apiErr.Sync = payload.Sync
+	} else {
+		apiErr.Body = strings.TrimSpace(string(body))
+	}
+
+	return apiErr
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("API request %s %s failed with status %d", e.Method, e.Path, e.StatusCode)
+
+	var messages []string
+	for _, detail := range e.Errors {
+		if detail.Message != "" {
+			messages = append(messages, detail.Message)
+		}
+	}
+	if len(messages) > 0 {
+		return msg + ": " + strings.Join(messages, "; ")
+	}
+	if e.Body != "" {
+		return msg + ": " + e.Body
+	}
+	return msg
+}
+
+// Help returns the help text Asana attached to the error, if any
+func (e *APIError) Help() string {
+	for _, detail := range e.Errors {
+		if detail.Help != "" {
+			return detail.Help
+		}
+	}
+	return ""
+}
+
+// hasStatus reports whether err is an APIError with one of the given status codes
+func hasStatus(err error, statusCodes ...int) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, statusCode := range statusCodes {
+		if apiErr.StatusCode == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotFound reports whether err is an API error for a missing resource
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is an API error for a missing, invalid or expired token
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// IsForbidden reports whether err is an API error for a resource the token can't access
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden, http.StatusPaymentRequired)
+}
+
+// IsRateLimited reports whether err is an API error caused by rate limiting
+func IsRateLimited(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests)
+}
+
+// IsServerError reports whether err is an API error caused by a failure on Asana's side
+func IsServerError(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode >= 500
+}
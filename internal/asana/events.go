@@ -0,0 +1,84 @@
+package asana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrSyncTokenInvalid is returned by GetEvents when the sync token is missing or
+// has expired; the Events it returns alongside carry a fresh token to use next time
+var ErrSyncTokenInvalid = errors.New("events sync token is missing or expired")
+
+// EventResource identifies the resource an event is about
+type EventResource struct {
+	GID          string `json:"gid"`
+	ResourceType string `json:"resource_type"`
+}
+
+// EventChange describes which field of a resource changed
+type EventChange struct {
+	Field  string `json:"field"`
+	Action string `json:"action"`
+}
+
+// Event is a change to a resource, as delivered by webhooks and the events endpoint
+type Event struct {
+	Action    string         `json:"action"`
+	Resource  EventResource  `json:"resource"`
+	Parent    *EventResource `json:"parent,omitempty"`
+	Change    *EventChange   `json:"change,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// Events are the changes to a resource since a sync token, and the token to
+// pass next time to get the changes after these
+type Events struct {
+	Events []Event
+	Sync   string
+}
+
+// GetEvents returns the events on a resource since the given sync token. Asana
+// answers a missing or expired token with 412 Precondition Failed and a fresh
+// token; GetEvents then returns that token with ErrSyncTokenInvalid, and the
+// caller has to look at the resource in full once.
+func (c *Client) GetEvents(ctx context.Context, resourceGID, syncToken string) (*Events, error) {
+	result := &Events{Sync: syncToken}
+	for {
+		params := map[string]string{"resource": resourceGID}
+		if result.Sync != "" {
+			params["sync"] = result.Sync
+		}
+
+		data, err := c.executeRequest(Request{
+			Method:      http.MethodGet,
+			Path:        "/events",
+			QueryParams: params,
+			Context:     ctx,
+		})
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusPreconditionFailed && apiErr.Sync != "" {
+			return &Events{Sync: apiErr.Sync}, fmt.Errorf("%w: %w", ErrSyncTokenInvalid, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get events: %w", err)
+		}
+
+		var page struct {
+			Data    []Event `json:"data"`
+			Sync    string  `json:"sync"`
+			HasMore bool    `json:"has_more"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse events: %w", err)
+		}
+		result.Events = append(result.Events, page.Data...)
+		result.Sync = page.Sync
+		if !page.HasMore {
+			return result, nil
+		}
+	}
+}
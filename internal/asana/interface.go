@@ -8,16 +8,26 @@ type API interface {
 	GetCurrentUser(ctx context.Context) (*User, error)
 	GetWorkspaces(ctx context.Context) ([]Workspace, error)
 	GetUserTaskList(ctx context.Context, userGID, workspaceGID string) (*UserTaskList, error)
-	
+
 	// Section methods
 	GetSectionsForProject(ctx context.Context, projectGID string) ([]Section, error)
 	CreateSection(ctx context.Context, projectGID, name string) (*Section, error)
-	
+	InsertSection(ctx context.Context, projectGID string, insert SectionInsert) error
+
 	// Task methods
 	GetTasksFromUserTaskList(ctx context.Context, userTaskListGID string) ([]Task, error)
 	GetTasksInSection(ctx context.Context, sectionGID string) ([]Task, error)
-	MoveTaskToSection(ctx context.Context, sectionGID, taskGID string) error
+	GetTask(ctx context.Context, taskGID string) (*Task, error)
+	MoveTaskToSection(ctx context.Context, move SectionMove) error
+	MoveTasksToSections(ctx context.Context, moves []SectionMove) ([]error, error)
+
+	// Event methods
+	GetEvents(ctx context.Context, resourceGID, syncToken string) (*Events, error)
+
+	// Webhook methods
+	CreateWebhook(ctx context.Context, resourceGID, target string, filters []WebhookFilter) (*Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookGID string) error
 }
 
 // Ensure Client implements the API interface
-var _ API = (*Client)(nil)
\ No newline at end of file
+var _ API = (*Client)(nil)
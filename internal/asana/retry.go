@@ -0,0 +1,197 @@
+package asana
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Retry defaults
+const (
+	DefaultMaxAttempts = 5
+	DefaultBaseDelay   = 500 * time.Millisecond
+	DefaultMaxDelay    = 30 * time.Second
+)
+
+// RetryPolicy controls how failed requests are retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Zero or one disables retries.
+	MaxAttempts int
+
+	// BaseDelay is the initial backoff delay, doubled after every attempt
+	BaseDelay time.Duration
+
+	// MaxDelay caps the backoff delay and any Retry-After value
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used by NewClient
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: DefaultMaxAttempts,
+		BaseDelay:   DefaultBaseDelay,
+		MaxDelay:    DefaultMaxDelay,
+	}
+}
+
+// attempts returns the number of attempts allowed by the policy
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// backoff returns a randomized delay for the given retry number (starting at 0)
+// using exponential backoff with full jitter
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	base := p.BaseDelay
+	if base <= 0 {
+		base = DefaultBaseDelay
+	}
+
+	delay := base << retry
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// isIdempotent reports whether a request with the given method can be sent
+// again when it isn't known whether Asana applied it. A repeated POST could,
+// for example, create a section twice.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// isRetryableStatus reports whether a response status should be retried. A rate
+// limited request was never applied, but a server error may come after the
+// request was, so those are only retried for idempotent methods.
+func isRetryableStatus(method string, statusCode int) bool {
+	if statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return statusCode >= 500 && isIdempotent(method)
+}
+
+// isRetryableError reports whether a transport error should be retried.
+// Errors caused by the request's own context are never retried, and requests
+// that aren't idempotent are only retried when they never reached Asana.
+func isRetryableError(ctx context.Context, method string, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return isIdempotent(method) || notSent(err)
+}
+
+// notSent reports whether a transport error happened before the request was
+// sent: the host couldn't be resolved or the connection couldn't be made
+func notSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := date.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
+
+// retryDelay determines how long to wait before the next attempt, preferring
+// the server's Retry-After header when one was sent
+func (p RetryPolicy) retryDelay(retry int, header http.Header) time.Duration {
+	if header != nil {
+		if delay, ok := parseRetryAfter(header.Get("Retry-After"), time.Now()); ok {
+			if p.MaxDelay > 0 && delay > p.MaxDelay {
+				delay = p.MaxDelay
+			}
+			return delay
+		}
+	}
+	return p.backoff(retry)
+}
+
+// sleepContext waits for the given delay, returning early with an error if the
+// context is cancelled or its deadline would pass before the delay elapses
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return context.DeadlineExceeded
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rateLimitGate lets concurrent requests share a rate limit pause, so that
+// once Asana returns 429 to one request the others back off too
+type rateLimitGate struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// pause blocks new requests for the given delay
+func (g *rateLimitGate) pause(delay time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if until := time.Now().Add(delay); until.After(g.until) {
+		g.until = until
+	}
+}
+
+// wait blocks until any active pause is over or the context ends
+func (g *rateLimitGate) wait(ctx context.Context) error {
+	g.mu.Lock()
+	delay := time.Until(g.until)
+	g.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	return sleepContext(ctx, delay)
+}
@@ -0,0 +1,35 @@
+package asana
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrCannotRefresh is returned by token sources whose tokens can't be renewed
+var ErrCannotRefresh = errors.New("access token can't be refreshed")
+
+// TokenSource supplies the access token sent with each request
+type TokenSource interface {
+	// Token returns the access token to use for the next request
+	Token(ctx context.Context) (string, error)
+
+	// Refresh is called when Asana answered a request made with the token
+	// rejected with 401 Unauthorized. It obtains a new token, or returns an error
+	// such as ErrCannotRefresh if there is none. Implementations must be safe for
+	// concurrent use; when another request has already replaced the rejected
+	// token there is nothing to do.
+	Refresh(ctx context.Context, rejected string) error
+}
+
+// StaticToken is a token that never changes, such as a personal access token
+type StaticToken string
+
+// Token implements TokenSource
+func (t StaticToken) Token(ctx context.Context) (string, error) {
+	return string(t), nil
+}
+
+// Refresh implements TokenSource; a static token can't be refreshed
+func (t StaticToken) Refresh(ctx context.Context, rejected string) error {
+	return ErrCannotRefresh
+}
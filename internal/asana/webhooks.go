@@ -0,0 +1,63 @@
+package asana
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Webhook is a subscription that makes Asana post events about a resource to a target URL
+type Webhook struct {
+	GID    string `json:"gid"`
+	Target string `json:"target"`
+	Active bool   `json:"active"`
+}
+
+// WebhookFilter limits the events a webhook delivers
+type WebhookFilter struct {
+	ResourceType string   `json:"resource_type"`
+	Action       string   `json:"action,omitempty"`
+	Fields       []string `json:"fields,omitempty"`
+}
+
+// CreateWebhook subscribes target to events about a resource. Asana confirms
+// the target by sending it a handshake request before this call returns, so
+// the target must already be accepting requests.
+func (c *Client) CreateWebhook(ctx context.Context, resourceGID, target string, filters []WebhookFilter) (*Webhook, error) {
+	data, err := c.executeRequest(Request{
+		Method: http.MethodPost,
+		Path:   "/webhooks",
+		Body: map[string]interface{}{
+			"data": map[string]interface{}{
+				"resource": resourceGID,
+				"target":   target,
+				"filters":  filters,
+			},
+		},
+		Context: ctx,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	var webhook Webhook
+	if err := unmarshalResponse(data, &webhook); err != nil {
+		return nil, fmt.Errorf("failed to parse created webhook: %w", err)
+	}
+
+	return &webhook, nil
+}
+
+// DeleteWebhook removes a webhook subscription
+func (c *Client) DeleteWebhook(ctx context.Context, webhookGID string) error {
+	_, err := c.executeRequest(Request{
+		Method:  http.MethodDelete,
+		Path:    fmt.Sprintf("/webhooks/%s", webhookGID),
+		Context: ctx,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// appName names the sorter's directory within the user's config directory
+const appName = "asana-tasks-sorter"
+
+// Credentials are the tokens stored for a profile: a personal access token, or
+// the tokens of an OAuth login with the app they belong to so they can be refreshed
+type Credentials struct {
+	// User is the name of the Asana user the credentials belong to, for display
+	User         string    `json:"user,omitempty"`
+	ClientID     string    `json:"client_id,omitempty"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	RedirectURL  string    `json:"redirect_url,omitempty"`
+	TokenURL     string    `json:"token_url,omitempty"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitzero"`
+}
+
+// NewCredentials combines an OAuth app and the tokens it was granted
+func NewCredentials(conf Config, token *Token) Credentials {
+	return Credentials{
+		ClientID:     conf.ClientID,
+		ClientSecret: conf.ClientSecret,
+		RedirectURL:  conf.RedirectURL,
+		TokenURL:     conf.TokenURL,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		ExpiresAt:    token.ExpiresAt,
+	}
+}
+
+// Config returns the OAuth app the credentials were granted to
+func (c Credentials) Config() Config {
+	return Config{ClientID: c.ClientID, ClientSecret: c.ClientSecret, RedirectURL: c.RedirectURL, TokenURL: c.TokenURL}
+}
+
+// IsOAuth reports whether the credentials come from an OAuth login, rather
+// than being a personal access token
+func (c Credentials) IsOAuth() bool {
+	return c.RefreshToken != ""
+}
+
+// DefaultStorePath returns the file credentials are kept in, encrypted, when no OS
+// keyring is available: $XDG_CONFIG_HOME/asana-tasks-sorter/credentials.enc, or
+// ~/.config/asana-tasks-sorter/credentials.enc
+func DefaultStorePath() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, appName, "credentials.enc")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), appName, "credentials.enc")
+	}
+	return filepath.Join(home, ".config", appName, "credentials.enc")
+}
+
+// LegacyCredentialsPath returns the unencrypted file that versions before
+// profiles saved the credentials of an OAuth login to, next to DefaultStorePath
+func LegacyCredentialsPath() string {
+	return filepath.Join(filepath.Dir(DefaultStorePath()), "credentials.json")
+}
+
+// ImportLegacyCredentials stores the credentials in an unencrypted file saved
+// by an earlier version as the default profile, then removes the file. It
+// returns false when there is no such file.
+func ImportLegacyCredentials(store Store, path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	var creds Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return false, fmt.Errorf("failed to parse credentials in %s: %w", path, err)
+	}
+	if creds.AccessToken == "" {
+		return false, fmt.Errorf("no access token in %s", path)
+	}
+	if err := store.Save(DefaultProfile, creds); err != nil {
+		return false, err
+	}
+	if err := os.Remove(path); err != nil {
+		return true, fmt.Errorf("stored the credentials in %s, but failed to remove the file: %w", path, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// PassphraseEnv names the environment variable holding the passphrase the
+// credentials file is encrypted with. Without it, a random key is kept in a
+// file next to the credentials.
+const PassphraseEnv = "ASANA_SORTER_PASSPHRASE"
+
+// Ways the key of a credentials file is obtained
+const (
+	keySourcePassphrase = "passphrase"
+	keySourceKeyFile    = "key-file"
+)
+
+// passphraseIterations is the PBKDF2-SHA256 work factor for passphrases
+const passphraseIterations = 600000
+
+// fileStoreVersion is the format version of encrypted credentials files
+const fileStoreVersion = 1
+
+// encryptedFile is the on-disk form of a credentials file: the profiles,
+// encrypted with AES-256-GCM
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KeySource  string `json:"key_source"`
+	Salt       []byte `json:"salt,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// FileStore keeps the credentials of every profile in a single encrypted file,
+// for systems without a keyring daemon
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileStore returns a store keeping credentials in the encrypted file at path
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Description implements Store
+func (s *FileStore) Description() string {
+	return "the encrypted file " + s.path
+}
+
+// Load implements Store
+func (s *FileStore) Load(profile string) (Credentials, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profiles, err := s.read()
+	if err != nil {
+		return Credentials{}, err
+	}
+	creds, ok := profiles[profile]
+	if !ok {
+		return Credentials{}, ErrNoCredentials
+	}
+	return creds, nil
+}
+
+// Save implements Store
+func (s *FileStore) Save(profile string, creds Credentials) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profiles, err := s.read()
+	if err != nil {
+		return err
+	}
+	profiles[profile] = creds
+	return s.write(profiles)
+}
+
+// Delete implements Store
+func (s *FileStore) Delete(profile string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profiles, err := s.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := profiles[profile]; !ok {
+		return ErrNoCredentials
+	}
+	delete(profiles, profile)
+	return s.write(profiles)
+}
+
+// Profiles implements Store
+func (s *FileStore) Profiles() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profiles, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// read decrypts the profiles in the file; a missing file holds no profiles
+func (s *FileStore) read() (map[string]Credentials, error) {
+	profiles := make(map[string]Credentials)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return profiles, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file %s: %w", s.path, err)
+	}
+	if file.Version != fileStoreVersion {
+		return nil, fmt.Errorf("credentials file %s has unsupported version %d", s.path, file.Version)
+	}
+
+	key, err := s.key(file.KeySource, file.Salt, file.Iterations, false)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		if file.KeySource == keySourcePassphrase {
+			return nil, fmt.Errorf("failed to decrypt credentials file %s: wrong %s?", s.path, PassphraseEnv)
+		}
+		return nil, fmt.Errorf("failed to decrypt credentials file %s: its key file doesn't match", s.path)
+	}
+	if err := json.Unmarshal(plaintext, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials: %w", err)
+	}
+	return profiles, nil
+}
+
+// write encrypts the profiles into the file, readable only by the user
+func (s *FileStore) write(profiles map[string]Credentials) error {
+	plaintext, err := json.Marshal(profiles)
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+
+	file := encryptedFile{Version: fileStoreVersion, KeySource: keySourceKeyFile}
+	if os.Getenv(PassphraseEnv) != "" {
+		file.KeySource = keySourcePassphrase
+		file.Iterations = passphraseIterations
+		if file.Salt, err = randomBytes(16); err != nil {
+			return err
+		}
+	}
+	key, err := s.key(file.KeySource, file.Salt, file.Iterations, true)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	if file.Nonce, err = randomBytes(gcm.NonceSize()); err != nil {
+		return err
+	}
+	file.Ciphertext = gcm.Seal(nil, file.Nonce, plaintext, nil)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials file: %w", err)
+	}
+	return writePrivateFile(s.path, data)
+}
+
+// key returns the encryption key for a file. A key file is created if create
+// is set and there is none yet.
+func (s *FileStore) key(source string, salt []byte, iterations int, create bool) ([]byte, error) {
+	switch source {
+	case keySourcePassphrase:
+		passphrase := os.Getenv(PassphraseEnv)
+		if passphrase == "" {
+			return nil, fmt.Errorf("credentials file %s is encrypted with a passphrase; set %s", s.path, PassphraseEnv)
+		}
+		key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive key: %w", err)
+		}
+		return key, nil
+	case keySourceKeyFile:
+		keyPath := s.keyPath()
+		key, err := os.ReadFile(keyPath)
+		if errors.Is(err, os.ErrNotExist) && create {
+			if key, err = randomBytes(32); err != nil {
+				return nil, err
+			}
+			return key, writePrivateFile(keyPath, key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key file %s is damaged", keyPath)
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("credentials file %s has unknown key source '%s'", s.path, source)
+}
+
+// keyPath is the file holding the random key when no passphrase is set
+func (s *FileStore) keyPath() string {
+	return s.path + ".key"
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return gcm, nil
+}
+
+func randomBytes(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return buf, nil
+}
+
+// writePrivateFile writes a new file and renames it over the old one, so a
+// crash can't leave a partial file. The mode is set explicitly since WriteFile
+// keeps the mode of a leftover file.
+func writePrivateFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
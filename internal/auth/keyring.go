@@ -0,0 +1,263 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// keyringService names the sorter's entries in the OS keyring
+const keyringService = appName
+
+// keyringIndex is the keyring entry listing the stored profiles, since
+// keyrings can't be searched the same way everywhere
+const keyringIndex = "profiles"
+
+// errKeyringNotFound is returned by a keyring that has no entry for an account
+var errKeyringNotFound = errors.New("not found in keyring")
+
+// keyring is an OS secret store holding one secret per account
+type keyring interface {
+	name() string
+	available() bool
+	get(account string) (string, error)
+	set(account, secret string) error
+	delete(account string) error
+}
+
+// systemKeyring returns the keyring of the current OS, or nil if it isn't supported
+func systemKeyring() keyring {
+	switch runtime.GOOS {
+	case "darwin":
+		return macKeychain{}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return secretService{}
+	}
+	return nil
+}
+
+// KeyringStore keeps each profile's credentials as an entry in the OS keyring
+type KeyringStore struct {
+	keyring keyring
+}
+
+// Description implements Store
+func (s *KeyringStore) Description() string {
+	return s.keyring.name()
+}
+
+// Load implements Store
+func (s *KeyringStore) Load(profile string) (Credentials, error) {
+	var creds Credentials
+	secret, err := s.keyring.get(profileAccount(profile))
+	if errors.Is(err, errKeyringNotFound) {
+		return creds, ErrNoCredentials
+	}
+	if err != nil {
+		return creds, err
+	}
+	if err := json.Unmarshal([]byte(secret), &creds); err != nil {
+		return creds, fmt.Errorf("failed to parse credentials of profile '%s': %w", profile, err)
+	}
+	return creds, nil
+}
+
+// Save implements Store
+func (s *KeyringStore) Save(profile string, creds Credentials) error {
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+	if err := s.keyring.set(profileAccount(profile), string(data)); err != nil {
+		return err
+	}
+
+	profiles, err := s.Profiles()
+	if err != nil {
+		return err
+	}
+	for _, name := range profiles {
+		if name == profile {
+			return nil
+		}
+	}
+	return s.saveIndex(append(profiles, profile))
+}
+
+// Delete implements Store
+func (s *KeyringStore) Delete(profile string) error {
+	err := s.keyring.delete(profileAccount(profile))
+	if errors.Is(err, errKeyringNotFound) {
+		return ErrNoCredentials
+	}
+	if err != nil {
+		return err
+	}
+
+	profiles, err := s.Profiles()
+	if err != nil {
+		return err
+	}
+	kept := profiles[:0]
+	for _, name := range profiles {
+		if name != profile {
+			kept = append(kept, name)
+		}
+	}
+	return s.saveIndex(kept)
+}
+
+// Profiles implements Store
+func (s *KeyringStore) Profiles() ([]string, error) {
+	secret, err := s.keyring.get(keyringIndex)
+	if errors.Is(err, errKeyringNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var profiles []string
+	if err := json.Unmarshal([]byte(secret), &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse profile list in keyring: %w", err)
+	}
+	sort.Strings(profiles)
+	return profiles, nil
+}
+
+func (s *KeyringStore) saveIndex(profiles []string) error {
+	sort.Strings(profiles)
+	data, err := json.Marshal(profiles)
+	if err != nil {
+		return fmt.Errorf("failed to encode profile list: %w", err)
+	}
+	return s.keyring.set(keyringIndex, string(data))
+}
+
+// profileAccount is the keyring account holding a profile's credentials
+func profileAccount(profile string) string {
+	return "profile:" + profile
+}
+
+// secretService uses the freedesktop Secret Service (GNOME Keyring, KWallet)
+// through libsecret's secret-tool command
+type secretService struct{}
+
+func (secretService) name() string {
+	return "the Secret Service keyring"
+}
+
+// available checks that secret-tool is installed and can reach a keyring daemon
+func (k secretService) available() bool {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return false
+	}
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return false
+	}
+	_, err := k.get("probe")
+	return err == nil || errors.Is(err, errKeyringNotFound)
+}
+
+func (secretService) get(account string) (string, error) {
+	cmd := exec.Command("secret-tool", "lookup", "service", keyringService, "account", account)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		// secret-tool exits with status 1 and says nothing when there is no such secret
+		if stderr.Len() == 0 {
+			return "", errKeyringNotFound
+		}
+		return "", fmt.Errorf("failed to read from keyring: %s", strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func (secretService) set(account, secret string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", keyringService+" "+account, "service", keyringService, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write to keyring: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (k secretService) delete(account string) error {
+	if _, err := k.get(account); err != nil {
+		return err
+	}
+	cmd := exec.Command("secret-tool", "clear", "service", keyringService, "account", account)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to delete from keyring: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// macKeychain uses the macOS login keychain through the security command
+type macKeychain struct{}
+
+// macNotFoundStatus is the exit status of security when an item doesn't exist
+const macNotFoundStatus = 44
+
+func (macKeychain) name() string {
+	return "the macOS keychain"
+}
+
+func (macKeychain) available() bool {
+	_, err := exec.LookPath("security")
+	return err == nil
+}
+
+func (macKeychain) get(account string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", keyringService, "-a", account, "-w").Output()
+	if err != nil {
+		return "", macError("read from", err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// set passes the secret to security on stdin, through its interactive mode, so
+// it doesn't show up in the process list. It is sent hex encoded, which needs
+// no quoting on security's command line.
+func (macKeychain) set(account, secret string) error {
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n",
+		keyringService, account, hex.EncodeToString([]byte(secret))))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	// The interactive mode reports a failed command on stderr, but may still exit successfully
+	if message := strings.TrimSpace(stderr.String()); message != "" {
+		return fmt.Errorf("failed to write to keychain: %s", message)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write to keychain: %w", err)
+	}
+	return nil
+}
+
+func (macKeychain) delete(account string) error {
+	_, err := exec.Command("security", "delete-generic-password", "-s", keyringService, "-a", account).Output()
+	if err != nil {
+		return macError("delete from", err)
+	}
+	return nil
+}
+
+// macError turns a failed security command into errKeyringNotFound or a readable error
+func macError(action string, err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.ExitCode() == macNotFoundStatus {
+			return errKeyringNotFound
+		}
+		return fmt.Errorf("failed to %s keychain: %s", action, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return fmt.Errorf("failed to %s keychain: %w", action, err)
+}
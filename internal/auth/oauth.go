@@ -0,0 +1,287 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Asana's OAuth endpoints, and the redirect URL the login listener uses by default
+const (
+	AuthorizeURL       = "https://app.asana.com/-/oauth_authorize"
+	TokenURL           = "https://app.asana.com/-/oauth_token"
+	DefaultRedirectURL = "http://localhost:8734/callback"
+)
+
+// Config describes the Asana OAuth app the sorter logs in with
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+
+	// AuthorizeURL and TokenURL default to Asana's endpoints
+	AuthorizeURL string
+	TokenURL     string
+
+	// HTTPClient is used for token requests; http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// Token is the result of an authorization code exchange or a refresh
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// tokenResponse is the body of a successful token endpoint response
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// tokenError is the body of a failed token endpoint response
+type tokenError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// NewVerifier returns a random PKCE code verifier
+func NewVerifier() (string, error) {
+	return randomString(32)
+}
+
+// Challenge returns the S256 PKCE code challenge for a verifier
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the URL the user visits to grant access
+func (c Config) AuthCodeURL(state, challenge string) string {
+	query := url.Values{
+		"client_id":             {c.ClientID},
+		"redirect_uri":          {c.RedirectURL},
+		"response_type":         {"code"},
+		"state":                 {state},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+	return c.authorizeURL() + "?" + query.Encode()
+}
+
+// Exchange trades an authorization code for tokens
+func (c Config) Exchange(ctx context.Context, code, verifier string) (*Token, error) {
+	return c.requestToken(ctx, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.RedirectURL},
+		"code_verifier": {verifier},
+	})
+}
+
+// Refresh obtains a new access token with a refresh token. Asana keeps the
+// refresh token the same unless the response carries a new one.
+func (c Config) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	token, err := c.requestToken(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"redirect_uri":  {c.RedirectURL},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// requestToken posts a grant to the token endpoint
+func (c Config) requestToken(ctx context.Context, form url.Values) (*Token, error) {
+	form.Set("client_id", c.ClientID)
+	if c.ClientSecret != "" {
+		form.Set("client_secret", c.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var tokenErr tokenError
+		if json.Unmarshal(body, &tokenErr) == nil && tokenErr.Error != "" {
+			if tokenErr.Description != "" {
+				return nil, fmt.Errorf("token request rejected: %s: %s", tokenErr.Error, tokenErr.Description)
+			}
+			return nil, fmt.Errorf("token request rejected: %s", tokenErr.Error)
+		}
+		return nil, fmt.Errorf("token request failed with status %d", resp.StatusCode)
+	}
+
+	var tokenResp tokenResponse
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return nil, errors.New("token response has no access token")
+	}
+	token := &Token{AccessToken: tokenResp.AccessToken, RefreshToken: tokenResp.RefreshToken}
+	if tokenResp.ExpiresIn > 0 {
+		token.ExpiresAt = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+func (c Config) authorizeURL() string {
+	if c.AuthorizeURL != "" {
+		return c.AuthorizeURL
+	}
+	return AuthorizeURL
+}
+
+func (c Config) tokenURL() string {
+	if c.TokenURL != "" {
+		return c.TokenURL
+	}
+	return TokenURL
+}
+
+// Login runs the authorization code flow with PKCE. It listens on the redirect
+// URL, which must be an http URL on the local machine, passes the URL the user
+// has to visit to open, and waits for Asana to redirect back with a code. A
+// redirect URL with port 0 listens on any free port. The redirect URL actually
+// used is set in the returned config, since refreshes must send it too.
+func Login(ctx context.Context, conf Config, open func(authURL string)) (*Token, Config, error) {
+	if conf.ClientID == "" {
+		return nil, conf, errors.New("an OAuth client ID is required")
+	}
+	if conf.RedirectURL == "" {
+		conf.RedirectURL = DefaultRedirectURL
+	}
+	redirect, err := url.Parse(conf.RedirectURL)
+	if err != nil {
+		return nil, conf, fmt.Errorf("invalid redirect URL: %w", err)
+	}
+	if redirect.Scheme != "http" || !isLoopback(redirect.Hostname()) {
+		return nil, conf, fmt.Errorf("redirect URL '%s' must be an http URL on localhost", conf.RedirectURL)
+	}
+
+	listener, err := net.Listen("tcp", redirect.Host)
+	if err != nil {
+		return nil, conf, fmt.Errorf("failed to listen for the redirect: %w", err)
+	}
+	if redirect.Port() == "0" {
+		redirect.Host = net.JoinHostPort(redirect.Hostname(), fmt.Sprint(listener.Addr().(*net.TCPAddr).Port))
+		conf.RedirectURL = redirect.String()
+	}
+
+	verifier, err := NewVerifier()
+	if err != nil {
+		listener.Close()
+		return nil, conf, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		listener.Close()
+		return nil, conf, err
+	}
+
+	// The first redirect carrying our state ends the flow, with a code or an error
+	type callback struct {
+		code string
+		err  error
+	}
+	callbacks := make(chan callback, 1)
+	path := redirect.Path
+	if path == "" {
+		path = "/"
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("state") != state {
+			http.Error(w, "Unexpected login request", http.StatusBadRequest)
+			return
+		}
+		result := callback{code: query.Get("code")}
+		if errCode := query.Get("error"); errCode != "" {
+			result.err = fmt.Errorf("authorization denied: %s", errCode)
+			fmt.Fprintln(w, "Login failed. You can close this window.")
+		} else if result.code == "" {
+			result.err = errors.New("redirect has no authorization code")
+			fmt.Fprintln(w, "Login failed. You can close this window.")
+		} else {
+			fmt.Fprintln(w, "Logged in to Asana Tasks Sorter. You can close this window.")
+		}
+		select {
+		case callbacks <- result:
+		default:
+		}
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	defer server.Close()
+
+	open(conf.AuthCodeURL(state, Challenge(verifier)))
+
+	select {
+	case <-ctx.Done():
+		return nil, conf, fmt.Errorf("waiting for the login redirect: %w", ctx.Err())
+	case result := <-callbacks:
+		if result.err != nil {
+			return nil, conf, result.err
+		}
+		token, err := conf.Exchange(ctx, result.code, verifier)
+		if err != nil {
+			return nil, conf, err
+		}
+		return token, conf, nil
+	}
+}
+
+// isLoopback reports whether host names the local machine
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// randomString returns n random bytes, base64url encoded
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
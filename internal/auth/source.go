@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// expiryMargin is how long before it expires an access token is refreshed
+const expiryMargin = time.Minute
+
+// TokenSource supplies the access token of saved credentials, refreshing it
+// when it is about to expire or Asana rejects it, and saving the new tokens.
+// It implements asana.TokenSource and is safe for concurrent use.
+type TokenSource struct {
+	mu    sync.Mutex
+	creds Credentials
+	save  func(Credentials) error
+}
+
+// NewTokenSource returns a token source for creds. save is called with the
+// credentials after each refresh; it may be nil.
+func NewTokenSource(creds Credentials, save func(Credentials) error) *TokenSource {
+	return &TokenSource{creds: creds, save: save}
+}
+
+// Token returns the current access token, refreshing it first if it is about to expire
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.creds.RefreshToken != "" && !s.creds.ExpiresAt.IsZero() && time.Until(s.creds.ExpiresAt) < expiryMargin {
+		if err := s.refreshLocked(ctx); err != nil {
+			return "", err
+		}
+	}
+	return s.creds.AccessToken, nil
+}
+
+// Refresh gets a new access token after Asana rejected the rejected one. When
+// another request has refreshed it in the meantime there is nothing to do.
+func (s *TokenSource) Refresh(ctx context.Context, rejected string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.creds.AccessToken != rejected {
+		return nil
+	}
+	return s.refreshLocked(ctx)
+}
+
+// refreshLocked replaces the access token; s.mu must be held
+func (s *TokenSource) refreshLocked(ctx context.Context) error {
+	if s.creds.RefreshToken == "" {
+		return errors.New("no refresh token saved; run the login command again")
+	}
+	token, err := s.creds.Config().Refresh(ctx, s.creds.RefreshToken)
+	if err != nil {
+		return err
+	}
+	s.creds.AccessToken = token.AccessToken
+	s.creds.RefreshToken = token.RefreshToken
+	s.creds.ExpiresAt = token.ExpiresAt
+	if s.save != nil {
+		if err := s.save(s.creds); err != nil {
+			return fmt.Errorf("failed to save refreshed credentials: %w", err)
+		}
+	}
+	return nil
+}
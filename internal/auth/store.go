@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// DefaultProfile is the profile used when none is chosen
+const DefaultProfile = "default"
+
+// ErrNoCredentials is returned when a profile has no stored credentials
+var ErrNoCredentials = errors.New("no stored credentials")
+
+// profileNamePattern limits profile names to what every store can hold as a key
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Store keeps credentials for named profiles
+type Store interface {
+	// Description names where the credentials are kept, for display
+	Description() string
+
+	// Load returns the credentials of a profile, or ErrNoCredentials
+	Load(profile string) (Credentials, error)
+
+	// Save stores the credentials of a profile, replacing any already there
+	Save(profile string, creds Credentials) error
+
+	// Delete removes the credentials of a profile, or returns ErrNoCredentials
+	Delete(profile string) error
+
+	// Profiles returns the names of the stored profiles, sorted
+	Profiles() ([]string, error)
+}
+
+// ValidateProfile checks that a profile name can be stored
+func ValidateProfile(profile string) error {
+	if !profileNamePattern.MatchString(profile) {
+		return fmt.Errorf("invalid profile name '%s': use letters, digits, '.', '_' and '-'", profile)
+	}
+	return nil
+}
+
+// OpenStore returns the OS keyring if one is available, and otherwise an
+// encrypted file at path
+func OpenStore(path string) Store {
+	if keyring := systemKeyring(); keyring != nil && keyring.available() {
+		return &KeyringStore{keyring: keyring}
+	}
+	return NewFileStore(path)
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+)
+
+// Config file formats
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// FormatForPath picks the format of a config file by its extension. Files
+// with other extensions are read as JSON, as they always have been.
+func FormatForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+	return FormatJSON
+}
+
+// parse reads a config file in the given format into a tree of nodes
+func parse(format string, data []byte) (*node, error) {
+	switch format {
+	case FormatYAML:
+		return parseYAML(data)
+	case FormatTOML:
+		return parseTOML(data)
+	}
+	return parseJSON(data)
+}
+
+// Encode writes a configuration in the given format. Settings left at their
+// zero value are left out.
+func Encode(config core.SectionConfig, format string) ([]byte, error) {
+	data, err := marshalJSON(config)
+	if err != nil {
+		return nil, err
+	}
+	if format == FormatJSON {
+		var out bytes.Buffer
+		if err := json.Indent(&out, data, "", "  "); err != nil {
+			return nil, fmt.Errorf("failed to encode config: %w", err)
+		}
+		out.WriteByte('\n')
+		return out.Bytes(), nil
+	}
+
+	// The JSON encoding keeps the settings in their declared order
+	root, err := parseJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	switch format {
+	case FormatYAML:
+		return encodeYAML(root), nil
+	case FormatTOML:
+		return encodeTOML(root)
+	}
+	return nil, fmt.Errorf("unknown config format '%s': use json, yaml or toml", format)
+}
+
+// marshalJSON encodes v as compact JSON without escaping HTML characters, so
+// its strings can be reused as YAML and TOML strings
+func marshalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	return bytes.TrimSpace(buf.Bytes()), nil
+}
+
+// quoteString writes s as a double-quoted string, which reads the same in JSON, YAML and TOML
+func quoteString(s string) string {
+	data, _ := marshalJSON(s)
+	return string(data)
+}
@@ -0,0 +1,351 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+)
+
+const appName = "asana-tasks-sorter"
+
+// ConfigEnv names a user config file to read instead of the one in UserConfigDir
+const ConfigEnv = "ASANA_SORTER_CONFIG"
+
+// EnvPrefix starts the environment variables that set a single setting, such as
+// ASANA_SORTER_WORKSPACE for "workspace"
+const EnvPrefix = "ASANA_SORTER_"
+
+// projectConfigBase is the name, without extension, of the config file looked
+// for in the current directory
+const projectConfigBase = ".asana-sorter"
+
+// configExtensions are the extensions config files are discovered with
+var configExtensions = []string{".json", ".yaml", ".yml", ".toml"}
+
+// Options chooses the layers of a configuration
+type Options struct {
+	// File is a config file to read instead of the discovered ones, "default"
+	// for the built-in defaults alone, or empty to discover the config files
+	File string
+	// Flags are the settings given on the command line, by config key
+	Flags map[string]interface{}
+}
+
+// Origin is where the value of a setting came from
+type Origin struct {
+	// Source is "default", a config file, "env NAME" or "flag --name"
+	Source string
+	// Line and Column locate the setting in a config file
+	Line   int
+	Column int
+}
+
+func (o Origin) String() string {
+	if o.Line == 0 {
+		return o.Source
+	}
+	return fmt.Sprintf("%s:%d:%d", o.Source, o.Line, o.Column)
+}
+
+// Setting is the effective value of a setting and where it came from
+type Setting struct {
+	Key    string
+	Value  interface{}
+	Origin Origin
+}
+
+// Effective is a configuration merged from its layers
+type Effective struct {
+	Config core.SectionConfig
+	// Files are the config files that were read, lowest precedence first
+	Files []string
+	// Settings are the settings given by any layer, in the order they are declared
+	Settings []Setting
+}
+
+// layer is one source of settings
+type layer struct {
+	source string
+	// file is set when the settings' positions are in the source
+	file bool
+	root *node
+}
+
+// UserConfigDir returns the directory the user config file is looked for in:
+// $XDG_CONFIG_HOME/asana-tasks-sorter, or ~/.config/asana-tasks-sorter
+func UserConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, appName)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", appName)
+}
+
+// Discover returns the config files that apply, lowest precedence first: the
+// file named by $ASANA_SORTER_CONFIG or a config.* file in UserConfigDir, then
+// a .asana-sorter.* file in the current directory
+func Discover() ([]string, error) {
+	var files []string
+	if path := os.Getenv(ConfigEnv); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("config file named by %s: %w", ConfigEnv, err)
+		}
+		files = append(files, path)
+	} else if dir := UserConfigDir(); dir != "" {
+		path, err := findConfigFile(filepath.Join(dir, "config"))
+		if err != nil {
+			return nil, err
+		}
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+
+	path, err := findConfigFile(projectConfigBase)
+	if err != nil {
+		return nil, err
+	}
+	if path != "" {
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// findConfigFile looks for base with each config file extension, returning ""
+// when there is none. More than one is an error, as it isn't clear which is meant.
+func findConfigFile(base string) (string, error) {
+	var found []string
+	for _, ext := range configExtensions {
+		info, err := os.Stat(base + ext)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return "", fmt.Errorf("failed to look for config file: %w", err)
+		}
+		if !info.IsDir() {
+			found = append(found, base+ext)
+		}
+	}
+	if len(found) > 1 {
+		return "", fmt.Errorf("found both %s and %s; keep only one of them", found[0], found[1])
+	}
+	if len(found) == 0 {
+		return "", nil
+	}
+	return found[0], nil
+}
+
+// Load merges the configuration from its layers: the built-in defaults, the
+// config files, the ASANA_SORTER_* environment variables and the flags, each
+// overriding the ones before it. Files only need the settings they change, and
+// a setting replaces the earlier value as a whole, lists included. Every
+// problem in every layer is reported in a *ValidationError.
+func Load(opts Options) (*Effective, error) {
+	var files []string
+	switch opts.File {
+	case "":
+		var err error
+		if files, err = Discover(); err != nil {
+			return nil, err
+		}
+	case "default":
+	default:
+		files = []string{opts.File}
+	}
+	return merge(files, append(envLayers(), flagLayers(opts.Flags)...))
+}
+
+// merge layers the config files and then the settings over the defaults, and
+// validates the result
+func merge(files []string, settingLayers []layer) (*Effective, error) {
+	defaults, err := marshalJSON(core.DefaultSectionConfig())
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseJSON(defaults)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	layers := []layer{{source: "default", root: root}}
+
+	var problems []Problem
+	for _, file := range files {
+		root, fileProblems, err := readConfigFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		problems = append(problems, fileProblems...)
+		if root != nil && root.kind == objectNode {
+			layers = append(layers, layer{source: file, file: true, root: root})
+		}
+	}
+
+	// The environment and flags only hold single settings, checked like files
+	for _, l := range settingLayers {
+		problems = append(problems, checkSchema(l.source, l.root, reflect.TypeOf(core.SectionConfig{}), "")...)
+	}
+	layers = append(layers, settingLayers...)
+
+	// Later layers replace the settings of earlier ones
+	merged := &node{kind: objectNode}
+	owners := make(map[string]layer)
+	origins := make(map[string]Origin)
+	for _, l := range layers {
+		for _, f := range l.root.fields {
+			if _, ok := owners[f.key]; ok {
+				for i := range merged.fields {
+					if merged.fields[i].key == f.key {
+						merged.fields[i] = f
+					}
+				}
+			} else {
+				merged.fields = append(merged.fields, f)
+			}
+			owners[f.key] = l
+			origin := Origin{Source: l.source}
+			if l.file {
+				origin.Line, origin.Column = f.pos.Line, f.pos.Column
+			}
+			origins[f.key] = origin
+		}
+	}
+
+	config, err := decodeConfig(merged, len(problems) > 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Problems with the merged settings are reported in the layer that set them
+	for _, configProblem := range config.Problems() {
+		key := configProblem.Field
+		if i := strings.IndexAny(key, ".["); i >= 0 {
+			key = key[:i]
+		}
+		owner, ok := owners[key]
+		if !ok {
+			owner = layers[0]
+		}
+		if owner.file {
+			problems = append(problems, locateProblems(owner.source, owner.root, []core.ConfigProblem{configProblem})...)
+		} else {
+			problems = append(problems, Problem{File: owner.source, Message: configProblem.Message})
+		}
+	}
+
+	if len(problems) > 0 {
+		sources := append([]string{"default"}, files...)
+		for _, l := range settingLayers {
+			sources = append(sources, l.source)
+		}
+		sortProblems(problems, sources)
+		return nil, &ValidationError{File: problemsFile(problems, files), Problems: problems}
+	}
+
+	effective := &Effective{Config: config, Files: files}
+	for _, key := range configKeys() {
+		if value := merged.lookup(key); value != nil {
+			effective.Settings = append(effective.Settings, Setting{Key: key, Value: value.toValue(), Origin: origins[key]})
+		}
+	}
+	return effective, nil
+}
+
+// envLayers reads the settings that can be given as environment variables,
+// one layer each. Lists and rules can only be set in config files.
+func envLayers() []layer {
+	fields := jsonFields(reflect.TypeOf(core.SectionConfig{}))
+	var layers []layer
+	for _, key := range configKeys() {
+		kind := fields[key].Kind()
+		if kind != reflect.String && kind != reflect.Int && kind != reflect.Bool {
+			continue
+		}
+		name := EnvPrefix + strings.ToUpper(key)
+		text := os.Getenv(name)
+		if text == "" {
+			continue
+		}
+
+		// Values that don't parse are kept as strings, for the schema check to report
+		var value interface{} = text
+		switch kind {
+		case reflect.Int:
+			if _, err := strconv.Atoi(text); err == nil {
+				value = json.Number(text)
+			}
+		case reflect.Bool:
+			if b, err := strconv.ParseBool(text); err == nil {
+				value = b
+			}
+		}
+		layers = append(layers, settingLayer("env "+name, key, value))
+	}
+	return layers
+}
+
+// flagLayers turns the settings given as flags into layers, one each
+func flagLayers(flags map[string]interface{}) []layer {
+	keys := make([]string, 0, len(flags))
+	for key := range flags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var layers []layer
+	for _, key := range keys {
+		value := flags[key]
+		if n, ok := value.(int); ok {
+			value = json.Number(strconv.Itoa(n))
+		}
+		layers = append(layers, settingLayer("flag --"+strings.ReplaceAll(key, "_", "-"), key, value))
+	}
+	return layers
+}
+
+// settingLayer returns a layer holding a single setting
+func settingLayer(source, key string, value interface{}) layer {
+	return layer{source: source, root: &node{kind: objectNode, fields: []field{
+		{key: key, value: &node{kind: scalarNode, value: value}},
+	}}}
+}
+
+// configKeys lists the keys of the settings in the order they are declared
+func configKeys() []string {
+	t := reflect.TypeOf(core.SectionConfig{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+// problemsFile returns the config file all the problems are in, or "" when
+// they are in several layers or in one that isn't a file
+func problemsFile(problems []Problem, files []string) string {
+	for _, problem := range problems[1:] {
+		if problem.File != problems[0].File {
+			return ""
+		}
+	}
+	for _, file := range files {
+		if file == problems[0].File {
+			return file
+		}
+	}
+	return ""
+}
@@ -2,52 +2,96 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 
 	"github.com/dackerman/asana-tasks-sorter/internal/core"
 )
 
-// LoadConfiguration loads the configuration from a file or returns defaults
-func LoadConfiguration(configFile string) core.SectionConfig {
-	// If the user explicitly asked for defaults
-	if configFile == "default" {
-		return core.DefaultSectionConfig()
-	}
-	
-	// Try to load from the file
-	loadedConfig, err := loadSectionConfig(configFile)
-	if err == nil {
-		return loadedConfig
-	}
-	
-	fmt.Printf("Error loading section config: %v\nUsing default configuration\n", err)
-	return core.DefaultSectionConfig()
+// LoadConfiguration loads the configuration from a single JSON, YAML or TOML
+// file layered over the defaults, like Load does without the environment and
+// flags, or returns the defaults when configFile is "default". Every problem
+// in the file is reported in a *ValidationError.
+func LoadConfiguration(configFile string) (core.SectionConfig, error) {
+	var files []string
+	if configFile != "default" {
+		files = []string{configFile}
+	}
+	effective, err := merge(files, nil)
+	if err != nil {
+		return core.SectionConfig{}, err
+	}
+	return effective.Config, nil
 }
 
-// loadSectionConfig loads the section configuration from a JSON file
-func loadSectionConfig(configPath string) (core.SectionConfig, error) {
+// readConfigFile parses a config file, keeping track of where each setting is,
+// and checks it for unknown keys and values of the wrong type. A file that
+// can't be parsed at all has a nil root and its syntax error as the only problem.
+func readConfigFile(configPath string) (*node, []Problem, error) {
 	// Handle relative paths
+	absPath := configPath
 	if !filepath.IsAbs(configPath) {
-		absPath, err := filepath.Abs(configPath)
+		var err error
+		absPath, err = filepath.Abs(configPath)
 		if err != nil {
-			return core.SectionConfig{}, fmt.Errorf("failed to resolve absolute path: %w", err)
+			return nil, nil, fmt.Errorf("failed to resolve absolute path: %w", err)
 		}
-		configPath = absPath
 	}
 
 	// Read config file
-	configData, err := os.ReadFile(configPath)
+	configData, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	root, err := parse(FormatForPath(configPath), configData)
 	if err != nil {
-		return core.SectionConfig{}, fmt.Errorf("failed to read config file: %w", err)
+		var syntaxErr *syntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, []Problem{problemAt(configPath, syntaxErr.pos, "%s", syntaxErr.msg)}, nil
+		}
+		return nil, nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	return root, checkSchema(configPath, root, reflect.TypeOf(core.SectionConfig{}), ""), nil
+}
+
+// decodeConfig decodes a parsed config through its JSON equivalent. When the
+// schema check already found problems, values of the wrong type are left out of
+// the decoded config, so the rest can still be checked.
+func decodeConfig(root *node, hasProblems bool) (core.SectionConfig, error) {
+	data, err := marshalJSON(root.toValue())
+	if err != nil {
+		return core.SectionConfig{}, err
 	}
 
-	// Parse JSON
 	var config core.SectionConfig
-	if err := json.Unmarshal(configData, &config); err != nil {
+	err = json.Unmarshal(data, &config)
+	var typeErr *json.UnmarshalTypeError
+	if err != nil && !(errors.As(err, &typeErr) && hasProblems) {
 		return core.SectionConfig{}, fmt.Errorf("failed to parse config file: %w", err)
 	}
-
 	return config, nil
-}
\ No newline at end of file
+}
+
+// sortProblems puts problems in the order of the sources they are in, and in
+// file order within each, with missing settings last
+func sortProblems(problems []Problem, sources []string) {
+	rank := make(map[string]int, len(sources))
+	for i, source := range sources {
+		rank[source] = i
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		if rank[problems[i].File] != rank[problems[j].File] {
+			return rank[problems[i].File] < rank[problems[j].File]
+		}
+		if (problems[i].Line == 0) != (problems[j].Line == 0) {
+			return problems[j].Line == 0
+		}
+		return problems[i].Line < problems[j].Line
+	})
+}
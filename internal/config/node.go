@@ -0,0 +1,225 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// position is a line and column in a config file, both counted from 1
+type position struct {
+	Line   int
+	Column int
+}
+
+// nodeKind tells what a node holds
+type nodeKind int
+
+const (
+	scalarNode nodeKind = iota
+	objectNode
+	arrayNode
+)
+
+// node is a value read from a config file, remembering where it appeared so
+// problems with it can be reported there
+type node struct {
+	kind nodeKind
+	pos  position
+
+	// value of a scalar: a string, bool, json.Number or nil
+	value  interface{}
+	fields []field
+	items  []*node
+}
+
+// field is a key of an object node and its value
+type field struct {
+	key   string
+	pos   position
+	value *node
+}
+
+// syntaxError is a config file that can't be parsed at all
+type syntaxError struct {
+	pos position
+	msg string
+}
+
+func (e *syntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.pos.Line, e.pos.Column, e.msg)
+}
+
+// lineIndex converts byte offsets in a file to positions
+type lineIndex []int
+
+func newLineIndex(data []byte) lineIndex {
+	index := lineIndex{0}
+	for i, b := range data {
+		if b == '\n' {
+			index = append(index, i+1)
+		}
+	}
+	return index
+}
+
+func (l lineIndex) position(offset int) position {
+	line := sort.Search(len(l), func(i int) bool { return l[i] > offset })
+	return position{Line: line, Column: offset - l[line-1] + 1}
+}
+
+// parseJSON reads a JSON document into a tree of nodes
+func parseJSON(data []byte) (*node, error) {
+	p := &jsonParser{data: data, lines: newLineIndex(data), dec: json.NewDecoder(bytes.NewReader(data))}
+	p.dec.UseNumber()
+
+	root, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.dec.Token(); err != io.EOF {
+		return nil, &syntaxError{pos: p.lines.position(p.next()), msg: "unexpected content after the end of the config"}
+	}
+	return root, nil
+}
+
+// jsonParser walks the tokens of a JSON document, tracking their offsets
+type jsonParser struct {
+	data  []byte
+	lines lineIndex
+	dec   *json.Decoder
+}
+
+// next returns the offset of the next token, skipping the whitespace and
+// separators the decoder hasn't consumed yet
+func (p *jsonParser) next() int {
+	offset := int(p.dec.InputOffset())
+	for offset < len(p.data) && bytes.IndexByte([]byte(" \t\r\n,:"), p.data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// token reads the next token, converting decoder errors to syntax errors
+func (p *jsonParser) token() (json.Token, position, error) {
+	pos := p.lines.position(p.next())
+	tok, err := p.dec.Token()
+	if err == io.EOF {
+		return nil, pos, &syntaxError{pos: pos, msg: "unexpected end of file"}
+	}
+	var jsonErr *json.SyntaxError
+	if errors.As(err, &jsonErr) {
+		return nil, pos, &syntaxError{pos: p.lines.position(int(jsonErr.Offset)), msg: jsonErr.Error()}
+	}
+	if err != nil {
+		return nil, pos, &syntaxError{pos: pos, msg: err.Error()}
+	}
+	return tok, pos, nil
+}
+
+func (p *jsonParser) value() (*node, error) {
+	tok, pos, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		n := &node{kind: objectNode, pos: pos}
+		for p.dec.More() {
+			keyTok, keyPos, err := p.token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			n.fields = append(n.fields, field{key: keyTok.(string), pos: keyPos, value: value})
+		}
+		if _, _, err := p.token(); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case json.Delim('['):
+		n := &node{kind: arrayNode, pos: pos}
+		for p.dec.More() {
+			item, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, item)
+		}
+		if _, _, err := p.token(); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	return &node{kind: scalarNode, pos: pos, value: tok}, nil
+}
+
+// positions returns where each setting appears, keyed by its path such as "rules[2].section"
+func (n *node) positions() map[string]position {
+	positions := make(map[string]position)
+	var walk func(n *node, path string)
+	walk = func(n *node, path string) {
+		switch n.kind {
+		case objectNode:
+			for _, f := range n.fields {
+				fieldPath := joinPath(path, f.key)
+				if _, ok := positions[fieldPath]; !ok {
+					positions[fieldPath] = f.pos
+				}
+				walk(f.value, fieldPath)
+			}
+		case arrayNode:
+			for i, item := range n.items {
+				itemPath := fmt.Sprintf("%s[%d]", path, i)
+				positions[itemPath] = item.pos
+				walk(item, itemPath)
+			}
+		}
+	}
+	walk(n, "")
+	return positions
+}
+
+// joinPath appends a key to the path of the object holding it
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// toValue converts the tree to the maps, slices and scalars encoding/json works with
+func (n *node) toValue() interface{} {
+	switch n.kind {
+	case objectNode:
+		object := make(map[string]interface{}, len(n.fields))
+		for _, f := range n.fields {
+			object[f.key] = f.value.toValue()
+		}
+		return object
+	case arrayNode:
+		items := make([]interface{}, len(n.items))
+		for i, item := range n.items {
+			items[i] = item.toValue()
+		}
+		return items
+	}
+	return n.value
+}
+
+// lookup returns the value of a key in an object node, or nil
+func (n *node) lookup(key string) *node {
+	for _, f := range n.fields {
+		if f.key == key {
+			return f.value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,576 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The TOML reader handles TOML 1.0 apart from dates and times, which no
+// setting uses: tables, arrays of tables, dotted keys, inline tables, arrays,
+// all four kinds of strings, integers, floats, booleans and comments.
+
+// tomlParser reads a TOML document character by character, tracking positions
+type tomlParser struct {
+	text  string
+	i     int
+	lines lineIndex
+
+	root *node
+	// defined records the tables created by a header, which can't be defined again
+	defined map[*node]bool
+}
+
+// parseTOML reads a TOML document into a tree of nodes
+func parseTOML(data []byte) (*node, error) {
+	p := &tomlParser{
+		text:    string(data),
+		lines:   newLineIndex(data),
+		root:    &node{kind: objectNode, pos: position{Line: 1, Column: 1}},
+		defined: make(map[*node]bool),
+	}
+
+	current := p.root
+	for {
+		p.skipBlank()
+		if p.i >= len(p.text) {
+			return p.root, nil
+		}
+
+		var err error
+		if strings.HasPrefix(p.text[p.i:], "[[") {
+			current, err = p.arrayTableHeader()
+		} else if p.text[p.i] == '[' {
+			current, err = p.tableHeader()
+		} else {
+			err = p.keyValue(current)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := p.endOfLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *tomlParser) pos() position {
+	return p.lines.position(p.i)
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return &syntaxError{pos: p.pos(), msg: fmt.Sprintf(format, args...)}
+}
+
+// skipSpace skips spaces and tabs within a line
+func (p *tomlParser) skipSpace() {
+	for p.i < len(p.text) && (p.text[p.i] == ' ' || p.text[p.i] == '\t') {
+		p.i++
+	}
+}
+
+// skipBlank skips whitespace, newlines and comments
+func (p *tomlParser) skipBlank() {
+	for p.i < len(p.text) {
+		switch p.text[p.i] {
+		case ' ', '\t', '\r', '\n':
+			p.i++
+		case '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) skipComment() {
+	for p.i < len(p.text) && p.text[p.i] != '\n' {
+		p.i++
+	}
+}
+
+// endOfLine checks that nothing but a comment follows a statement
+func (p *tomlParser) endOfLine() error {
+	p.skipSpace()
+	if p.i < len(p.text) && p.text[p.i] == '#' {
+		p.skipComment()
+	}
+	if p.i < len(p.text) && p.text[p.i] != '\n' && p.text[p.i] != '\r' {
+		return p.errorf("expected the end of the line, got '%s'", p.restOfLine())
+	}
+	return nil
+}
+
+func (p *tomlParser) restOfLine() string {
+	end := strings.IndexByte(p.text[p.i:], '\n')
+	if end < 0 {
+		return strings.TrimSpace(p.text[p.i:])
+	}
+	return strings.TrimSpace(p.text[p.i : p.i+end])
+}
+
+// tableHeader reads "[a.b]" and returns the table it names
+func (p *tomlParser) tableHeader() (*node, error) {
+	pos := p.pos()
+	p.i++
+	keys, _, err := p.key(']')
+	if err != nil {
+		return nil, err
+	}
+	p.i++
+
+	table, err := p.table(p.root, keys, pos)
+	if err != nil {
+		return nil, err
+	}
+	if p.defined[table] {
+		return nil, &syntaxError{pos: pos, msg: fmt.Sprintf("table [%s] is defined more than once", strings.Join(keys, "."))}
+	}
+	p.defined[table] = true
+	return table, nil
+}
+
+// arrayTableHeader reads "[[a.b]]" and returns the new table it appends to the array
+func (p *tomlParser) arrayTableHeader() (*node, error) {
+	pos := p.pos()
+	p.i += 2
+	keys, _, err := p.key(']')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(p.text[p.i:], "]]") {
+		return nil, p.errorf("expected ']]'")
+	}
+	p.i += 2
+
+	parent, err := p.table(p.root, keys[:len(keys)-1], pos)
+	if err != nil {
+		return nil, err
+	}
+	last := keys[len(keys)-1]
+	array := parent.lookup(last)
+	if array == nil {
+		array = &node{kind: arrayNode, pos: pos}
+		parent.fields = append(parent.fields, field{key: last, pos: pos, value: array})
+	} else if array.kind != arrayNode {
+		return nil, &syntaxError{pos: pos, msg: fmt.Sprintf("'%s' is already set and isn't an array of tables", strings.Join(keys, "."))}
+	}
+	table := &node{kind: objectNode, pos: pos}
+	array.items = append(array.items, table)
+	return table, nil
+}
+
+// table finds or creates the table at keys below parent. Through an array of
+// tables, the last table in the array is used.
+func (p *tomlParser) table(parent *node, keys []string, pos position) (*node, error) {
+	for i, key := range keys {
+		child := parent.lookup(key)
+		switch {
+		case child == nil:
+			child = &node{kind: objectNode, pos: pos}
+			parent.fields = append(parent.fields, field{key: key, pos: pos, value: child})
+		case child.kind == arrayNode && len(child.items) > 0 && child.items[len(child.items)-1].kind == objectNode:
+			child = child.items[len(child.items)-1]
+		case child.kind != objectNode:
+			return nil, &syntaxError{pos: pos, msg: fmt.Sprintf("'%s' is already set and isn't a table", strings.Join(keys[:i+1], "."))}
+		}
+		parent = child
+	}
+	return parent, nil
+}
+
+// keyValue reads "key = value" into table
+func (p *tomlParser) keyValue(table *node) error {
+	keys, pos, err := p.key('=')
+	if err != nil {
+		return err
+	}
+	p.i++
+	p.skipSpace()
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+
+	parent, err := p.table(table, keys[:len(keys)-1], pos)
+	if err != nil {
+		return err
+	}
+	// A key given twice is kept twice, so it is reported with the other problems
+	parent.fields = append(parent.fields, field{key: keys[len(keys)-1], pos: pos, value: value})
+	return nil
+}
+
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+`)
+
+// key reads a possibly dotted key up to the terminator, which is left unread
+func (p *tomlParser) key(terminator byte) ([]string, position, error) {
+	p.skipSpace()
+	pos := p.pos()
+	var keys []string
+	for {
+		p.skipSpace()
+		if p.i >= len(p.text) {
+			return nil, pos, p.errorf("unexpected end of file in a key")
+		}
+		switch c := p.text[p.i]; {
+		case c == '"' || c == '\'':
+			key, err := p.singleLineString()
+			if err != nil {
+				return nil, pos, err
+			}
+			keys = append(keys, key)
+		default:
+			bare := tomlBareKey.FindString(p.text[p.i:])
+			if bare == "" {
+				return nil, pos, p.errorf("expected a key, got '%s'", p.restOfLine())
+			}
+			keys = append(keys, bare)
+			p.i += len(bare)
+		}
+
+		p.skipSpace()
+		if p.i < len(p.text) && p.text[p.i] == '.' {
+			p.i++
+			continue
+		}
+		if p.i >= len(p.text) || p.text[p.i] != terminator {
+			return nil, pos, p.errorf("expected '%c' after the key", terminator)
+		}
+		return keys, pos, nil
+	}
+}
+
+var (
+	tomlDate   = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}|^\d{2}:\d{2}`)
+	tomlNumber = regexp.MustCompile(`^[-+]?[0-9A-Za-z_.]+([-+][0-9_]+)?`)
+	// Decimal integers have no leading zeros, and underscores only between digits
+	tomlInteger = regexp.MustCompile(`^[-+]?(0|[1-9](_?[0-9])*)$`)
+	// Floats need digits on both sides of the point, and a fraction or an exponent
+	tomlFloat = regexp.MustCompile(`^[-+]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][-+]?[0-9](_?[0-9])*)?$`)
+)
+
+// value reads a string, number, boolean, array or inline table
+func (p *tomlParser) value() (*node, error) {
+	pos := p.pos()
+	if p.i >= len(p.text) {
+		return nil, p.errorf("expected a value")
+	}
+	rest := p.text[p.i:]
+
+	switch {
+	case rest[0] == '"' || rest[0] == '\'':
+		s, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: scalarNode, pos: pos, value: s}, nil
+	case rest[0] == '[':
+		return p.array()
+	case rest[0] == '{':
+		return p.inlineTable()
+	case strings.HasPrefix(rest, "true") && !tomlBareKey.MatchString(rest[4:]):
+		p.i += 4
+		return &node{kind: scalarNode, pos: pos, value: true}, nil
+	case strings.HasPrefix(rest, "false") && !tomlBareKey.MatchString(rest[5:]):
+		p.i += 5
+		return &node{kind: scalarNode, pos: pos, value: false}, nil
+	case tomlDate.MatchString(rest):
+		return nil, p.errorf("dates and times are not supported in the config")
+	}
+
+	text := tomlNumber.FindString(rest)
+	number, ok := tomlNumberValue(text)
+	if !ok {
+		return nil, p.errorf("invalid value '%s'", p.restOfLine())
+	}
+	p.i += len(text)
+	return &node{kind: scalarNode, pos: pos, value: number}, nil
+}
+
+// tomlNumberValue converts a TOML integer or float to a JSON number
+func tomlNumberValue(text string) (json.Number, bool) {
+	if text == "" || strings.HasPrefix(text, "_") || strings.HasSuffix(text, "_") || strings.Contains(text, "__") {
+		return "", false
+	}
+	digits := strings.ReplaceAll(text, "_", "")
+	for _, prefix := range []string{"0x", "0o", "0b"} {
+		if strings.HasPrefix(digits, prefix) {
+			i, err := strconv.ParseInt(digits, 0, 64)
+			if err != nil {
+				return "", false
+			}
+			return json.Number(strconv.FormatInt(i, 10)), true
+		}
+	}
+	if tomlInteger.MatchString(text) {
+		i, err := strconv.ParseInt(digits, 10, 64)
+		if err != nil {
+			return "", false
+		}
+		return json.Number(strconv.FormatInt(i, 10)), true
+	}
+	if tomlFloat.MatchString(text) {
+		if f, err := strconv.ParseFloat(digits, 64); err == nil {
+			return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), true
+		}
+	}
+	return "", false
+}
+
+// array reads "[a, b]", which may span lines and hold comments
+func (p *tomlParser) array() (*node, error) {
+	n := &node{kind: arrayNode, pos: p.pos()}
+	p.i++
+	for {
+		p.skipBlank()
+		if p.i >= len(p.text) {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.text[p.i] == ']' {
+			p.i++
+			return n, nil
+		}
+		item, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		n.items = append(n.items, item)
+		p.skipBlank()
+		if p.i < len(p.text) && p.text[p.i] == ',' {
+			p.i++
+		} else if p.i >= len(p.text) || p.text[p.i] != ']' {
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+// inlineTable reads "{ a = 1, b = 2 }" on a single line
+func (p *tomlParser) inlineTable() (*node, error) {
+	n := &node{kind: objectNode, pos: p.pos()}
+	p.i++
+	p.skipSpace()
+	if p.i < len(p.text) && p.text[p.i] == '}' {
+		p.i++
+		return n, nil
+	}
+	for {
+		if err := p.keyValue(n); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.i >= len(p.text) {
+			return nil, p.errorf("unterminated inline table")
+		}
+		switch p.text[p.i] {
+		case ',':
+			p.i++
+		case '}':
+			p.i++
+			return n, nil
+		default:
+			return nil, p.errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
+
+// string reads any of the four kinds of TOML string
+func (p *tomlParser) string() (string, error) {
+	rest := p.text[p.i:]
+	if strings.HasPrefix(rest, `"""`) || strings.HasPrefix(rest, "'''") {
+		return p.multiLineString()
+	}
+	return p.singleLineString()
+}
+
+// singleLineString reads a basic "..." or literal '...' string
+func (p *tomlParser) singleLineString() (string, error) {
+	start := p.pos()
+	quote := p.text[p.i]
+	p.i++
+	var out strings.Builder
+	for p.i < len(p.text) {
+		c := p.text[p.i]
+		switch {
+		case c == quote:
+			p.i++
+			return out.String(), nil
+		case c == '\n':
+			return "", &syntaxError{pos: start, msg: "strings must be closed on the same line"}
+		case c == '\\' && quote == '"':
+			if err := p.escape(&out); err != nil {
+				return "", err
+			}
+		default:
+			out.WriteByte(c)
+			p.i++
+		}
+	}
+	return "", &syntaxError{pos: start, msg: "unterminated string"}
+}
+
+// multiLineString reads a multi-line basic or literal string
+func (p *tomlParser) multiLineString() (string, error) {
+	start := p.pos()
+	quote := p.text[p.i : p.i+3]
+	p.i += 3
+	// A newline right after the opening quotes isn't part of the string
+	if strings.HasPrefix(p.text[p.i:], "\r\n") {
+		p.i += 2
+	} else if strings.HasPrefix(p.text[p.i:], "\n") {
+		p.i++
+	}
+
+	var out strings.Builder
+	for p.i < len(p.text) {
+		if strings.HasPrefix(p.text[p.i:], quote) {
+			// Up to two quotes may directly precede the closing ones
+			p.i += 3
+			for extra := 0; extra < 2 && p.i < len(p.text) && p.text[p.i] == quote[0]; extra++ {
+				out.WriteByte(quote[0])
+				p.i++
+			}
+			return out.String(), nil
+		}
+		c := p.text[p.i]
+		if c == '\\' && quote == `"""` {
+			// A backslash at the end of a line trims the line break and the indentation after it
+			j := p.i + 1
+			for j < len(p.text) && (p.text[j] == ' ' || p.text[j] == '\t') {
+				j++
+			}
+			if j < len(p.text) && (p.text[j] == '\n' || p.text[j] == '\r') {
+				p.i = j
+				for p.i < len(p.text) && strings.ContainsRune(" \t\r\n", rune(p.text[p.i])) {
+					p.i++
+				}
+				continue
+			}
+			if err := p.escape(&out); err != nil {
+				return "", err
+			}
+			continue
+		}
+		out.WriteByte(c)
+		p.i++
+	}
+	return "", &syntaxError{pos: start, msg: "unterminated multi-line string"}
+}
+
+// escape reads an escape sequence in a basic string
+func (p *tomlParser) escape(out *strings.Builder) error {
+	if p.i+1 >= len(p.text) {
+		return p.errorf("incomplete escape sequence")
+	}
+	c := p.text[p.i+1]
+	simple := map[byte]byte{'b': '\b', 't': '\t', 'n': '\n', 'f': '\f', 'r': '\r', '"': '"', '\\': '\\'}
+	if r, ok := simple[c]; ok {
+		out.WriteByte(r)
+		p.i += 2
+		return nil
+	}
+	size := map[byte]int{'u': 4, 'U': 8}[c]
+	if size == 0 || p.i+2+size > len(p.text) {
+		return p.errorf("invalid escape sequence '\\%c'", c)
+	}
+	code, err := strconv.ParseUint(p.text[p.i+2:p.i+2+size], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return p.errorf("invalid escape sequence '%s'", p.text[p.i:p.i+2+size])
+	}
+	out.WriteRune(rune(code))
+	p.i += 2 + size
+	return nil
+}
+
+// encodeTOML writes a tree of nodes as a TOML document. Settings holding
+// lists of objects become arrays of tables, and other objects inline tables.
+func encodeTOML(root *node) ([]byte, error) {
+	var out strings.Builder
+	var arrayTables []field
+	for _, f := range root.fields {
+		if isArrayOfTables(f.value) {
+			arrayTables = append(arrayTables, f)
+			continue
+		}
+		value, err := tomlValue(f.value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", f.key, err)
+		}
+		out.WriteString(tomlKey(f.key) + " = " + value + "\n")
+	}
+
+	for _, f := range arrayTables {
+		for _, item := range f.value.items {
+			out.WriteString("\n[[" + tomlKey(f.key) + "]]\n")
+			for _, itemField := range item.fields {
+				value, err := tomlValue(itemField.value)
+				if err != nil {
+					return nil, fmt.Errorf("failed to encode %s.%s: %w", f.key, itemField.key, err)
+				}
+				out.WriteString(tomlKey(itemField.key) + " = " + value + "\n")
+			}
+		}
+	}
+	return []byte(out.String()), nil
+}
+
+// isArrayOfTables reports whether n is a non-empty list of objects
+func isArrayOfTables(n *node) bool {
+	if n.kind != arrayNode || len(n.items) == 0 {
+		return false
+	}
+	for _, item := range n.items {
+		if item.kind != objectNode {
+			return false
+		}
+	}
+	return true
+}
+
+// tomlValue writes a value on one line
+func tomlValue(n *node) (string, error) {
+	switch n.kind {
+	case objectNode:
+		parts := make([]string, 0, len(n.fields))
+		for _, f := range n.fields {
+			value, err := tomlValue(f.value)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, tomlKey(f.key)+" = "+value)
+		}
+		if len(parts) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(parts, ", ") + " }", nil
+	case arrayNode:
+		parts := make([]string, 0, len(n.items))
+		for _, item := range n.items {
+			value, err := tomlValue(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, value)
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	}
+
+	switch v := n.value.(type) {
+	case nil:
+		return "", fmt.Errorf("TOML has no null value")
+	case string:
+		return quoteString(v), nil
+	}
+	return fmt.Sprint(n.value), nil
+}
+
+// tomlKey writes a key bare when it can be, and quoted otherwise
+func tomlKey(key string) string {
+	if tomlBareKey.FindString(key) == key && key != "" {
+		return key
+	}
+	return quoteString(key)
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+)
+
+// Problem is a single mistake in a config file
+type Problem struct {
+	File string
+	// Line and Column locate the mistake; zero when the setting is missing from the file
+	Line    int
+	Column  int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s", p.File, p.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", p.File, p.Line, p.Column, p.Message)
+}
+
+// ValidationError lists every problem found in a config file. File is empty
+// when the problems are spread over the layers of a merged configuration.
+type ValidationError struct {
+	File     string
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	count := fmt.Sprintf("%d problems", len(e.Problems))
+	if len(e.Problems) == 1 {
+		count = "1 problem"
+	}
+	header := fmt.Sprintf("config file %s has %s", e.File, count)
+	if e.File == "" {
+		header = "config has " + count
+	}
+	lines := []string{header}
+	for _, problem := range e.Problems {
+		lines = append(lines, "  "+problem.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// problemAt returns a problem located at pos
+func problemAt(file string, pos position, format string, args ...interface{}) Problem {
+	return Problem{File: file, Line: pos.Line, Column: pos.Column, Message: fmt.Sprintf(format, args...)}
+}
+
+// checkSchema compares a parsed config with the fields of t, reporting unknown
+// and repeated keys and values of the wrong type
+func checkSchema(file string, n *node, t reflect.Type, path string) []Problem {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if n.kind == scalarNode && n.value == nil {
+		return nil
+	}
+
+	name := path
+	if name == "" {
+		name = "the config"
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.kind != objectNode {
+			return []Problem{problemAt(file, n.pos, "%s must be an object", name)}
+		}
+		fields := jsonFields(t)
+		var problems []Problem
+		seen := make(map[string]bool)
+		for _, f := range n.fields {
+			fieldPath := joinPath(path, f.key)
+			if seen[f.key] {
+				problems = append(problems, problemAt(file, f.pos, "%s is set more than once", fieldPath))
+				continue
+			}
+			seen[f.key] = true
+			fieldType, ok := fields[f.key]
+			if !ok {
+				problems = append(problems, problemAt(file, f.pos, "unknown key '%s'%s", fieldPath, suggestKey(f.key, fields)))
+				continue
+			}
+			problems = append(problems, checkSchema(file, f.value, fieldType, fieldPath)...)
+		}
+		return problems
+	case reflect.Slice:
+		if n.kind != arrayNode {
+			return []Problem{problemAt(file, n.pos, "%s must be a list", name)}
+		}
+		var problems []Problem
+		for i, item := range n.items {
+			problems = append(problems, checkSchema(file, item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return problems
+	case reflect.String:
+		if _, ok := n.value.(string); !ok {
+			return []Problem{problemAt(file, n.pos, "%s must be a string", name)}
+		}
+	case reflect.Bool:
+		if _, ok := n.value.(bool); !ok {
+			return []Problem{problemAt(file, n.pos, "%s must be true or false", name)}
+		}
+	case reflect.Int:
+		number, ok := n.value.(json.Number)
+		if _, err := number.Int64(); !ok || err != nil {
+			return []Problem{problemAt(file, n.pos, "%s must be a whole number", name)}
+		}
+	}
+	return nil
+}
+
+// jsonFields maps the JSON keys of a struct to the types of its fields
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// suggestKey names a known key that an unknown one was probably meant to be,
+// such as "due_today" for "dueToday"
+func suggestKey(key string, fields map[string]reflect.Type) string {
+	normalize := func(s string) string {
+		return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(s))
+	}
+	for known := range fields {
+		if normalize(known) == normalize(key) {
+			return fmt.Sprintf(" (did you mean '%s'?)", known)
+		}
+	}
+	return ""
+}
+
+// locateProblems places the problems found in a decoded config at the settings
+// they are about, falling back to the enclosing setting when it isn't in the file
+func locateProblems(file string, root *node, configProblems []core.ConfigProblem) []Problem {
+	positions := root.positions()
+	problems := make([]Problem, 0, len(configProblems))
+	for _, configProblem := range configProblems {
+		problem := Problem{File: file, Message: configProblem.Message}
+		for path := configProblem.Field; path != ""; path = parentPath(path) {
+			if pos, ok := positions[path]; ok {
+				problem.Line, problem.Column = pos.Line, pos.Column
+				break
+			}
+		}
+		problems = append(problems, problem)
+	}
+	return problems
+}
+
+// parentPath strips the last key or index from a path
+func parentPath(path string) string {
+	if i := strings.LastIndexAny(path, ".["); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
@@ -0,0 +1,538 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The YAML reader handles the subset of YAML a config file needs: block
+// mappings and sequences, one-line flow collections, plain and quoted scalars,
+// and comments. Anchors, tags, block scalars and multiple documents are reported
+// as unsupported rather than misread.
+
+// yamlLine is a line of a YAML file with content, after its indentation
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// yamlParser reads the lines of a YAML document into nodes
+type yamlParser struct {
+	lines []yamlLine
+	next  int
+}
+
+// parseYAML reads a YAML document into a tree of nodes
+func parseYAML(data []byte) (*node, error) {
+	p := &yamlParser{}
+	started := false
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, " \t\r")
+		text := strings.TrimLeft(raw, " ")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		indent := len(raw) - len(text)
+		pos := position{Line: i + 1, Column: indent + 1}
+		if strings.HasPrefix(text, "\t") {
+			return nil, &syntaxError{pos: pos, msg: "tabs can't be used for indentation in YAML"}
+		}
+		if indent == 0 && (text == "---" || strings.HasPrefix(text, "--- ")) {
+			if started {
+				return nil, &syntaxError{pos: pos, msg: "a config file can only hold one YAML document"}
+			}
+			continue
+		}
+		if indent == 0 && text == "..." {
+			break
+		}
+		if strings.HasPrefix(text, "%") {
+			return nil, &syntaxError{pos: pos, msg: "YAML directives are not supported"}
+		}
+		started = true
+		p.lines = append(p.lines, yamlLine{number: i + 1, indent: indent, text: text})
+	}
+
+	if len(p.lines) == 0 {
+		return &node{kind: objectNode, pos: position{Line: 1, Column: 1}}, nil
+	}
+	root, err := p.block(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.next < len(p.lines) {
+		line := p.lines[p.next]
+		return nil, &syntaxError{pos: position{Line: line.number, Column: line.indent + 1}, msg: "unexpected indentation"}
+	}
+	return root, nil
+}
+
+// block reads the mapping or sequence starting at the current line
+func (p *yamlParser) block(indent int) (*node, error) {
+	if isSequenceItem(p.lines[p.next].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+// isSequenceItem reports whether a line starts a block sequence item
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// mapping reads "key: value" lines at the given indentation
+func (p *yamlParser) mapping(indent int) (*node, error) {
+	first := p.lines[p.next]
+	n := &node{kind: objectNode, pos: position{Line: first.number, Column: first.indent + 1}}
+	for p.next < len(p.lines) {
+		line := p.lines[p.next]
+		if line.indent < indent {
+			break
+		}
+		pos := position{Line: line.number, Column: line.indent + 1}
+		if line.indent > indent {
+			return nil, &syntaxError{pos: pos, msg: "unexpected indentation"}
+		}
+		if isSequenceItem(line.text) {
+			return nil, &syntaxError{pos: pos, msg: "expected a 'key: value' line, not a list item"}
+		}
+
+		key, rest, err := splitYAMLKey(line.text, pos)
+		if err != nil {
+			return nil, err
+		}
+		p.next++
+
+		f := field{key: key, pos: pos}
+		valueColumn := line.indent + len(line.text) - len(rest) + 1
+		if value := stripYAMLComment(rest); value != "" {
+			f.value, err = yamlValue(value, position{Line: line.number, Column: valueColumn})
+			if err != nil {
+				return nil, err
+			}
+		} else if p.next < len(p.lines) && (p.lines[p.next].indent > indent ||
+			p.lines[p.next].indent == indent && isSequenceItem(p.lines[p.next].text)) {
+			// A nested block; a sequence may sit at the same indentation as its key
+			f.value, err = p.block(p.lines[p.next].indent)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			f.value = &node{kind: scalarNode, pos: pos}
+		}
+		n.fields = append(n.fields, f)
+	}
+	return n, nil
+}
+
+// sequence reads "- item" lines at the given indentation
+func (p *yamlParser) sequence(indent int) (*node, error) {
+	first := p.lines[p.next]
+	n := &node{kind: arrayNode, pos: position{Line: first.number, Column: first.indent + 1}}
+	for p.next < len(p.lines) {
+		line := p.lines[p.next]
+		if line.indent != indent || !isSequenceItem(line.text) {
+			if line.indent > indent {
+				return nil, &syntaxError{pos: position{Line: line.number, Column: line.indent + 1}, msg: "unexpected indentation"}
+			}
+			break
+		}
+
+		content := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		contentIndent := line.indent + len(line.text) - len(content)
+		pos := position{Line: line.number, Column: contentIndent + 1}
+		switch {
+		case stripYAMLComment(content) == "":
+			// The item is the block on the following lines
+			p.next++
+			if p.next < len(p.lines) && p.lines[p.next].indent > indent {
+				item, err := p.block(p.lines[p.next].indent)
+				if err != nil {
+					return nil, err
+				}
+				n.items = append(n.items, item)
+			} else {
+				n.items = append(n.items, &node{kind: scalarNode, pos: pos})
+			}
+		case isSequenceItem(content) || isYAMLMappingEntry(content):
+			// A nested sequence or a mapping starting on the item's line, such as
+			// "- section: Errands"; read the rest of the line as if it were on its own
+			p.lines[p.next] = yamlLine{number: line.number, indent: contentIndent, text: content}
+			item, err := p.block(contentIndent)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, item)
+		default:
+			item, err := yamlValue(stripYAMLComment(content), pos)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, item)
+			p.next++
+		}
+	}
+	return n, nil
+}
+
+// isYAMLMappingEntry reports whether text is a "key: value" pair rather than a scalar
+func isYAMLMappingEntry(text string) bool {
+	if text == "" || strings.ContainsRune("[{", rune(text[0])) {
+		return false
+	}
+	_, _, err := splitYAMLKey(text, position{})
+	return err == nil
+}
+
+// splitYAMLKey splits a "key: value" line into its key and the rest of the line
+func splitYAMLKey(text string, pos position) (string, string, error) {
+	if text[0] == '"' || text[0] == '\'' {
+		key, end, err := yamlQuoted(text, pos)
+		if err != nil {
+			return "", "", err
+		}
+		rest := strings.TrimLeft(text[end:], " ")
+		if !strings.HasPrefix(rest, ":") || len(rest) > 1 && rest[1] != ' ' {
+			return "", "", &syntaxError{pos: pos, msg: "expected ':' after the key"}
+		}
+		return key, strings.TrimLeft(rest[1:], " "), nil
+	}
+
+	switch text[0] {
+	case '?':
+		return "", "", &syntaxError{pos: pos, msg: "complex YAML keys are not supported"}
+	case '&', '*', '!':
+		return "", "", &syntaxError{pos: pos, msg: "YAML anchors, aliases and tags are not supported"}
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '#' && i > 0 && text[i-1] == ' ' {
+			break
+		}
+		if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ') {
+			key := strings.TrimRight(text[:i], " ")
+			if key == "" {
+				break
+			}
+			return key, strings.TrimLeft(text[i+1:], " "), nil
+		}
+	}
+	return "", "", &syntaxError{pos: pos, msg: fmt.Sprintf("expected 'key: value', got '%s'", text)}
+}
+
+// stripYAMLComment removes a trailing comment from a value, leaving quoted
+// strings and flow collections for yamlValue to check
+func stripYAMLComment(text string) string {
+	if text == "" || text[0] == '"' || text[0] == '\'' || text[0] == '[' || text[0] == '{' {
+		return text
+	}
+	if text[0] == '#' {
+		return ""
+	}
+	if i := strings.Index(text, " #"); i >= 0 {
+		text = text[:i]
+	}
+	return strings.TrimRight(text, " ")
+}
+
+// yamlValue reads a value written on the same line as its key or list marker
+func yamlValue(text string, pos position) (*node, error) {
+	switch text[0] {
+	case '|', '>':
+		return nil, &syntaxError{pos: pos, msg: "YAML block scalars are not supported; use a quoted string"}
+	case '&', '*', '!':
+		return nil, &syntaxError{pos: pos, msg: "YAML anchors, aliases and tags are not supported"}
+	}
+
+	f := &yamlFlow{text: text, pos: pos}
+	n, err := f.value(false)
+	if err != nil {
+		return nil, err
+	}
+	f.skipSpace()
+	if f.i < len(f.text) && !(f.text[f.i] == '#' && f.text[f.i-1] == ' ') {
+		return nil, f.errorf("unexpected '%s' after the value", f.text[f.i:])
+	}
+	return n, nil
+}
+
+// yamlFlow reads scalars and one-line flow collections such as [a, b] and {from: 1}
+type yamlFlow struct {
+	text string
+	i    int
+	pos  position
+}
+
+func (f *yamlFlow) errorf(format string, args ...interface{}) error {
+	return &syntaxError{pos: position{Line: f.pos.Line, Column: f.pos.Column + f.i}, msg: fmt.Sprintf(format, args...)}
+}
+
+func (f *yamlFlow) skipSpace() {
+	for f.i < len(f.text) && f.text[f.i] == ' ' {
+		f.i++
+	}
+}
+
+func (f *yamlFlow) here() position {
+	return position{Line: f.pos.Line, Column: f.pos.Column + f.i}
+}
+
+// value reads a scalar or collection; inFlow is set inside brackets, where ',' ends a plain scalar
+func (f *yamlFlow) value(inFlow bool) (*node, error) {
+	f.skipSpace()
+	if f.i >= len(f.text) {
+		return nil, f.errorf("expected a value")
+	}
+	pos := f.here()
+
+	switch f.text[f.i] {
+	case '[':
+		n := &node{kind: arrayNode, pos: pos}
+		f.i++
+		for {
+			f.skipSpace()
+			if f.i >= len(f.text) {
+				return nil, f.errorf("flow sequences must be closed with ']' on the same line")
+			}
+			if f.text[f.i] == ']' {
+				f.i++
+				return n, nil
+			}
+			item, err := f.value(true)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, item)
+			if err := f.separator(']'); err != nil {
+				return nil, err
+			}
+		}
+	case '{':
+		n := &node{kind: objectNode, pos: pos}
+		f.i++
+		for {
+			f.skipSpace()
+			if f.i >= len(f.text) {
+				return nil, f.errorf("flow mappings must be closed with '}' on the same line")
+			}
+			if f.text[f.i] == '}' {
+				f.i++
+				return n, nil
+			}
+			keyPos := f.here()
+			key, err := f.value(true)
+			if err != nil {
+				return nil, err
+			}
+			keyText, ok := key.value.(string)
+			if key.kind != scalarNode || !ok {
+				keyText = fmt.Sprint(key.toValue())
+			}
+			f.skipSpace()
+			if f.i >= len(f.text) || f.text[f.i] != ':' {
+				return nil, f.errorf("expected ':' after the key")
+			}
+			f.i++
+			value, err := f.value(true)
+			if err != nil {
+				return nil, err
+			}
+			n.fields = append(n.fields, field{key: keyText, pos: keyPos, value: value})
+			if err := f.separator('}'); err != nil {
+				return nil, err
+			}
+		}
+	case '"', '\'':
+		s, end, err := yamlQuoted(f.text[f.i:], pos)
+		if err != nil {
+			return nil, err
+		}
+		f.i += end
+		return &node{kind: scalarNode, pos: pos, value: s}, nil
+	}
+
+	// A plain scalar runs to the end of the line, a comment, or inside brackets a separator
+	start := f.i
+	for f.i < len(f.text) {
+		c := f.text[f.i]
+		if c == '#' && f.i > start && f.text[f.i-1] == ' ' {
+			break
+		}
+		if inFlow && (c == ',' || c == ']' || c == '}' || c == ':' && (f.i+1 == len(f.text) || f.text[f.i+1] == ' ')) {
+			break
+		}
+		f.i++
+	}
+	return &node{kind: scalarNode, pos: pos, value: resolveYAMLScalar(strings.TrimRight(f.text[start:f.i], " "))}, nil
+}
+
+// separator consumes the ',' between flow items, leaving the closing bracket
+func (f *yamlFlow) separator(closing byte) error {
+	f.skipSpace()
+	if f.i < len(f.text) && f.text[f.i] == ',' {
+		f.i++
+		return nil
+	}
+	if f.i < len(f.text) && f.text[f.i] == closing {
+		return nil
+	}
+	return f.errorf("expected ',' or '%c'", closing)
+}
+
+// yamlQuoted reads a single- or double-quoted scalar at the start of text,
+// returning it and the length of text it took up
+func yamlQuoted(text string, pos position) (string, int, error) {
+	quote := text[0]
+	var out strings.Builder
+	for i := 1; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '\'' && c == '\'':
+			if i+1 < len(text) && text[i+1] == '\'' {
+				out.WriteByte('\'')
+				i++
+				continue
+			}
+			return out.String(), i + 1, nil
+		case quote == '"' && c == '"':
+			return out.String(), i + 1, nil
+		case quote == '"' && c == '\\':
+			if i+1 >= len(text) {
+				break
+			}
+			i++
+			switch text[i] {
+			case 'n':
+				out.WriteByte('\n')
+			case 't':
+				out.WriteByte('\t')
+			case 'r':
+				out.WriteByte('\r')
+			case 'b':
+				out.WriteByte('\b')
+			case 'f':
+				out.WriteByte('\f')
+			case '0':
+				out.WriteByte(0)
+			case '"', '\\', '/', ' ':
+				out.WriteByte(text[i])
+			case 'u', 'U', 'x':
+				size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[text[i]]
+				if i+size >= len(text) {
+					return "", 0, &syntaxError{pos: pos, msg: "incomplete escape sequence"}
+				}
+				code, err := strconv.ParseUint(text[i+1:i+1+size], 16, 32)
+				if err != nil {
+					return "", 0, &syntaxError{pos: pos, msg: fmt.Sprintf("invalid escape sequence '\\%s'", text[i:i+1+size])}
+				}
+				out.WriteRune(rune(code))
+				i += size
+			default:
+				return "", 0, &syntaxError{pos: pos, msg: fmt.Sprintf("invalid escape sequence '\\%c'", text[i])}
+			}
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return "", 0, &syntaxError{pos: pos, msg: "quoted strings must be closed on the same line"}
+}
+
+var (
+	yamlInt   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlFloat = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// resolveYAMLScalar gives a plain scalar its type: null, a bool, a number or a string
+func resolveYAMLScalar(text string) interface{} {
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if yamlInt.MatchString(text) {
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return json.Number(strconv.FormatInt(i, 10))
+		}
+	}
+	if yamlFloat.MatchString(text) {
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+	}
+	return text
+}
+
+// encodeYAML writes a tree of nodes as a YAML document
+func encodeYAML(root *node) []byte {
+	var out strings.Builder
+	writeYAMLFields(&out, root.fields, "", false)
+	return []byte(out.String())
+}
+
+// writeYAMLFields writes the fields of a mapping, each on its own line. With
+// firstInline the first field continues the current line, after a list marker.
+func writeYAMLFields(out *strings.Builder, fields []field, indent string, firstInline bool) {
+	for i, f := range fields {
+		if i > 0 || !firstInline {
+			out.WriteString(indent)
+		}
+		out.WriteString(yamlScalar(f.key) + ":")
+		writeYAMLValue(out, f.value, indent)
+	}
+}
+
+// writeYAMLValue writes a value after its key or list marker
+func writeYAMLValue(out *strings.Builder, n *node, indent string) {
+	switch {
+	case n.kind == objectNode && len(n.fields) > 0:
+		out.WriteString("\n")
+		writeYAMLFields(out, n.fields, indent+"  ", false)
+	case n.kind == arrayNode && len(n.items) > 0:
+		out.WriteString("\n")
+		for _, item := range n.items {
+			out.WriteString(indent + "  -")
+			if item.kind == objectNode && len(item.fields) > 0 {
+				// The first field goes on the marker's line, the rest line up under it
+				out.WriteString(" ")
+				writeYAMLFields(out, item.fields, indent+"    ", true)
+				continue
+			}
+			writeYAMLValue(out, item, indent+"  ")
+		}
+	case n.kind == objectNode:
+		out.WriteString(" {}\n")
+	case n.kind == arrayNode:
+		out.WriteString(" []\n")
+	default:
+		out.WriteString(" " + yamlScalarValue(n.value) + "\n")
+	}
+}
+
+// yamlScalarValue writes a scalar value
+func yamlScalarValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return yamlScalar(v)
+	}
+	return fmt.Sprint(value)
+}
+
+// yamlScalar writes a string plainly, or quoted when it would otherwise be
+// read as something else
+func yamlScalar(s string) string {
+	if s == "" || s != strings.TrimSpace(s) || resolveYAMLScalar(s) != interface{}(s) ||
+		strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") ||
+		strings.ContainsAny(s, "\n\r\t\\") {
+		return quoteString(s)
+	}
+	return s
+}
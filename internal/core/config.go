@@ -1,5 +1,33 @@
 package core
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/schedule"
+)
+
+// Week modes for the "this week" bucket
+const (
+	WeekModeRolling  = "rolling"
+	WeekModeCalendar = "calendar"
+)
+
+// DefaultConcurrency is the default number of task moves executed in parallel
+const DefaultConcurrency = 4
+
+// DefaultBatchSize is the default number of task moves sent in a single batch request
+const DefaultBatchSize = asana.MaxBatchActions
+
+// Overdue modes for tasks with a due time
+const (
+	OverdueAsOfStartOfDay = "start_of_day"
+	OverdueAsOfNow        = "now"
+)
+
 // SectionConfig defines the mapping of task categories to section names
 type SectionConfig struct {
 	Overdue         string   `json:"overdue"`
@@ -8,6 +36,57 @@ type SectionConfig struct {
 	DueLater        string   `json:"due_later"`
 	NoDate          string   `json:"no_date"`
 	IgnoredSections []string `json:"ignored_sections,omitempty"`
+
+	// DueTomorrow optionally names a section for tasks due tomorrow, split out of DueThisWeek
+	DueTomorrow string `json:"due_tomorrow,omitempty"`
+	// NotStarted optionally names a section for tasks whose start date is still in the future
+	NotStarted string `json:"not_started,omitempty"`
+	// StartsToday optionally names a section that surfaces tasks whose start date is today
+	StartsToday string `json:"starts_today,omitempty"`
+	// LookAheadDays is the size of the rolling "this week" window in days (default 7)
+	LookAheadDays int `json:"look_ahead_days,omitempty"`
+	// WeekMode is "rolling" (the next LookAheadDays days) or "calendar" (until the end of the current week)
+	WeekMode string `json:"week_mode,omitempty"`
+	// WeekStart is the first day of a calendar week, e.g. "monday" (the default) or "sunday"
+	WeekStart string `json:"week_start,omitempty"`
+
+	// Timezone is the IANA time zone used to decide what "today" is; defaults to the Asana profile's
+	Timezone string `json:"timezone,omitempty"`
+	// OverdueMode is "start_of_day" (tasks are overdue once their due day has passed) or
+	// "now" (tasks with a due time are overdue as soon as it passes)
+	OverdueMode string `json:"overdue_mode,omitempty"`
+
+	// Rules are evaluated in order before the due-date buckets above; the first match wins
+	Rules []Rule `json:"rules,omitempty"`
+
+	// TaskOrder is how tasks are ordered within each target section: "keep_existing"
+	// (the default, moved tasks go to the bottom), "due_date", "due_time" or "priority"
+	TaskOrder string `json:"task_order,omitempty"`
+	// PriorityField is the custom field sorted on by the "priority" order (default "Priority")
+	PriorityField string `json:"priority_field,omitempty"`
+	// PriorityValues lists the priority field's values from most to least important (default High, Medium, Low)
+	PriorityValues []string `json:"priority_values,omitempty"`
+
+	// OrderSections moves the managed sections into the order given by SectionOrder
+	OrderSections bool `json:"order_sections,omitempty"`
+	// SectionOrder lists section names in the order they should appear; defaults to the
+	// target sections of the rules followed by the due-date buckets
+	SectionOrder []string `json:"section_order,omitempty"`
+
+	// Concurrency is the maximum number of task moves executed in parallel; zero means DefaultConcurrency
+	Concurrency int `json:"concurrency,omitempty"`
+	// BatchSize is the number of task moves grouped into one batch request (at most 10);
+	// zero means DefaultBatchSize and one moves tasks with individual requests
+	BatchSize int `json:"batch_size,omitempty"`
+
+	// Workspace selects the workspace to sort by name or GID
+	Workspace string `json:"workspace,omitempty"`
+	// AllWorkspaces sorts the My Tasks list of every workspace in turn
+	AllWorkspaces bool `json:"all_workspaces,omitempty"`
+
+	// Schedule is how often the serve command re-sorts: an interval such as "15m"
+	// or a cron expression such as "*/30 8-18 * * 1-5"
+	Schedule string `json:"schedule,omitempty"`
 }
 
 // DefaultSectionConfig returns the default section configuration
@@ -20,4 +99,189 @@ func DefaultSectionConfig() SectionConfig {
 		NoDate:          "Recently assigned",
 		IgnoredSections: []string{},
 	}
-}
\ No newline at end of file
+}
+
+// CategoryOptions converts the bucket settings into options for task categorization.
+// Invalid settings fall back to their defaults; use Validate to report them.
+func (c SectionConfig) CategoryOptions() asana.CategoryOptions {
+	weekStart, err := parseWeekday(c.WeekStart)
+	if err != nil {
+		weekStart = time.Monday
+	}
+
+	return asana.CategoryOptions{
+		LookAheadDays:  c.LookAheadDays,
+		CalendarWeek:   strings.EqualFold(c.WeekMode, WeekModeCalendar),
+		WeekStart:      weekStart,
+		DueTomorrow:    c.DueTomorrow != "",
+		OverdueAsOfNow: strings.EqualFold(c.OverdueMode, OverdueAsOfNow),
+		NotStarted:     c.NotStarted != "",
+		StartsToday:    c.StartsToday != "",
+	}
+}
+
+// ConfigProblem is a mistake in the configuration. Field is the path of the
+// setting in the config file, such as "rules[2].section", so it can be located there.
+type ConfigProblem struct {
+	Field   string
+	Message string
+}
+
+// Validate checks the configuration, returning the first problem found
+func (c SectionConfig) Validate() error {
+	if problems := c.Problems(); len(problems) > 0 {
+		return errors.New(problems[0].Message)
+	}
+	return nil
+}
+
+// Problems checks the bucket settings, section names and rules, returning every mistake found
+func (c SectionConfig) Problems() []ConfigProblem {
+	var problems []ConfigProblem
+	add := func(field, format string, args ...interface{}) {
+		problems = append(problems, ConfigProblem{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if c.Concurrency < 0 {
+		add("concurrency", "concurrency must not be negative, got %d", c.Concurrency)
+	}
+
+	if c.BatchSize < 0 || c.BatchSize > asana.MaxBatchActions {
+		add("batch_size", "batch_size must be between 0 and %d, got %d", asana.MaxBatchActions, c.BatchSize)
+	}
+
+	if c.LookAheadDays < 0 {
+		add("look_ahead_days", "look_ahead_days must not be negative, got %d", c.LookAheadDays)
+	}
+
+	switch strings.ToLower(c.WeekMode) {
+	case "", WeekModeRolling, WeekModeCalendar:
+	default:
+		add("week_mode", "week_mode must be '%s' or '%s', got '%s'", WeekModeRolling, WeekModeCalendar, c.WeekMode)
+	}
+
+	if _, err := parseWeekday(c.WeekStart); err != nil {
+		add("week_start", "%v", err)
+	}
+
+	switch strings.ToLower(c.OverdueMode) {
+	case "", OverdueAsOfStartOfDay, OverdueAsOfNow:
+	default:
+		add("overdue_mode", "overdue_mode must be '%s' or '%s', got '%s'", OverdueAsOfStartOfDay, OverdueAsOfNow, c.OverdueMode)
+	}
+
+	if err := validateTaskOrder(c.TaskOrder); err != nil {
+		add("task_order", "%v", err)
+	}
+
+	for i, name := range c.SectionOrder {
+		if strings.TrimSpace(name) == "" {
+			add(fmt.Sprintf("section_order[%d]", i), "section_order entry #%d is empty", i+1)
+		}
+	}
+
+	if c.Schedule != "" {
+		if _, err := schedule.Parse(c.Schedule); err != nil {
+			add("schedule", "%v", err)
+		}
+	}
+
+	if c.Timezone != "" {
+		if _, err := time.LoadLocation(c.Timezone); err != nil {
+			add("timezone", "timezone '%s' is not a known time zone: %v", c.Timezone, err)
+		}
+	}
+
+	ignored := make(map[string]bool, len(c.IgnoredSections))
+	for i, name := range c.IgnoredSections {
+		if strings.TrimSpace(name) == "" {
+			add(fmt.Sprintf("ignored_sections[%d]", i), "ignored_sections entry #%d is empty", i+1)
+		}
+		ignored[name] = true
+	}
+
+	// Every bucket needs a section of its own that the sorter is allowed to move tasks out of
+	buckets := []struct {
+		field    string
+		section  string
+		optional bool
+	}{
+		{"overdue", c.Overdue, false},
+		{"due_today", c.DueToday, false},
+		{"due_tomorrow", c.DueTomorrow, true},
+		{"starts_today", c.StartsToday, true},
+		{"due_this_week", c.DueThisWeek, false},
+		{"due_later", c.DueLater, false},
+		{"no_date", c.NoDate, false},
+		{"not_started", c.NotStarted, true},
+	}
+	bucketFor := make(map[string]string)
+	for _, bucket := range buckets {
+		if bucket.optional && bucket.section == "" {
+			continue
+		}
+		if strings.TrimSpace(bucket.section) == "" {
+			add(bucket.field, "%s must name a section", bucket.field)
+			continue
+		}
+		if other, ok := bucketFor[bucket.section]; ok {
+			add(bucket.field, "%s uses section '%s', which is already used by %s", bucket.field, bucket.section, other)
+		} else {
+			bucketFor[bucket.section] = bucket.field
+		}
+		if ignored[bucket.section] {
+			add(bucket.field, "%s section '%s' is also in ignored_sections", bucket.field, bucket.section)
+		}
+	}
+
+	for i, rule := range c.Rules {
+		field := fmt.Sprintf("rules[%d]", i)
+		if err := rule.Validate(); err != nil {
+			add(field, "invalid rule #%d: %v", i+1, err)
+			continue
+		}
+		if ignored[rule.Section] {
+			add(field+".section", "rule '%s' moves tasks to section '%s', which is also in ignored_sections", rule.DisplayName(), rule.Section)
+		}
+	}
+
+	return problems
+}
+
+// parseWeekday parses a day name such as "monday" or "Sun"; empty means Monday
+func parseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return time.Monday, nil
+	}
+
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		dayName := strings.ToLower(day.String())
+		if name == dayName || name == dayName[:3] {
+			return day, nil
+		}
+	}
+
+	return time.Monday, fmt.Errorf("week_start must be a day of the week, got '%s'", name)
+}
+
+// ResolveLocation picks the time zone used to decide what "today" is: the
+// configured zone if set, otherwise the Asana profile's zone, otherwise the
+// local zone of this machine
+func ResolveLocation(configured, profile string) (*time.Location, error) {
+	if configured != "" {
+		loc, err := time.LoadLocation(configured)
+		if err != nil {
+			return nil, fmt.Errorf("unknown time zone '%s': %w", configured, err)
+		}
+		return loc, nil
+	}
+
+	if profile != "" {
+		if loc, err := time.LoadLocation(profile); err == nil {
+			return loc, nil
+		}
+	}
+
+	return time.Local, nil
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+)
+
+// Ways the tasks and sections in Asana can drift from a saved plan
+var (
+	ErrTaskGone       = errors.New("task is no longer in My Tasks (completed, deleted or reassigned)")
+	ErrTaskMoved      = errors.New("task has moved since the plan was made")
+	ErrSectionMissing = errors.New("section no longer exists")
+	ErrSectionRenamed = errors.New("section has been renamed")
+	ErrAnchorDrifted  = errors.New("the task it's placed next to is no longer where the plan expects")
+)
+
+// Drift is a planned move that no longer matches the state of Asana
+type Drift struct {
+	Move   TaskMove
+	Reason error
+}
+
+// DriftError is returned when a plan is applied with drifted moves and drift isn't allowed
+type DriftError struct {
+	Drifts []Drift
+}
+
+// Error implements the error interface
+func (e *DriftError) Error() string {
+	lines := []string{fmt.Sprintf("%d planned moves no longer match Asana", len(e.Drifts))}
+	for _, drift := range e.Drifts {
+		lines = append(lines, fmt.Sprintf("  - '%s' to '%s': %v", drift.Move.Task.Name, drift.Move.SectionName, drift.Reason))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DetectDrift compares planned moves, and the sections they were planned
+// against, with the current state of the task list. It returns the moves that
+// still apply and the ones that have drifted. Moves placed next to a task that
+// is no longer in the target section, or next to a drifted move, drift too,
+// since their position depends on it.
+func DetectDrift(ctx context.Context, client asana.API, taskListGID string, plannedSections []asana.Section,
+	moves []TaskMove) ([]TaskMove, []Drift, error) {
+
+	currentSections, err := client.GetSectionsForProject(ctx, taskListGID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting sections: %w", err)
+	}
+	currentNames := make(map[string]string, len(currentSections))
+	for _, section := range currentSections {
+		currentNames[section.GID] = section.Name
+	}
+
+	// Sections that were deleted or renamed since the plan was made
+	sectionDrift := make(map[string]error)
+	for _, section := range plannedSections {
+		name, exists := currentNames[section.GID]
+		switch {
+		case !exists:
+			sectionDrift[section.GID] = fmt.Errorf("%w: '%s'", ErrSectionMissing, section.Name)
+		case name != section.Name:
+			sectionDrift[section.GID] = fmt.Errorf("%w: '%s' is now '%s'", ErrSectionRenamed, section.Name, name)
+		}
+	}
+
+	tasks, err := client.GetTasksFromUserTaskList(ctx, taskListGID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting tasks from user task list: %w", err)
+	}
+	currentTasks := make(map[string]asana.Task, len(tasks))
+	for _, task := range tasks {
+		currentTasks[task.GID] = task
+	}
+
+	// Anchors the plan moves itself are checked against where they are moved to
+	planned := make(map[string]TaskMove, len(moves))
+	for _, move := range moves {
+		planned[move.Task.GID] = move
+	}
+
+	var valid []TaskMove
+	var drifts []Drift
+	drifted := make(map[string]bool)
+	anchorValid := func(gid string, move TaskMove) bool {
+		if gid == "" {
+			return true
+		}
+		if drifted[gid] {
+			return false
+		}
+		if anchorMove, ok := planned[gid]; ok {
+			return anchorMove.SectionGID == move.SectionGID && anchorMove.SectionName == move.SectionName
+		}
+		anchor, exists := currentTasks[gid]
+		return exists && anchor.AssigneeSection.GID == move.SectionGID
+	}
+	for _, move := range moves {
+		var reason error
+		current, exists := currentTasks[move.Task.GID]
+		switch {
+		case !exists:
+			reason = ErrTaskGone
+		case sectionDrift[move.SectionGID] != nil:
+			reason = sectionDrift[move.SectionGID]
+		case sectionDrift[move.Task.AssigneeSection.GID] != nil:
+			reason = sectionDrift[move.Task.AssigneeSection.GID]
+		case current.AssigneeSection.GID != move.Task.AssigneeSection.GID:
+			reason = fmt.Errorf("%w (now in '%s')", ErrTaskMoved, current.AssigneeSection.Name)
+		case !anchorValid(move.InsertBefore, move) || !anchorValid(move.InsertAfter, move):
+			reason = ErrAnchorDrifted
+		}
+
+		if reason != nil {
+			drifted[move.Task.GID] = true
+			drifts = append(drifts, Drift{Move: move, Reason: reason})
+			continue
+		}
+		valid = append(valid, move)
+	}
+
+	return valid, drifts, nil
+}
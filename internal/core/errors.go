@@ -0,0 +1,69 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+)
+
+// MoveErrors aggregates the per-task errors of a batch of task moves
+type MoveErrors struct {
+	Failed []MoveResult
+	// Skipped counts moves that were never attempted because the run was stopped early
+	Skipped int
+}
+
+// Error implements the error interface
+func (e *MoveErrors) Error() string {
+	lines := make([]string, 0, len(e.Failed)+1)
+	summary := fmt.Sprintf("%d errors occurred while moving tasks", len(e.Failed))
+	if e.Skipped > 0 {
+		summary += fmt.Sprintf(" (%d moves skipped)", e.Skipped)
+	}
+	lines = append(lines, summary)
+	for _, failed := range e.Failed {
+		lines = append(lines, fmt.Sprintf("  - '%s' to '%s': %v", failed.Move.Task.Name, failed.Move.SectionName, failed.Err))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap returns the individual move errors so errors.Is and errors.As can inspect them
+func (e *MoveErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, failed := range e.Failed {
+		errs = append(errs, failed.Err)
+	}
+	return errs
+}
+
+// ErrorHint returns actionable guidance for a failed Asana API call, or an
+// empty string if the error isn't one we know how to explain
+func ErrorHint(err error) string {
+	switch {
+	case asana.IsUnauthorized(err):
+		return "Your Asana access token is invalid or has expired. Sign in again with " +
+			"'asana-tasks-sorter login', or store a new token with 'asana-tasks-sorter auth add' " +
+			"(add --profile NAME for a named profile). If you use ASANA_ACCESS_TOKEN, regenerate it at " +
+			"https://app.asana.com/0/developer-console and update the variable."
+	case asana.IsForbidden(err):
+		return "Your Asana access token doesn't have permission for this resource. " +
+			"Check that the token belongs to the account that owns the task list."
+	case asana.IsRateLimited(err):
+		return "Asana is rate limiting requests. Wait a minute and try again, or raise --max-attempts."
+	case asana.IsNotFound(err):
+		return "An Asana resource could not be found. It may have been deleted or moved " +
+			"since the task list was fetched; running the sorter again usually resolves this."
+	case asana.IsServerError(err):
+		return "Asana is having trouble handling requests right now. Try again in a few minutes."
+	case errors.Is(err, errNoUserTaskList):
+		return "Open Asana in this workspace once so that a My Tasks list is created for you."
+	}
+
+	var apiErr *asana.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Help()
+	}
+	return ""
+}
@@ -0,0 +1,260 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+)
+
+// TaskList identifies the My Tasks list a long-running sorter watches
+type TaskList struct {
+	User      asana.User
+	Workspace asana.Workspace
+	GID       string
+	// Location is the time zone "today" is evaluated in
+	Location *time.Location
+}
+
+// ResolveTaskList finds the current user's My Tasks list in the configured
+// workspace, and the time zone to sort it in
+func ResolveTaskList(ctx context.Context, client asana.API, config SectionConfig, reporter Reporter) (*TaskList, error) {
+	if config.AllWorkspaces {
+		return nil, fmt.Errorf("sorting changed tasks works on a single workspace; pick one with --workspace")
+	}
+
+	user, err := client.GetCurrentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting current user: %w", err)
+	}
+	report(reporter, Event{Type: EventLoggedIn, User: user})
+
+	loc, err := ResolveLocation(config.Timezone, user.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	report(reporter, Event{Type: EventTimeZoneChosen, TimeZone: loc.String()})
+
+	workspaces, err := client.GetWorkspaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting workspaces: %w", err)
+	}
+	workspace, err := SelectWorkspace(workspaces, config.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	report(reporter, Event{Type: EventWorkspaceChosen, Workspace: &workspace})
+
+	userTaskList, err := client.GetUserTaskList(ctx, user.GID, workspace.GID)
+	if err != nil {
+		if asana.IsNotFound(err) {
+			return nil, fmt.Errorf("%w in workspace '%s': %w", errNoUserTaskList, workspace.Name, err)
+		}
+		return nil, fmt.Errorf("error getting user task list: %w", err)
+	}
+
+	return &TaskList{User: *user, Workspace: workspace, GID: userTaskList.GID, Location: loc}, nil
+}
+
+// ChangedTaskGIDs returns the tasks that events show were changed or added,
+// once each, in the order they first appear. Other resources and removals are ignored.
+func ChangedTaskGIDs(events []asana.Event) []string {
+	seen := make(map[string]bool)
+	var gids []string
+	for _, event := range events {
+		if event.Resource.ResourceType != "task" || event.Resource.GID == "" {
+			continue
+		}
+		switch strings.ToLower(event.Action) {
+		case "changed", "added", "undeleted":
+		default:
+			continue
+		}
+		if !seen[event.Resource.GID] {
+			seen[event.Resource.GID] = true
+			gids = append(gids, event.Resource.GID)
+		}
+	}
+	return gids
+}
+
+// OrganizeChangedTasks sorts only the given tasks of a My Tasks list. Each task
+// is fetched again; tasks that were deleted, completed or are no longer in the
+// list are left alone. The sections the tasks move into are fetched so the
+// tasks are placed according to the configured task order. A dry run only
+// plans the moves.
+func OrganizeChangedTasks(ctx context.Context, client asana.API, taskList *TaskList, taskGIDs []string,
+	config SectionConfig, dryRun bool, reporter Reporter) (*RunResult, error) {
+
+	result := &RunResult{DryRun: dryRun, User: taskList.User, TimeZone: taskList.Location.String()}
+	workspaceResult := organizeChangedTasks(ctx, client, taskList, taskGIDs, config, dryRun, reporter)
+	result.Workspaces = append(result.Workspaces, workspaceResult)
+	return result, workspaceResult.Err
+}
+
+// organizeChangedTasks sorts the given tasks, recording any failure in the result's Err field
+func organizeChangedTasks(ctx context.Context, client asana.API, taskList *TaskList, taskGIDs []string,
+	config SectionConfig, dryRun bool, reporter Reporter) WorkspaceResult {
+
+	result := WorkspaceResult{Workspace: taskList.Workspace, TaskListGID: taskList.GID}
+
+	sections, err := client.GetSectionsForProject(ctx, taskList.GID)
+	if err != nil {
+		result.Err = fmt.Errorf("error getting sections: %w", err)
+		return result
+	}
+	sectionNameToGID := CreateSectionNameToGIDMap(sections)
+	if dryRun {
+		result.SectionsToCreate = MissingSections(config, sectionNameToGID)
+		for _, sectionName := range result.SectionsToCreate {
+			sectionNameToGID[sectionName] = ""
+		}
+	} else if err := EnsureRequiredSections(ctx, client, taskList.GID, config, &sections, sectionNameToGID, reporter); err != nil {
+		result.Err = fmt.Errorf("error ensuring required sections: %w", err)
+		return result
+	}
+	result.Sections = sections
+
+	inList := make(map[string]bool, len(sections))
+	for _, section := range sections {
+		inList[section.GID] = true
+	}
+
+	var changed []asana.Task
+	for _, gid := range taskGIDs {
+		task, err := client.GetTask(ctx, gid)
+		if asana.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			result.Err = fmt.Errorf("error getting task %s: %w", gid, err)
+			return result
+		}
+		if task.Completed || !inList[task.AssigneeSection.GID] {
+			continue
+		}
+		changed = append(changed, *task)
+	}
+
+	ignoredSections := CreateIgnoredSectionsMap(config.IgnoredSections)
+	now := time.Now().In(taskList.Location)
+	result.Categorized = CategorizeTasks(changed, now, config.CategoryOptions())
+	result.Tasks = DescribeTasks(changed, config, ignoredSections, now)
+
+	moves := CalculateTaskMoves(changed, config, sectionNameToGID, ignoredSections, now)
+	if len(moves) == 0 {
+		return result
+	}
+
+	// The tasks around the changed ones: the sections they move into and,
+	// when sections are kept sorted, the sections they are in now
+	touched := make(map[string]bool)
+	for _, move := range moves {
+		touched[move.SectionGID] = true
+	}
+	if policy := strings.ToLower(config.TaskOrder); policy != "" && policy != TaskOrderKeepExisting {
+		for _, task := range changed {
+			if !ignoredSections[task.AssigneeSection.Name] {
+				touched[task.AssigneeSection.GID] = true
+			}
+		}
+	}
+
+	var allTasks []asana.Task
+	listed := make(map[string]bool)
+	for _, section := range sections {
+		if !touched[section.GID] {
+			continue
+		}
+		tasks, err := client.GetTasksInSection(ctx, section.GID)
+		if err != nil {
+			result.Err = fmt.Errorf("error getting tasks in section '%s': %w", section.Name, err)
+			return result
+		}
+		for _, task := range tasks {
+			task.AssigneeSection = asana.AssigneeSection{GID: section.GID, Name: section.Name}
+			listed[task.GID] = true
+			allTasks = append(allTasks, task)
+		}
+	}
+	for _, task := range changed {
+		if !listed[task.GID] {
+			allTasks = append(allTasks, task)
+		}
+	}
+
+	result.PlannedMoves = OrderTaskMoves(allTasks, moves, config, ignoredSections, now)
+	for i := range result.PlannedMoves {
+		report(reporter, Event{Type: EventMovePlanned, Move: &result.PlannedMoves[i]})
+	}
+	if dryRun {
+		return result
+	}
+
+	result.MoveResults, err = ExecuteTaskMoves(ctx, client, result.PlannedMoves, config.Concurrency, batchSize(config), reporter)
+	if err != nil {
+		result.Err = fmt.Errorf("error executing task moves: %w", err)
+	}
+
+	return result
+}
+
+// SyncState is what an incremental run remembers for the next one
+type SyncState struct {
+	// Token is the events sync token for the My Tasks list
+	Token string `json:"token"`
+	// FullScanAt is when the whole list was last sorted
+	FullScanAt time.Time `json:"full_scan_at"`
+}
+
+// OrganizeTasksSince sorts the tasks of a My Tasks list that changed since the
+// previous run, using the events sync token in previous. The whole list is
+// sorted instead when the token is missing or expired, and on the first run of
+// each day, since tasks move between the due-date buckets without changing.
+// It returns the state to pass to the next run; on failure that is previous,
+// so the changes are looked at again.
+func OrganizeTasksSince(ctx context.Context, client asana.API, taskList *TaskList, config SectionConfig,
+	previous SyncState, reporter Reporter) (*RunResult, SyncState, error) {
+
+	next := previous
+	now := time.Now().In(taskList.Location)
+
+	events, err := client.GetEvents(ctx, taskList.GID, previous.Token)
+	fullScan := false
+	switch {
+	case errors.Is(err, asana.ErrSyncTokenInvalid):
+		fullScan = true
+	case err != nil:
+		return &RunResult{User: taskList.User, TimeZone: taskList.Location.String()}, previous,
+			fmt.Errorf("error getting changes since the last run: %w", err)
+	}
+	next.Token = events.Sync
+
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, taskList.Location)
+	if previous.FullScanAt.Before(startOfDay) {
+		fullScan = true
+	}
+
+	if !fullScan {
+		gids := ChangedTaskGIDs(events.Events)
+		report(reporter, Event{Type: EventChangesFetched, Count: len(gids)})
+		result, err := OrganizeChangedTasks(ctx, client, taskList, gids, config, false, reporter)
+		if err != nil {
+			return result, previous, err
+		}
+		return result, next, nil
+	}
+
+	report(reporter, Event{Type: EventChangesFetched, Count: -1})
+	result := &RunResult{User: taskList.User, TimeZone: taskList.Location.String()}
+	workspaceResult := organizeTaskList(ctx, client, taskList.Workspace, taskList.GID, taskList.Location, config, false, reporter)
+	result.Workspaces = append(result.Workspaces, workspaceResult)
+	if workspaceResult.Err != nil {
+		return result, previous, workspaceResult.Err
+	}
+	next.FullScanAt = now
+	return result, next, nil
+}
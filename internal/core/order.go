@@ -0,0 +1,271 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+)
+
+// Task ordering policies within a section
+const (
+	TaskOrderKeepExisting = "keep_existing"
+	TaskOrderDueDate      = "due_date"
+	TaskOrderDueTime      = "due_time"
+	TaskOrderPriority     = "priority"
+)
+
+// DefaultPriorityField is the custom field the priority ordering policy sorts by
+const DefaultPriorityField = "Priority"
+
+// DefaultPriorityValues lists priority values from most to least important
+var DefaultPriorityValues = []string{"High", "Medium", "Low"}
+
+// validateTaskOrder checks the task_order setting
+func validateTaskOrder(order string) error {
+	switch strings.ToLower(order) {
+	case "", TaskOrderKeepExisting, TaskOrderDueDate, TaskOrderDueTime, TaskOrderPriority:
+		return nil
+	}
+	return fmt.Errorf("task_order must be one of '%s', '%s', '%s' or '%s', got '%s'",
+		TaskOrderKeepExisting, TaskOrderDueDate, TaskOrderDueTime, TaskOrderPriority, order)
+}
+
+// OrderTaskMoves decides where each planned move places its task within the
+// target section, following config.TaskOrder. With keep_existing (the default)
+// moved tasks are added below the tasks already in the section. They are all
+// placed after the same existing task so they can be moved in parallel, which
+// leaves their order among themselves up to Asana. The other
+// policies sort each target section, adding moves for tasks already in a
+// section that are out of place; tasks that are already in order stay put.
+//
+// tasks must be in the order Asana lists them. Moves that place a task next
+// to another moved task must be made after it, in the order returned.
+// It is a pure function that doesn't perform any side effects.
+func OrderTaskMoves(tasks []asana.Task, moves []TaskMove, config SectionConfig,
+	ignoredSections map[string]bool, now time.Time) []TaskMove {
+
+	movingOut := make(map[string]bool, len(moves))
+	incoming := make(map[string][]TaskMove)
+	for _, move := range moves {
+		movingOut[move.Task.GID] = true
+		incoming[move.SectionName] = append(incoming[move.SectionName], move)
+	}
+
+	// Tasks that stay where they are, in their current order
+	staying := make(map[string][]asana.Task)
+	for _, task := range tasks {
+		sectionName := task.AssigneeSection.Name
+		if !movingOut[task.GID] && !ignoredSections[sectionName] {
+			staying[sectionName] = append(staying[sectionName], task)
+		}
+	}
+
+	policy := strings.ToLower(config.TaskOrder)
+	if policy == "" || policy == TaskOrderKeepExisting {
+		ordered := make([]TaskMove, len(moves))
+		for i, move := range moves {
+			if existing := staying[move.SectionName]; len(existing) > 0 {
+				move.InsertAfter = existing[len(existing)-1].GID
+			}
+			ordered[i] = move
+		}
+		return ordered
+	}
+
+	compare := taskComparator(policy, config, now.Location())
+
+	var ordered []TaskMove
+	for _, sectionName := range RequiredSectionNames(EffectiveRules(config)) {
+		if ignoredSections[sectionName] {
+			continue
+		}
+		ordered = append(ordered, orderSection(sectionName, staying[sectionName], incoming[sectionName], compare)...)
+		delete(incoming, sectionName)
+	}
+
+	// Moves to sections no rule targets are left where Asana puts them
+	for _, move := range moves {
+		if _, ok := incoming[move.SectionName]; ok {
+			ordered = append(ordered, move)
+		}
+	}
+
+	return ordered
+}
+
+// orderSection returns the moves that sort a single section: the incoming
+// moves, placed in order, and moves for existing tasks that are out of place
+func orderSection(sectionName string, staying []asana.Task, incoming []TaskMove,
+	compare func(a, b asana.Task) int) []TaskMove {
+
+	if len(incoming) == 0 && len(staying) < 2 {
+		return nil
+	}
+
+	// The desired order of everything that will be in the section
+	type entry struct {
+		move     TaskMove
+		position int // current position of a staying task, or -1 for an incoming one
+	}
+	entries := make([]entry, 0, len(staying)+len(incoming))
+	for i, task := range staying {
+		entries = append(entries, entry{
+			move:     TaskMove{Task: task, SectionGID: task.AssigneeSection.GID, SectionName: sectionName, Reorder: true},
+			position: i,
+		})
+	}
+	for _, move := range incoming {
+		entries = append(entries, entry{move: move, position: -1})
+	}
+	slices.SortStableFunc(entries, func(a, b entry) int {
+		return compare(a.move.Task, b.move.Task)
+	})
+
+	// Staying tasks that are already in the right order relative to each other don't need to move
+	positions := make([]int, 0, len(staying))
+	for _, e := range entries {
+		if e.position >= 0 {
+			positions = append(positions, e.position)
+		}
+	}
+	inPlace := longestIncreasing(positions)
+	if len(staying) > 0 && entries[0].position == 0 {
+		inPlace[0] = true
+	}
+
+	var moves []TaskMove
+	for i, e := range entries {
+		if e.position >= 0 && inPlace[e.position] {
+			continue
+		}
+		move := e.move
+		move.InsertBefore, move.InsertAfter = "", ""
+		if i > 0 {
+			move.InsertAfter = entries[i-1].move.Task.GID
+		} else if len(staying) > 0 {
+			move.InsertBefore = staying[0].GID
+		}
+		moves = append(moves, move)
+	}
+
+	return moves
+}
+
+// longestIncreasing returns the members of a longest increasing subsequence of
+// values, which must be distinct integers from 0 to len(values)-1
+func longestIncreasing(values []int) map[int]bool {
+	return heaviestIncreasing(values, func(int) int { return 1 })
+}
+
+// heaviestIncreasing returns the members of the increasing subsequence of
+// values with the greatest total weight. Values must be distinct integers
+// from 0 to len(values)-1.
+func heaviestIncreasing(values []int, weight func(value int) int) map[int]bool {
+	total := make([]int, len(values))
+	prev := make([]int, len(values))
+	best := -1
+	for i := range values {
+		total[i], prev[i] = weight(values[i]), -1
+		for j := 0; j < i; j++ {
+			if values[j] < values[i] && total[j]+weight(values[i]) > total[i] {
+				total[i], prev[i] = total[j]+weight(values[i]), j
+			}
+		}
+		if best < 0 || total[i] > total[best] {
+			best = i
+		}
+	}
+
+	members := make(map[int]bool)
+	for i := best; i >= 0; i = prev[i] {
+		members[values[i]] = true
+	}
+	return members
+}
+
+// taskComparator returns the comparison used to sort a section for the given policy.
+// Tasks without the value being sorted on go last.
+func taskComparator(policy string, config SectionConfig, loc *time.Location) func(a, b asana.Task) int {
+	byDueDate := func(a, b asana.Task) int {
+		return compareOptional(dueDay(a, loc), dueDay(b, loc))
+	}
+
+	switch policy {
+	case TaskOrderDueTime:
+		return func(a, b asana.Task) int {
+			return compareOptional(dueTime(a, loc), dueTime(b, loc))
+		}
+	case TaskOrderPriority:
+		field := config.PriorityField
+		if field == "" {
+			field = DefaultPriorityField
+		}
+		values := config.PriorityValues
+		if len(values) == 0 {
+			values = DefaultPriorityValues
+		}
+		return func(a, b asana.Task) int {
+			if c := cmp.Compare(priorityRank(a, field, values), priorityRank(b, field, values)); c != 0 {
+				return c
+			}
+			return byDueDate(a, b)
+		}
+	default:
+		return byDueDate
+	}
+}
+
+// compareOptional orders times ascending, with zero times last
+func compareOptional(a, b time.Time) int {
+	switch {
+	case a.IsZero() && b.IsZero():
+		return 0
+	case a.IsZero():
+		return 1
+	case b.IsZero():
+		return -1
+	}
+	return a.Compare(b)
+}
+
+// dueDay returns the calendar day a task is due, or the zero time if it has no due date
+func dueDay(task asana.Task, loc *time.Location) time.Time {
+	dueDate, ok := task.DueDate(loc)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Date(dueDate.Year(), dueDate.Month(), dueDate.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// dueTime returns the moment a task is due. Tasks with only a due date are due
+// at the end of that day, after tasks with a due time on the same day.
+func dueTime(task asana.Task, loc *time.Location) time.Time {
+	if !task.DueAt.IsZero() {
+		return task.DueAt
+	}
+	if task.DueOn.IsZero() {
+		return time.Time{}
+	}
+	day := task.DueOn.Time()
+	return time.Date(day.Year(), day.Month(), day.Day(), 23, 59, 59, 0, loc)
+}
+
+// priorityRank returns the position of the task's priority in values, or
+// len(values) if the field is unset or has another value
+func priorityRank(task asana.Task, field string, values []string) int {
+	for _, customField := range task.CustomFields {
+		if !strings.EqualFold(customField.Name, field) {
+			continue
+		}
+		for rank, value := range values {
+			if strings.EqualFold(customField.DisplayValue, value) {
+				return rank
+			}
+		}
+	}
+	return len(values)
+}
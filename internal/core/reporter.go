@@ -0,0 +1,126 @@
+package core
+
+import (
+	"sync"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+)
+
+// EventType identifies what happened during a sorting run
+type EventType string
+
+const (
+	// EventLoggedIn is sent once the current user is known (User)
+	EventLoggedIn EventType = "logged_in"
+	// EventTimeZoneChosen is sent once the time zone for "today" is decided (TimeZone)
+	EventTimeZoneChosen EventType = "time_zone_chosen"
+	// EventWorkspaceChosen is sent before a workspace is sorted (Workspace)
+	EventWorkspaceChosen EventType = "workspace_chosen"
+	// EventSectionCreated is sent after a missing section was created (Section)
+	EventSectionCreated EventType = "section_created"
+	// EventSectionMoved is sent after a section was moved into its configured position (SectionReorder)
+	EventSectionMoved EventType = "section_moved"
+	// EventChangesFetched is sent once an incremental run knows how many tasks changed (Count, or -1 for a full scan)
+	EventChangesFetched EventType = "changes_fetched"
+	// EventFetchingTasks is sent before the My Tasks list is fetched (Workspace)
+	EventFetchingTasks EventType = "fetching_tasks"
+	// EventTaskSkipped is sent for each task left alone because it is in an ignored section (Task, Section)
+	EventTaskSkipped EventType = "task_skipped"
+	// EventMovePlanned is sent for each move the sorter intends to make (Move)
+	EventMovePlanned EventType = "move_planned"
+	// EventMovesStarted is sent before planned moves are executed (Count)
+	EventMovesStarted EventType = "moves_started"
+	// EventBatchFailed is sent when a batch request failed and its moves are retried one at a time (Count, Err)
+	EventBatchFailed EventType = "batch_failed"
+	// EventMoveStarted is sent for each attempted move once it has finished, just before its outcome.
+	// Moves run in parallel, so these events follow the planned order, not the order of the requests (Move)
+	EventMoveStarted EventType = "move_started"
+	// EventMoveSucceeded is sent after a task was moved (Move)
+	EventMoveSucceeded EventType = "move_succeeded"
+	// EventMoveFailed is sent after a task failed to move (Move, Err)
+	EventMoveFailed EventType = "move_failed"
+	// EventMovesFinished is sent after all moves were attempted (Count of successful moves, Err if any failed)
+	EventMovesFinished EventType = "moves_finished"
+	// EventMoveDrifted is sent for each planned move skipped because Asana changed since the plan was made (Move, Err)
+	EventMoveDrifted EventType = "move_drifted"
+	// EventUndoSkipped is sent for each recorded move that undo leaves alone (Task, Err with the reason)
+	EventUndoSkipped EventType = "undo_skipped"
+	// EventWorkspaceFailed is sent when sorting a workspace failed (Workspace, Err)
+	EventWorkspaceFailed EventType = "workspace_failed"
+	// EventRunFinished is sent at the end of an all-workspaces run (Result)
+	EventRunFinished EventType = "run_finished"
+)
+
+// Event is a structured notification about the progress of a sorting run.
+// Only the fields relevant to the event type are set.
+type Event struct {
+	Type           EventType
+	User           *asana.User
+	Workspace      *asana.Workspace
+	TimeZone       string
+	Section        string
+	SectionReorder *SectionReorder
+	Task           *asana.Task
+	Move           *TaskMove
+	Count          int
+	Err            error
+	Result         *RunResult
+}
+
+// Reporter receives events as a sorting run progresses. Implementations must
+// be safe for concurrent use.
+type Reporter interface {
+	Report(event Event)
+}
+
+// ReporterFunc adapts a function to the Reporter interface
+type ReporterFunc func(event Event)
+
+// Report calls f(event)
+func (f ReporterFunc) Report(event Event) {
+	f(event)
+}
+
+// NopReporter discards every event, for running quietly as a library
+type NopReporter struct{}
+
+// Report implements Reporter
+func (NopReporter) Report(Event) {}
+
+// RecordingReporter keeps every event it receives, for use in tests
+type RecordingReporter struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// Report implements Reporter
+func (r *RecordingReporter) Report(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// Events returns a copy of the recorded events
+func (r *RecordingReporter) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// EventsOfType returns the recorded events of the given type
+func (r *RecordingReporter) EventsOfType(eventType EventType) []Event {
+	var matching []Event
+	for _, event := range r.Events() {
+		if event.Type == eventType {
+			matching = append(matching, event)
+		}
+	}
+	return matching
+}
+
+// report sends an event, treating a nil reporter as silent
+func report(reporter Reporter, event Event) {
+	if reporter != nil {
+		reporter.Report(event)
+	}
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+)
+
+// RunResult describes everything a sorting run looked at and did
+type RunResult struct {
+	User       asana.User
+	TimeZone   string
+	DryRun     bool
+	Workspaces []WorkspaceResult
+}
+
+// WorkspaceResult describes the outcome of sorting a single workspace
+type WorkspaceResult struct {
+	Workspace        asana.Workspace
+	TaskListGID      string
+	Sections         []asana.Section
+	SectionsToCreate []string
+	SectionReorders  []SectionReorder
+	Tasks            []TaskResult
+	PlannedMoves     []TaskMove
+	MoveResults      []MoveResult
+	Categorized      map[asana.TaskCategory][]asana.Task
+	Err              error
+}
+
+// TaskResult records how a single task was categorized
+type TaskResult struct {
+	Task          asana.Task
+	Category      asana.TaskCategory
+	TargetSection string
+	Rule          string
+	Ignored       bool
+}
+
+// MoveResult records the outcome of a single attempted task move
+type MoveResult struct {
+	Move TaskMove
+	Err  error
+	// At is when the move finished
+	At time.Time
+}
+
+// Categorized merges the categorized tasks of every workspace in the run
+func (r *RunResult) Categorized() map[asana.TaskCategory][]asana.Task {
+	combined := make(map[asana.TaskCategory][]asana.Task)
+	for _, workspace := range r.Workspaces {
+		for category, tasks := range workspace.Categorized {
+			combined[category] = append(combined[category], tasks...)
+		}
+	}
+	return combined
+}
+
+// ExecutedMoves returns the moves that succeeded
+func (w WorkspaceResult) ExecutedMoves() []TaskMove {
+	var moves []TaskMove
+	for _, result := range w.MoveResults {
+		if result.Err == nil {
+			moves = append(moves, result.Move)
+		}
+	}
+	return moves
+}
+
+// FailedMoves returns the moves that failed
+func (w WorkspaceResult) FailedMoves() []MoveResult {
+	var failed []MoveResult
+	for _, result := range w.MoveResults {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// DescribeTasks categorizes every task and works out the section it belongs in,
+// including tasks that won't move because they're ignored or already in place
+func DescribeTasks(tasks []asana.Task, config SectionConfig, ignoredSections map[string]bool, now time.Time) []TaskResult {
+	rules := EffectiveRules(config)
+	opts := config.CategoryOptions()
+
+	results := make([]TaskResult, 0, len(tasks))
+	for _, task := range tasks {
+		result := TaskResult{
+			Task:     task,
+			Category: task.Categorize(now, opts),
+			Ignored:  ignoredSections[task.AssigneeSection.Name],
+		}
+		if rule, matched := MatchRule(rules, task, now, opts); matched {
+			result.TargetSection = rule.Section
+			result.Rule = rule.DisplayName()
+		}
+		results = append(results, result)
+	}
+	return results
+}
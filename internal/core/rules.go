@@ -0,0 +1,266 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+)
+
+// Rule maps tasks matching every one of its predicates to a target section.
+// Rules are evaluated in order and the first matching rule wins.
+type Rule struct {
+	// Name describes the rule in output; defaults to the target section
+	Name string `json:"name,omitempty"`
+	// Section is the name of the section matching tasks are moved to
+	Section string `json:"section"`
+
+	// Category matches the task's due-date bucket, e.g. "overdue" or "no_date"
+	Category string `json:"category,omitempty"`
+	// Due matches tasks whose due date falls within a range of days relative to today
+	Due *DayRange `json:"due,omitempty"`
+	// Start matches tasks whose start date falls within a range of days relative to today
+	Start *DayRange `json:"start,omitempty"`
+	// Tag matches tasks carrying a tag with this name or GID
+	Tag string `json:"tag,omitempty"`
+	// Project matches tasks that belong to a project with this name or GID
+	Project string `json:"project,omitempty"`
+	// NameMatches is a regular expression matched against the task name
+	NameMatches string `json:"name_matches,omitempty"`
+	// CustomField matches tasks whose custom field has the given value
+	CustomField *CustomFieldMatch `json:"custom_field,omitempty"`
+
+	// pattern is NameMatches compiled when the rule is loaded
+	pattern *regexp.Regexp
+}
+
+// UnmarshalJSON loads a rule, compiling its name_matches pattern once. An
+// invalid pattern is left for Validate to report.
+func (r *Rule) UnmarshalJSON(data []byte) error {
+	type plainRule Rule
+	if err := json.Unmarshal(data, (*plainRule)(r)); err != nil {
+		return err
+	}
+	r.pattern = nil
+	if r.NameMatches != "" {
+		r.pattern, _ = regexp.Compile(r.NameMatches)
+	}
+	return nil
+}
+
+// DayRange is an inclusive range of days relative to today. A nil bound is open,
+// so {"to": -1} means "before today" and {"from": 8} means "more than a week out".
+// Tasks without the corresponding date never match a range.
+type DayRange struct {
+	From *int `json:"from,omitempty"`
+	To   *int `json:"to,omitempty"`
+}
+
+// CustomFieldMatch matches a custom field by name against its display value.
+// Both comparisons are case-insensitive; an empty Value matches an unset field.
+type CustomFieldMatch struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// DisplayName returns the rule's name, falling back to its target section
+func (r Rule) DisplayName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Section
+}
+
+// Validate checks that the rule has a target section and well-formed predicates
+func (r Rule) Validate() error {
+	if strings.TrimSpace(r.Section) == "" {
+		return fmt.Errorf("rule '%s' has no target section", r.Name)
+	}
+	if r.Category != "" {
+		if _, ok := asana.ParseTaskCategory(r.Category); !ok {
+			return fmt.Errorf("rule '%s' has unknown category '%s'", r.DisplayName(), r.Category)
+		}
+	}
+	if r.NameMatches != "" {
+		if _, err := regexp.Compile(r.NameMatches); err != nil {
+			return fmt.Errorf("rule '%s' has invalid name_matches pattern: %w", r.DisplayName(), err)
+		}
+	}
+	if r.CustomField != nil && strings.TrimSpace(r.CustomField.Name) == "" {
+		return fmt.Errorf("rule '%s' has a custom_field predicate without a name", r.DisplayName())
+	}
+	if err := r.Due.validate(); err != nil {
+		return fmt.Errorf("rule '%s' has an invalid due range: %w", r.DisplayName(), err)
+	}
+	if err := r.Start.validate(); err != nil {
+		return fmt.Errorf("rule '%s' has an invalid start range: %w", r.DisplayName(), err)
+	}
+	return nil
+}
+
+// Matches reports whether the task satisfies every predicate of the rule
+func (r Rule) Matches(task asana.Task, now time.Time, opts asana.CategoryOptions) bool {
+	if r.Category != "" {
+		category, ok := asana.ParseTaskCategory(r.Category)
+		if !ok || task.Categorize(now, opts) != category {
+			return false
+		}
+	}
+	if r.Due != nil {
+		dueDate, ok := task.DueDate(now.Location())
+		if !ok || !r.Due.contains(dueDate, now) {
+			return false
+		}
+	}
+	if r.Start != nil {
+		startDate, ok := task.StartDate(now.Location())
+		if !ok || !r.Start.contains(startDate, now) {
+			return false
+		}
+	}
+	if r.Tag != "" && !hasTag(task, r.Tag) {
+		return false
+	}
+	if r.Project != "" && !inProject(task, r.Project) {
+		return false
+	}
+	if r.NameMatches != "" {
+		// Rules built in code rather than loaded have no compiled pattern
+		pattern := r.pattern
+		if pattern == nil {
+			var err error
+			if pattern, err = regexp.Compile(r.NameMatches); err != nil {
+				return false
+			}
+		}
+		if !pattern.MatchString(task.Name) {
+			return false
+		}
+	}
+	if r.CustomField != nil && !r.CustomField.matches(task) {
+		return false
+	}
+	return true
+}
+
+// validate checks that a range can match at least one day
+func (d *DayRange) validate() error {
+	if d != nil && d.From != nil && d.To != nil && *d.From > *d.To {
+		return fmt.Errorf("from %d is after to %d", *d.From, *d.To)
+	}
+	return nil
+}
+
+// contains reports whether the date lies within the range relative to now
+func (d DayRange) contains(date time.Time, now time.Time) bool {
+	days := asana.DaysBetween(now, date)
+	if d.From != nil && days < *d.From {
+		return false
+	}
+	if d.To != nil && days > *d.To {
+		return false
+	}
+	return true
+}
+
+// matches reports whether the task has the custom field set to the expected value
+func (m CustomFieldMatch) matches(task asana.Task) bool {
+	for _, field := range task.CustomFields {
+		if strings.EqualFold(field.Name, m.Name) || field.GID == m.Name {
+			return strings.EqualFold(strings.TrimSpace(field.DisplayValue), strings.TrimSpace(m.Value))
+		}
+	}
+	return false
+}
+
+// hasTag reports whether the task carries a tag with the given name or GID
+func hasTag(task asana.Task, tag string) bool {
+	for _, t := range task.Tags {
+		if t.GID == tag || strings.EqualFold(t.Name, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// inProject reports whether the task belongs to a project with the given name or GID
+func inProject(task asana.Task, project string) bool {
+	for _, p := range task.Projects {
+		if p.GID == project || strings.EqualFold(p.Name, project) {
+			return true
+		}
+	}
+	return false
+}
+
+// categoryOrder lists the due-date buckets in the order their sections should appear
+var categoryOrder = []asana.TaskCategory{
+	asana.Overdue,
+	asana.DueToday,
+	asana.DueTomorrow,
+	asana.StartsToday,
+	asana.DueThisWeek,
+	asana.DueLater,
+	asana.NoDate,
+	asana.NotStarted,
+}
+
+// optionalCategories are buckets that are only used when a section is configured for them
+var optionalCategories = map[asana.TaskCategory]bool{
+	asana.DueTomorrow: true,
+	asana.StartsToday: true,
+	asana.NotStarted:  true,
+}
+
+// DefaultRules returns the rule set equivalent to the due-date buckets in config
+func DefaultRules(config SectionConfig) []Rule {
+	categoryToSection := GetCategoryToSectionMap(config)
+
+	var rules []Rule
+	for _, category := range categoryOrder {
+		// Optional buckets only exist when a section is configured for them
+		if categoryToSection[category] == "" && optionalCategories[category] {
+			continue
+		}
+		rules = append(rules, Rule{
+			Section:  categoryToSection[category],
+			Category: category.String(),
+		})
+	}
+	return rules
+}
+
+// EffectiveRules returns the configured rules followed by the default due-date
+// bucket rules, which act as a fallback for tasks no custom rule matched
+func EffectiveRules(config SectionConfig) []Rule {
+	rules := make([]Rule, 0, len(config.Rules)+len(categoryOrder))
+	rules = append(rules, config.Rules...)
+	return append(rules, DefaultRules(config)...)
+}
+
+// MatchRule returns the first rule matching the task
+func MatchRule(rules []Rule, task asana.Task, now time.Time, opts asana.CategoryOptions) (Rule, bool) {
+	for _, rule := range rules {
+		if rule.Matches(task, now, opts) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// RequiredSectionNames returns the distinct target sections of the rules, in rule order
+func RequiredSectionNames(rules []Rule) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, rule := range rules {
+		if rule.Section == "" || seen[rule.Section] {
+			continue
+		}
+		seen[rule.Section] = true
+		names = append(names, rule.Section)
+	}
+	return names
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+)
+
+// SectionReorder moves a section so it sits directly before or after another section
+type SectionReorder struct {
+	Section asana.Section
+	// Exactly one of Before and After is set to the section it's placed next to
+	Before asana.Section
+	After  asana.Section
+}
+
+// ManagedSectionOrder returns the order the sorter keeps its sections in:
+// section_order if configured, otherwise the target sections of the rules
+// followed by the due-date buckets. Ignored sections are never managed.
+func ManagedSectionOrder(config SectionConfig) []string {
+	names := config.SectionOrder
+	if len(names) == 0 {
+		names = RequiredSectionNames(EffectiveRules(config))
+	}
+
+	ignored := CreateIgnoredSectionsMap(config.IgnoredSections)
+	var managed []string
+	for _, name := range names {
+		if !ignored[name] {
+			managed = append(managed, name)
+		}
+	}
+	return managed
+}
+
+// CalculateSectionOrder determines how to reorder sections, given in their
+// current order, so the managed sections appear in the configured order. The
+// managed sections swap places among the positions they already hold, so
+// ignored and unmanaged sections keep their places. The returned moves must be
+// made in order. It is a pure function that doesn't perform any side effects.
+func CalculateSectionOrder(sections []asana.Section, config SectionConfig) []SectionReorder {
+	rank := make(map[string]int)
+	for i, name := range ManagedSectionOrder(config) {
+		if _, exists := rank[name]; !exists {
+			rank[name] = i
+		}
+	}
+
+	// Fill the positions held by managed sections with them in the configured order
+	var managed []int
+	for i, section := range sections {
+		if _, ok := rank[section.Name]; ok {
+			managed = append(managed, i)
+		}
+	}
+	sorted := append([]int(nil), managed...)
+	slices.SortStableFunc(sorted, func(a, b int) int {
+		return cmp.Compare(rank[sections[a].Name], rank[sections[b].Name])
+	})
+	desired := append([]asana.Section(nil), sections...)
+	for i, position := range managed {
+		desired[position] = sections[sorted[i]]
+	}
+
+	// Sections already in the right order relative to each other stay put,
+	// always including every unmanaged section
+	current := make(map[string]int, len(sections))
+	for i, section := range sections {
+		current[section.GID] = i
+	}
+	positions := make([]int, len(desired))
+	for i, section := range desired {
+		positions[i] = current[section.GID]
+	}
+	inPlace := heaviestIncreasing(positions, func(position int) int {
+		if _, ok := rank[sections[position].Name]; ok {
+			return 1
+		}
+		return len(sections) + 1
+	})
+	if len(sections) > 0 && positions[0] == 0 {
+		inPlace[0] = true
+	}
+
+	var moves []SectionReorder
+	for i, section := range desired {
+		if inPlace[current[section.GID]] {
+			continue
+		}
+		move := SectionReorder{Section: section}
+		if i > 0 {
+			move.After = desired[i-1]
+		} else {
+			move.Before = sections[0]
+		}
+		moves = append(moves, move)
+	}
+
+	return moves
+}
+
+// ReorderSections puts the managed sections of the project in the configured order
+func ReorderSections(ctx context.Context, client asana.API, projectGID string, moves []SectionReorder,
+	reporter Reporter) error {
+
+	for _, move := range moves {
+		err := client.InsertSection(ctx, projectGID, asana.SectionInsert{
+			SectionGID:    move.Section.GID,
+			BeforeSection: move.Before.GID,
+			AfterSection:  move.After.GID,
+		})
+		if err != nil {
+			return fmt.Errorf("error moving section '%s': %w", move.Section.Name, err)
+		}
+		report(reporter, Event{Type: EventSectionMoved, SectionReorder: &move})
+	}
+	return nil
+}
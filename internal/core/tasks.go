@@ -2,26 +2,50 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/dackerman/asana-tasks-sorter/internal/asana"
-	"github.com/dackerman/asana-tasks-sorter/internal/ui"
 )
 
+// errNoUserTaskList is returned when the user has no My Tasks list in the chosen workspace
+var errNoUserTaskList = errors.New("no My Tasks list found")
+
 // TaskMove represents a task that should be moved to a new section
 type TaskMove struct {
 	Task        asana.Task
 	SectionGID  string
 	SectionName string
+
+	// Rule names the rule that selected the target section
+	Rule string
+
+	// InsertBefore or InsertAfter, if set, is the GID of the task in the target
+	// section that the moved task is placed next to
+	InsertBefore string
+	InsertAfter  string
+	// Reorder marks a task that stays in its section and only changes position
+	Reorder bool
+}
+
+// sectionMove converts the move into an Asana addTask request
+func (m TaskMove) sectionMove() asana.SectionMove {
+	return asana.SectionMove{
+		SectionGID:   m.SectionGID,
+		TaskGID:      m.Task.GID,
+		InsertBefore: m.InsertBefore,
+		InsertAfter:  m.InsertAfter,
+	}
 }
 
 // CategorizeTasks sorts a list of tasks into categories based on due date
-func CategorizeTasks(tasks []asana.Task, now time.Time) map[asana.TaskCategory][]asana.Task {
+func CategorizeTasks(tasks []asana.Task, now time.Time, opts asana.CategoryOptions) map[asana.TaskCategory][]asana.Task {
 	categorized := make(map[asana.TaskCategory][]asana.Task)
 
 	for _, task := range tasks {
-		category := task.GetTaskCategory(now)
+		category := task.Categorize(now, opts)
 		categorized[category] = append(categorized[category], task)
 	}
 
@@ -33,9 +57,12 @@ func GetCategoryToSectionMap(config SectionConfig) map[asana.TaskCategory]string
 	return map[asana.TaskCategory]string{
 		asana.Overdue:     config.Overdue,
 		asana.DueToday:    config.DueToday,
+		asana.DueTomorrow: config.DueTomorrow,
 		asana.DueThisWeek: config.DueThisWeek,
 		asana.DueLater:    config.DueLater,
 		asana.NoDate:      config.NoDate,
+		asana.NotStarted:  config.NotStarted,
+		asana.StartsToday: config.StartsToday,
 	}
 }
 
@@ -46,8 +73,9 @@ func CalculateTaskMoves(tasks []asana.Task, config SectionConfig,
 
 	var moves []TaskMove
 
-	// Map categories to section names
-	categoryToSection := GetCategoryToSectionMap(config)
+	// Custom rules first, then the due-date buckets
+	rules := EffectiveRules(config)
+	opts := config.CategoryOptions()
 
 	for _, task := range tasks {
 		// Get current section name
@@ -58,11 +86,14 @@ func CalculateTaskMoves(tasks []asana.Task, config SectionConfig,
 			continue
 		}
 
-		// Calculate which category the task belongs in
-		category := task.GetTaskCategory(now)
+		// Find the first rule the task matches
+		rule, matched := MatchRule(rules, task, now, opts)
+		if !matched {
+			continue
+		}
 
-		// Get the target section name for this category
-		targetSectionName := categoryToSection[category]
+		// Get the target section name for this rule
+		targetSectionName := rule.Section
 
 		// Skip if target section is in the ignored list
 		if ignoredSections[targetSectionName] {
@@ -85,71 +116,337 @@ func CalculateTaskMoves(tasks []asana.Task, config SectionConfig,
 			Task:        task,
 			SectionGID:  sectionGID,
 			SectionName: targetSectionName,
+			Rule:        rule.DisplayName(),
 		})
 	}
 
 	return moves
 }
 
+// MissingSections returns the required sections from the configured rules that don't exist yet
+func MissingSections(config SectionConfig, sectionNameToGID map[string]string) []string {
+	var missing []string
+	for _, sectionName := range RequiredSectionNames(EffectiveRules(config)) {
+		if _, exists := sectionNameToGID[sectionName]; !exists {
+			missing = append(missing, sectionName)
+		}
+	}
+	return missing
+}
+
 // EnsureRequiredSections creates any missing required sections
 func EnsureRequiredSections(ctx context.Context, client asana.API, projectGID string, config SectionConfig,
-	sections *[]asana.Section, sectionNameToGID map[string]string) error {
+	sections *[]asana.Section, sectionNameToGID map[string]string, reporter Reporter) error {
+
+	for _, sectionName := range MissingSections(config, sectionNameToGID) {
+		newSection, err := client.CreateSection(ctx, projectGID, sectionName)
+		if err != nil {
+			return fmt.Errorf("error creating section '%s': %w", sectionName, err)
+		}
+		report(reporter, Event{Type: EventSectionCreated, Section: newSection.Name})
+		sectionNameToGID[newSection.Name] = newSection.GID
+		*sections = append(*sections, *newSection)
+	}
+	return nil
+}
+
+// CreatePlannedSections creates the named sections a dry run planned moves into,
+// unless they have been created since, and points those moves at them
+func CreatePlannedSections(ctx context.Context, client asana.API, projectGID string, sectionNames []string,
+	moves []TaskMove, reporter Reporter) error {
 
-	// List of required sections from config
-	requiredSections := []string{
-		config.Overdue,
-		config.DueToday,
-		config.DueThisWeek,
-		config.DueLater,
-		config.NoDate,
+	if len(sectionNames) == 0 {
+		return nil
+	}
+	sections, err := client.GetSectionsForProject(ctx, projectGID)
+	if err != nil {
+		return fmt.Errorf("error getting sections: %w", err)
+	}
+	sectionNameToGID := CreateSectionNameToGIDMap(sections)
+	for _, sectionName := range sectionNames {
+		if _, exists := sectionNameToGID[sectionName]; exists {
+			continue
+		}
+		newSection, err := client.CreateSection(ctx, projectGID, sectionName)
+		if err != nil {
+			return fmt.Errorf("error creating section '%s': %w", sectionName, err)
+		}
+		report(reporter, Event{Type: EventSectionCreated, Section: newSection.Name})
+		sectionNameToGID[newSection.Name] = newSection.GID
 	}
 
-	for _, sectionName := range requiredSections {
-		if _, exists := sectionNameToGID[sectionName]; !exists {
-			fmt.Printf("%s %s\n", ui.Operation("Creating section:"), ui.SectionName(sectionName))
-			newSection, err := client.CreateSection(ctx, projectGID, sectionName)
-			if err != nil {
-				return fmt.Errorf("error creating section '%s': %w", sectionName, err)
-			}
-			sectionNameToGID[newSection.Name] = newSection.GID
-			*sections = append(*sections, *newSection)
+	for i := range moves {
+		if moves[i].SectionGID == "" {
+			moves[i].SectionGID = sectionNameToGID[moves[i].SectionName]
 		}
 	}
 	return nil
 }
 
-// ExecuteTaskMoves performs the actual moves in Asana
-func ExecuteTaskMoves(ctx context.Context, client asana.API, taskMoves []TaskMove) error {
+// ExecuteTaskMoves performs the actual moves in Asana using up to concurrency
+// parallel requests, and returns the outcome of each move attempted in the
+// order the moves were planned. Events are reported in that same order.
+// Moves are grouped into batch requests of up to batchSize moves; a batch size
+// of one (or less) moves each task with its own request.
+func ExecuteTaskMoves(ctx context.Context, client asana.API, taskMoves []TaskMove, concurrency, batchSize int,
+	reporter Reporter) ([]MoveResult, error) {
+
+	report(reporter, Event{Type: EventMovesStarted, Count: len(taskMoves)})
 	if len(taskMoves) == 0 {
-		fmt.Println("\n" + ui.Info("No tasks need to be moved"))
-		return nil
+		return nil, nil
+	}
+
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	if batchSize > asana.MaxBatchActions {
+		batchSize = asana.MaxBatchActions
 	}
 
-	fmt.Println("\n" + ui.Header("Moving tasks to appropriate sections..."))
-	errors := 0
+	jobs := groupTaskMoves(taskMoves, batchSize)
 
-	for _, move := range taskMoves {
-		fmt.Printf("%s %s %s %s\n", 
-			ui.Operation("Moving task"),
-			ui.TaskName("'"+move.Task.Name+"'"), 
-			ui.Subtle("to section:"),
-			ui.SectionName(move.SectionName))
-		err := client.MoveTaskToSection(ctx, move.SectionGID, move.Task.GID)
-		if err != nil {
-			fmt.Printf("%s %s: %v\n", 
-				ui.Error("Error moving task"),
-				ui.TaskName("'"+move.Task.Name+"'"), 
-				err)
-			errors++
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+	if concurrency > len(jobs) {
+		concurrency = len(jobs)
+	}
+
+	// A bad token will fail every remaining move, so stop handing out work once we see one
+	parentCtx := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]MoveResult, len(taskMoves))
+	attempted := make([]bool, len(taskMoves))
+	done := make(chan int)
+	queue := make(chan moveJob)
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				// Leave the moves unattempted if the run was stopped while they were queued
+				if ctx.Err() != nil {
+					continue
+				}
+				moves := make([]TaskMove, len(job.indexes))
+				for i, index := range job.indexes {
+					moves[i] = taskMoves[index]
+				}
+
+				var errs []error
+				if job.inOrder {
+					errs = moveTasksInOrder(ctx, client, moves)
+				} else {
+					errs = moveTasks(ctx, client, moves, reporter)
+				}
+				for i, err := range errs {
+					if asana.IsUnauthorized(err) {
+						cancel()
+					}
+					results[job.indexes[i]] = MoveResult{Move: moves[i], Err: err, At: time.Now()}
+					done <- job.indexes[i]
+				}
+			}
+		}()
+	}
+
+	// Hand out jobs until they run out or the run is cancelled
+	go func() {
+		defer close(queue)
+		for _, job := range jobs {
+			select {
+			case queue <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Report results in planned order as soon as every earlier move has finished
+	reportResult := func(result MoveResult) {
+		report(reporter, Event{Type: EventMoveStarted, Move: &result.Move})
+		if result.Err != nil {
+			report(reporter, Event{Type: EventMoveFailed, Move: &result.Move, Err: result.Err})
+		} else {
+			report(reporter, Event{Type: EventMoveSucceeded, Move: &result.Move})
+		}
+	}
+	next := 0
+	for i := range done {
+		attempted[i] = true
+		for next < len(taskMoves) && attempted[next] {
+			reportResult(results[next])
+			next++
 		}
 	}
 
-	if errors > 0 {
-		return fmt.Errorf("%s", ui.Error(fmt.Sprintf("%d errors occurred while moving tasks", errors)))
+	// A cancelled run leaves gaps, and moves made after a gap still happened
+	for ; next < len(taskMoves); next++ {
+		if attempted[next] {
+			reportResult(results[next])
+		}
 	}
 
-	fmt.Printf("\n%s\n", ui.Success(fmt.Sprintf("Moved %d tasks to their appropriate sections", len(taskMoves))))
-	return nil
+	// Collect the moves that were actually attempted, in order
+	var attemptedResults []MoveResult
+	var failed []MoveResult
+	for i, result := range results {
+		if !attempted[i] {
+			continue
+		}
+		attemptedResults = append(attemptedResults, result)
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+
+	skipped := len(taskMoves) - len(attemptedResults)
+	if len(failed) > 0 {
+		err := &MoveErrors{Failed: failed, Skipped: skipped}
+		report(reporter, Event{Type: EventMovesFinished, Count: len(attemptedResults) - len(failed), Err: err})
+		return attemptedResults, err
+	}
+	if skipped > 0 {
+		err := fmt.Errorf("%d moves were not attempted: %w", skipped, parentCtx.Err())
+		report(reporter, Event{Type: EventMovesFinished, Count: len(attemptedResults), Err: err})
+		return attemptedResults, err
+	}
+
+	report(reporter, Event{Type: EventMovesFinished, Count: len(attemptedResults)})
+	return attemptedResults, nil
+}
+
+// moveJob is a unit of work for a move worker: either a batch of independent
+// moves, or a chain of moves that must be made one after another
+type moveJob struct {
+	indexes []int
+	inOrder bool
+}
+
+// groupTaskMoves splits the moves into jobs. A move placed next to a task that
+// is itself being moved has to wait for that move, so such moves are chained
+// together and made in planned order. The remaining moves are packed into
+// batches of up to batchSize.
+func groupTaskMoves(taskMoves []TaskMove, batchSize int) []moveJob {
+	// Link each move to the earliest move in its chain
+	moved := make(map[string]int, len(taskMoves))
+	for i, move := range taskMoves {
+		moved[move.Task.GID] = i
+	}
+	parent := make([]int, len(taskMoves))
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(i int) int
+	root = func(i int) int {
+		for parent[i] != i {
+			i = parent[i]
+		}
+		return i
+	}
+	for i, move := range taskMoves {
+		for _, anchor := range []string{move.InsertBefore, move.InsertAfter} {
+			if j, ok := moved[anchor]; ok && anchor != "" {
+				a, b := root(i), root(j)
+				parent[max(a, b)] = min(a, b)
+			}
+		}
+	}
+
+	chains := make(map[int][]int)
+	for i := range taskMoves {
+		chains[root(i)] = append(chains[root(i)], i)
+	}
+
+	var jobs []moveJob
+	var batch []int
+	for i := range taskMoves {
+		chain := chains[root(i)]
+		if len(chain) > 1 {
+			if root(i) == i {
+				jobs = append(jobs, moveJob{indexes: chain, inOrder: true})
+			}
+			continue
+		}
+		batch = append(batch, i)
+		if len(batch) == batchSize {
+			jobs = append(jobs, moveJob{indexes: batch})
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		jobs = append(jobs, moveJob{indexes: batch})
+	}
+
+	return jobs
+}
+
+// moveTasksInOrder makes a chain of moves one request at a time, in order.
+// It stops early if the run is cancelled, returning errors only for the moves
+// it attempted.
+func moveTasksInOrder(ctx context.Context, client asana.API, moves []TaskMove) []error {
+	errs := make([]error, 0, len(moves))
+	for _, move := range moves {
+		if ctx.Err() != nil {
+			break
+		}
+		errs = append(errs, client.MoveTaskToSection(ctx, move.sectionMove()))
+	}
+	return errs
+}
+
+// moveTasks moves a group of tasks, using a single batch request when there is
+// more than one. If the batch request fails, or Asana rate limits or fails
+// individual actions within it, those moves are retried one request at a time.
+func moveTasks(ctx context.Context, client asana.API, moves []TaskMove, reporter Reporter) []error {
+	errs := make([]error, len(moves))
+	retry := make([]bool, len(moves))
+
+	if len(moves) == 1 {
+		retry[0] = true
+	} else {
+		sectionMoves := make([]asana.SectionMove, len(moves))
+		for i, move := range moves {
+			sectionMoves[i] = move.sectionMove()
+		}
+
+		batchErrs, err := client.MoveTasksToSections(ctx, sectionMoves)
+		switch {
+		case err != nil && (asana.IsUnauthorized(err) || ctx.Err() != nil):
+			// Retrying one at a time can't succeed either
+			for i := range errs {
+				errs[i] = err
+			}
+			return errs
+		case err != nil:
+			report(reporter, Event{Type: EventBatchFailed, Count: len(moves), Err: err})
+			for i := range retry {
+				retry[i] = true
+			}
+		default:
+			for i, moveErr := range batchErrs {
+				errs[i] = moveErr
+				retry[i] = asana.IsRateLimited(moveErr) || asana.IsServerError(moveErr)
+			}
+		}
+	}
+
+	for i, move := range moves {
+		if retry[i] {
+			errs[i] = client.MoveTaskToSection(ctx, move.sectionMove())
+		}
+	}
+
+	return errs
 }
 
 // CreateIgnoredSectionsMap converts a slice of ignored section names to a map for quick lookup
@@ -170,89 +467,191 @@ func CreateSectionNameToGIDMap(sections []asana.Section) map[string]string {
 	return result
 }
 
-// OrganizeTasks is the main business logic function that fetches and organizes tasks
-func OrganizeTasks(ctx context.Context, client asana.API, config SectionConfig, dryRun bool) (map[asana.TaskCategory][]asana.Task, error) {
+// batchSize returns the configured batch size, falling back to the default
+func batchSize(config SectionConfig) int {
+	if config.BatchSize == 0 {
+		return DefaultBatchSize
+	}
+	return config.BatchSize
+}
+
+// OrganizeTasks is the main business logic function that fetches and organizes tasks.
+// Progress is sent to reporter; pass NopReporter{} (or nil) to run quietly.
+func OrganizeTasks(ctx context.Context, client asana.API, config SectionConfig, dryRun bool, reporter Reporter) (*RunResult, error) {
+	result := &RunResult{DryRun: dryRun}
+
 	// Get current user
 	user, err := client.GetCurrentUser(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error getting current user: %w", err)
+		return result, fmt.Errorf("error getting current user: %w", err)
+	}
+	result.User = *user
+	report(reporter, Event{Type: EventLoggedIn, User: user})
+
+	// Decide which time zone "today" is evaluated in
+	loc, err := ResolveLocation(config.Timezone, user.TimeZone)
+	if err != nil {
+		return result, err
 	}
-	fmt.Printf("%s %s\n", ui.Info("Logged in as:"), ui.Important(user.Name))
+	result.TimeZone = loc.String()
+	report(reporter, Event{Type: EventTimeZoneChosen, TimeZone: loc.String()})
 
 	// Get workspaces
 	workspaces, err := client.GetWorkspaces(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error getting workspaces: %w", err)
+		return result, fmt.Errorf("error getting workspaces: %w", err)
 	}
 
 	if len(workspaces) == 0 {
-		return nil, fmt.Errorf("no workspaces found for user")
+		return result, fmt.Errorf("no workspaces found for user")
 	}
 
-	// Use first workspace
-	workspace := workspaces[0]
-	fmt.Printf("%s %s\n", ui.Info("Using workspace:"), ui.Important(workspace.Name))
+	if config.AllWorkspaces {
+		return result, organizeAllWorkspaces(ctx, client, result, workspaces, loc, config, reporter)
+	}
+
+	// Pick the workspace requested in config, or the only one available
+	workspace, err := SelectWorkspace(workspaces, config.Workspace)
+	if err != nil {
+		return result, err
+	}
+	report(reporter, Event{Type: EventWorkspaceChosen, Workspace: &workspace})
+
+	workspaceResult := organizeWorkspace(ctx, client, user, workspace, loc, config, dryRun, reporter)
+	result.Workspaces = append(result.Workspaces, workspaceResult)
+
+	return result, workspaceResult.Err
+}
+
+// organizeAllWorkspaces sorts the My Tasks list of every workspace in turn and
+// prints a combined summary. Failures in one workspace don't stop the others.
+func organizeAllWorkspaces(ctx context.Context, client asana.API, result *RunResult, workspaces []asana.Workspace,
+	loc *time.Location, config SectionConfig, reporter Reporter) error {
+
+	failed := 0
+	for _, workspace := range workspaces {
+		report(reporter, Event{Type: EventWorkspaceChosen, Workspace: &workspace})
+
+		workspaceResult := organizeWorkspace(ctx, client, &result.User, workspace, loc, config, result.DryRun, reporter)
+		if workspaceResult.Err != nil {
+			report(reporter, Event{Type: EventWorkspaceFailed, Workspace: &workspace, Err: workspaceResult.Err})
+			failed++
+		}
+		result.Workspaces = append(result.Workspaces, workspaceResult)
+	}
+
+	report(reporter, Event{Type: EventRunFinished, Result: result})
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d workspaces failed to sort", failed, len(result.Workspaces))
+	}
+
+	return nil
+}
+
+// organizeWorkspace fetches and sorts the user's My Tasks list in a single workspace.
+// Any failure is recorded in the returned result's Err field.
+func organizeWorkspace(ctx context.Context, client asana.API, user *asana.User, workspace asana.Workspace,
+	loc *time.Location, config SectionConfig, dryRun bool, reporter Reporter) WorkspaceResult {
+
+	result := WorkspaceResult{Workspace: workspace}
 
 	// Get user's "My Tasks" list
 	userTaskList, err := client.GetUserTaskList(ctx, user.GID, workspace.GID)
 	if err != nil {
-		return nil, fmt.Errorf("error getting user task list: %w", err)
+		if asana.IsNotFound(err) {
+			result.Err = fmt.Errorf("%w in workspace '%s': %w", errNoUserTaskList, workspace.Name, err)
+		} else {
+			result.Err = fmt.Errorf("error getting user task list: %w", err)
+		}
+		return result
 	}
 
+	return organizeTaskList(ctx, client, workspace, userTaskList.GID, loc, config, dryRun, reporter)
+}
+
+// organizeTaskList sorts a My Tasks list. Any failure is recorded in the returned result's Err field.
+func organizeTaskList(ctx context.Context, client asana.API, workspace asana.Workspace, taskListGID string,
+	loc *time.Location, config SectionConfig, dryRun bool, reporter Reporter) WorkspaceResult {
+
+	result := WorkspaceResult{Workspace: workspace, TaskListGID: taskListGID}
+
 	// Get sections in My Tasks list (using the project/sections API)
-	sections, err := client.GetSectionsForProject(ctx, userTaskList.GID)
+	sections, err := client.GetSectionsForProject(ctx, taskListGID)
 	if err != nil {
-		return nil, fmt.Errorf("error getting sections: %w", err)
+		result.Err = fmt.Errorf("error getting sections: %w", err)
+		return result
 	}
 
 	// Create a map to store section names to their GIDs
 	sectionNameToGID := CreateSectionNameToGIDMap(sections)
 
-	// Ensure required sections exist, create them if needed
-	if !dryRun {
-		if err := EnsureRequiredSections(ctx, client, userTaskList.GID, config, &sections, sectionNameToGID); err != nil {
-			return nil, fmt.Errorf("error ensuring required sections: %w", err)
+	// Ensure required sections exist, create them if needed. A dry run only
+	// lists them, and plans moves into them without a section GID.
+	if dryRun {
+		result.SectionsToCreate = MissingSections(config, sectionNameToGID)
+		for _, sectionName := range result.SectionsToCreate {
+			sectionNameToGID[sectionName] = ""
 		}
+	} else if err := EnsureRequiredSections(ctx, client, taskListGID, config, &sections, sectionNameToGID, reporter); err != nil {
+		result.Err = fmt.Errorf("error ensuring required sections: %w", err)
+		return result
 	}
 
+	// Put the managed sections in their configured order
+	if config.OrderSections {
+		result.SectionReorders = CalculateSectionOrder(sections, config)
+		if !dryRun {
+			if err := ReorderSections(ctx, client, taskListGID, result.SectionReorders, reporter); err != nil {
+				result.Err = fmt.Errorf("error reordering sections: %w", err)
+				return result
+			}
+		}
+	}
+
+	result.Sections = sections
+
 	// Create a map of ignored sections for quick lookup
 	ignoredSections := CreateIgnoredSectionsMap(config.IgnoredSections)
 
 	// Collect all tasks from user task list at once
-	fmt.Println(ui.Header("Fetching all tasks from My Tasks list..."))
-	allTasks, err := client.GetTasksFromUserTaskList(ctx, userTaskList.GID)
+	report(reporter, Event{Type: EventFetchingTasks, Workspace: &workspace})
+	allTasks, err := client.GetTasksFromUserTaskList(ctx, taskListGID)
 	if err != nil {
-		return nil, fmt.Errorf("error getting tasks from user task list: %w", err)
+		result.Err = fmt.Errorf("error getting tasks from user task list: %w", err)
+		return result
 	}
 
-	// Print tasks we're skipping due to being in ignored sections
+	// Report tasks we're skipping due to being in ignored sections
 	for _, task := range allTasks {
 		sectionName := task.AssigneeSection.Name
 		if ignoredSections[sectionName] {
-			fmt.Printf("%s %s %s %s%s\n",
-				ui.Subtle("Skipping task in ignored section:"),
-				ui.TaskName(task.Name), 
-				ui.Subtle("(in section"),
-				ui.SectionName(" '"+sectionName+"'"),
-				ui.Subtle(")"))
+			report(reporter, Event{Type: EventTaskSkipped, Task: &task, Section: sectionName})
 		}
 	}
 
 	// Get the current time once for consistency across all operations
-	now := time.Now()
+	now := time.Now().In(loc)
 
 	// Sort tasks into categories based on due date for display purposes
-	categorizedTasks := CategorizeTasks(allTasks, now)
+	result.Categorized = CategorizeTasks(allTasks, now, config.CategoryOptions())
+	result.Tasks = DescribeTasks(allTasks, config, ignoredSections, now)
 
 	// Calculate task moves without side effects
-	taskMoves := CalculateTaskMoves(allTasks, config, sectionNameToGID, ignoredSections, now)
+	// and place each moved task within its target section
+	moves := CalculateTaskMoves(allTasks, config, sectionNameToGID, ignoredSections, now)
+	result.PlannedMoves = OrderTaskMoves(allTasks, moves, config, ignoredSections, now)
+	for i := range result.PlannedMoves {
+		report(reporter, Event{Type: EventMovePlanned, Move: &result.PlannedMoves[i]})
+	}
 
 	// Execute the moves if not in dry run mode
-	if !dryRun && len(taskMoves) > 0 {
-		if err := ExecuteTaskMoves(ctx, client, taskMoves); err != nil {
-			return nil, fmt.Errorf("error executing task moves: %w", err)
+	if !dryRun && len(result.PlannedMoves) > 0 {
+		result.MoveResults, err = ExecuteTaskMoves(ctx, client, result.PlannedMoves, config.Concurrency, batchSize(config), reporter)
+		if err != nil {
+			result.Err = fmt.Errorf("error executing task moves: %w", err)
 		}
 	}
 
-	return categorizedTasks, nil
-}
\ No newline at end of file
+	return result
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+)
+
+// Reasons a recorded move can't be safely undone
+var (
+	ErrTaskDeleted   = errors.New("task no longer exists")
+	ErrTaskCompleted = errors.New("task has been completed")
+	ErrTaskMovedOn   = errors.New("task has been moved since the sorter moved it")
+)
+
+// Revert describes a recorded move to undo: the task was moved from one section to another
+type Revert struct {
+	TaskGID  string
+	TaskName string
+	From     asana.Section
+	To       asana.Section
+}
+
+// SkippedRevert is a recorded move that undo left alone, and why
+type SkippedRevert struct {
+	Revert Revert
+	Reason error
+}
+
+// UndoResult describes the outcome of undoing a run
+type UndoResult struct {
+	DryRun bool
+	// PlannedMoves are the moves that put the tasks back
+	PlannedMoves []TaskMove
+	MoveResults  []MoveResult
+	Skipped      []SkippedRevert
+}
+
+// UndoMoves moves tasks back to the sections they were in before a sorting run.
+// Each task is fetched first; tasks that were deleted or completed are skipped,
+// as are tasks that have been moved to another section since, unless force is set.
+// With dryRun the moves are only planned.
+func UndoMoves(ctx context.Context, client asana.API, reverts []Revert, force, dryRun bool, concurrency, batchSize int,
+	reporter Reporter) (*UndoResult, error) {
+
+	result := &UndoResult{DryRun: dryRun}
+	for _, revert := range reverts {
+		task, err := client.GetTask(ctx, revert.TaskGID)
+		var reason error
+		switch {
+		case asana.IsNotFound(err):
+			reason = ErrTaskDeleted
+		case err != nil:
+			return result, fmt.Errorf("error getting task '%s': %w", revert.TaskName, err)
+		case task.AssigneeSection.GID == revert.From.GID:
+			// Already back where it was, e.g. when retrying a partly failed undo
+			continue
+		case task.Completed:
+			reason = ErrTaskCompleted
+		case task.AssigneeSection.GID != revert.To.GID && !force:
+			reason = fmt.Errorf("%w (now in '%s')", ErrTaskMovedOn, task.AssigneeSection.Name)
+		}
+
+		if reason != nil {
+			result.Skipped = append(result.Skipped, SkippedRevert{Revert: revert, Reason: reason})
+			report(reporter, Event{Type: EventUndoSkipped, Task: &asana.Task{GID: revert.TaskGID, Name: revert.TaskName}, Err: reason})
+			continue
+		}
+
+		result.PlannedMoves = append(result.PlannedMoves, TaskMove{
+			Task:        *task,
+			SectionGID:  revert.From.GID,
+			SectionName: revert.From.Name,
+			Rule:        "undo",
+		})
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	var err error
+	result.MoveResults, err = ExecuteTaskMoves(ctx, client, result.PlannedMoves, concurrency, batchSize, reporter)
+	return result, err
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+)
+
+// SelectWorkspace picks the workspace matching selector, which may be either a
+// workspace GID or a (case-insensitive) workspace name. An empty selector is
+// only allowed when the user belongs to exactly one workspace.
+func SelectWorkspace(workspaces []asana.Workspace, selector string) (asana.Workspace, error) {
+	if len(workspaces) == 0 {
+		return asana.Workspace{}, fmt.Errorf("no workspaces found for user")
+	}
+
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		if len(workspaces) == 1 {
+			return workspaces[0], nil
+		}
+		return asana.Workspace{}, fmt.Errorf("you belong to %d workspaces, choose one with --workspace or --all-workspaces:\n%s",
+			len(workspaces), formatWorkspaceList(workspaces))
+	}
+
+	// An exact GID match always wins
+	for _, workspace := range workspaces {
+		if workspace.GID == selector {
+			return workspace, nil
+		}
+	}
+
+	var matches []asana.Workspace
+	for _, workspace := range workspaces {
+		if strings.EqualFold(workspace.Name, selector) {
+			matches = append(matches, workspace)
+		}
+	}
+
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		return asana.Workspace{}, fmt.Errorf("no workspace matches '%s', available workspaces:\n%s",
+			selector, formatWorkspaceList(workspaces))
+	default:
+		return asana.Workspace{}, fmt.Errorf("workspace name '%s' is ambiguous, use one of these GIDs instead:\n%s",
+			selector, formatWorkspaceList(matches))
+	}
+}
+
+// formatWorkspaceList renders workspaces as an indented "name (gid)" list
+func formatWorkspaceList(workspaces []asana.Workspace) string {
+	lines := make([]string, 0, len(workspaces))
+	for _, workspace := range workspaces {
+		lines = append(lines, fmt.Sprintf("  - %s (%s)", workspace.Name, workspace.GID))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,283 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+)
+
+// Output formats
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+)
+
+// ValidFormat reports whether format is a supported output format
+func ValidFormat(format string) bool {
+	return format == FormatText || format == FormatJSON || format == FormatNDJSON
+}
+
+// Document is the machine-readable form of a sorting run
+type Document struct {
+	User       *User       `json:"user,omitempty"`
+	TimeZone   string      `json:"time_zone,omitempty"`
+	DryRun     bool        `json:"dry_run"`
+	Workspaces []Workspace `json:"workspaces"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// User identifies the Asana user the run was performed as
+type User struct {
+	GID  string `json:"gid"`
+	Name string `json:"name"`
+}
+
+// Workspace holds the results for a single workspace
+type Workspace struct {
+	GID           string        `json:"gid"`
+	Name          string        `json:"name"`
+	NewSections   []string      `json:"new_sections,omitempty"`
+	SectionMoves  []SectionMove `json:"section_moves,omitempty"`
+	Tasks         []Task        `json:"tasks"`
+	PlannedMoves  []Move        `json:"planned_moves"`
+	ExecutedMoves []Move        `json:"executed_moves"`
+	Errors        []MoveError   `json:"errors"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// Task describes a task and where it belongs
+type Task struct {
+	GID            string     `json:"gid"`
+	Name           string     `json:"name"`
+	DueOn          asana.Date `json:"due_on"`
+	DueAt          *time.Time `json:"due_at"`
+	StartOn        asana.Date `json:"start_on"`
+	CurrentSection string     `json:"current_section"`
+	TargetSection  string     `json:"target_section,omitempty"`
+	Category       string     `json:"category"`
+	Rule           string     `json:"rule,omitempty"`
+	Ignored        bool       `json:"ignored"`
+}
+
+// Move describes a task moving between sections
+type Move struct {
+	TaskGID      string `json:"task_gid"`
+	TaskName     string `json:"task_name"`
+	FromSection  string `json:"from_section"`
+	ToSection    string `json:"to_section"`
+	ToSectionGID string `json:"to_section_gid"`
+	Rule         string `json:"rule,omitempty"`
+	InsertBefore string `json:"insert_before,omitempty"`
+	InsertAfter  string `json:"insert_after,omitempty"`
+	Reorder      bool   `json:"reorder,omitempty"`
+}
+
+// SectionMove describes a section moving next to another section
+type SectionMove struct {
+	SectionGID  string `json:"section_gid"`
+	SectionName string `json:"section_name"`
+	Before      string `json:"before,omitempty"`
+	After       string `json:"after,omitempty"`
+}
+
+// MoveError describes a move that failed
+type MoveError struct {
+	Move
+	Error string `json:"error"`
+}
+
+// NewDocument converts a run result (and the error it ended with, if any) into a Document
+func NewDocument(result *core.RunResult, runErr error) Document {
+	doc := Document{Workspaces: []Workspace{}}
+	if runErr != nil {
+		doc.Error = runErr.Error()
+	}
+	if result == nil {
+		return doc
+	}
+
+	doc.DryRun = result.DryRun
+	doc.TimeZone = result.TimeZone
+	if result.User.GID != "" {
+		doc.User = &User{GID: result.User.GID, Name: result.User.Name}
+	}
+
+	for _, workspaceResult := range result.Workspaces {
+		workspace := Workspace{
+			GID:           workspaceResult.Workspace.GID,
+			Name:          workspaceResult.Workspace.Name,
+			Tasks:         []Task{},
+			PlannedMoves:  []Move{},
+			ExecutedMoves: []Move{},
+			Errors:        []MoveError{},
+		}
+		if workspaceResult.Err != nil {
+			workspace.Error = workspaceResult.Err.Error()
+		}
+
+		workspace.NewSections = workspaceResult.SectionsToCreate
+		for _, reorder := range workspaceResult.SectionReorders {
+			workspace.SectionMoves = append(workspace.SectionMoves, SectionMove{
+				SectionGID:  reorder.Section.GID,
+				SectionName: reorder.Section.Name,
+				Before:      reorder.Before.Name,
+				After:       reorder.After.Name,
+			})
+		}
+		for _, taskResult := range workspaceResult.Tasks {
+			workspace.Tasks = append(workspace.Tasks, newTask(taskResult))
+		}
+		for _, move := range workspaceResult.PlannedMoves {
+			workspace.PlannedMoves = append(workspace.PlannedMoves, newMove(move))
+		}
+		for _, move := range workspaceResult.ExecutedMoves() {
+			workspace.ExecutedMoves = append(workspace.ExecutedMoves, newMove(move))
+		}
+		for _, failed := range workspaceResult.FailedMoves() {
+			workspace.Errors = append(workspace.Errors, MoveError{Move: newMove(failed.Move), Error: failed.Err.Error()})
+		}
+
+		doc.Workspaces = append(doc.Workspaces, workspace)
+	}
+
+	return doc
+}
+
+// newTask converts a categorized task
+func newTask(result core.TaskResult) Task {
+	task := Task{
+		GID:            result.Task.GID,
+		Name:           result.Task.Name,
+		DueOn:          result.Task.DueOn,
+		StartOn:        result.Task.StartOn,
+		CurrentSection: result.Task.AssigneeSection.Name,
+		TargetSection:  result.TargetSection,
+		Category:       result.Category.String(),
+		Rule:           result.Rule,
+		Ignored:        result.Ignored,
+	}
+	if !result.Task.DueAt.IsZero() {
+		dueAt := result.Task.DueAt
+		task.DueAt = &dueAt
+	}
+	return task
+}
+
+// newMove converts a planned or executed task move
+func newMove(move core.TaskMove) Move {
+	return Move{
+		TaskGID:      move.Task.GID,
+		TaskName:     move.Task.Name,
+		FromSection:  move.Task.AssigneeSection.Name,
+		ToSection:    move.SectionName,
+		ToSectionGID: move.SectionGID,
+		Rule:         move.Rule,
+		InsertBefore: move.InsertBefore,
+		InsertAfter:  move.InsertAfter,
+		Reorder:      move.Reorder,
+	}
+}
+
+// WriteJSON writes the run as a single indented JSON document
+func WriteJSON(w io.Writer, result *core.RunResult, runErr error) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(NewDocument(result, runErr))
+}
+
+// WriteNDJSON writes the run as newline-delimited JSON, one record per line.
+// Every record carries a "type" field: run, new_section, section_move, task, planned_move,
+// executed_move, move_error or workspace_error.
+func WriteNDJSON(w io.Writer, result *core.RunResult, runErr error) error {
+	doc := NewDocument(result, runErr)
+	encoder := json.NewEncoder(w)
+
+	write := func(recordType string, workspace *Workspace, record interface{}) error {
+		line := map[string]interface{}{"type": recordType}
+		if workspace != nil {
+			line["workspace_gid"] = workspace.GID
+			line["workspace_name"] = workspace.Name
+		}
+		if record != nil {
+			fields, err := toFields(record)
+			if err != nil {
+				return err
+			}
+			for key, value := range fields {
+				line[key] = value
+			}
+		}
+		return encoder.Encode(line)
+	}
+
+	for i := range doc.Workspaces {
+		workspace := &doc.Workspaces[i]
+		for _, sectionName := range workspace.NewSections {
+			if err := write("new_section", workspace, map[string]string{"section_name": sectionName}); err != nil {
+				return err
+			}
+		}
+		for _, sectionMove := range workspace.SectionMoves {
+			if err := write("section_move", workspace, sectionMove); err != nil {
+				return err
+			}
+		}
+		for _, task := range workspace.Tasks {
+			if err := write("task", workspace, task); err != nil {
+				return err
+			}
+		}
+		for _, move := range workspace.PlannedMoves {
+			if err := write("planned_move", workspace, move); err != nil {
+				return err
+			}
+		}
+		for _, move := range workspace.ExecutedMoves {
+			if err := write("executed_move", workspace, move); err != nil {
+				return err
+			}
+		}
+		for _, moveErr := range workspace.Errors {
+			if err := write("move_error", workspace, moveErr); err != nil {
+				return err
+			}
+		}
+		if workspace.Error != "" {
+			if err := write("workspace_error", workspace, map[string]string{"error": workspace.Error}); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Finish with a summary record so consumers know the run is complete
+	summary := map[string]interface{}{
+		"dry_run":    doc.DryRun,
+		"time_zone":  doc.TimeZone,
+		"workspaces": len(doc.Workspaces),
+	}
+	if doc.User != nil {
+		summary["user"] = doc.User
+	}
+	if doc.Error != "" {
+		summary["error"] = doc.Error
+	}
+	return write("run", nil, summary)
+}
+
+// toFields flattens a record into a map of its JSON fields
+func toFields(record interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode record: %w", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to encode record: %w", err)
+	}
+	return fields, nil
+}
@@ -0,0 +1,126 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+)
+
+// UndoDocument is the machine-readable form of an undo
+type UndoDocument struct {
+	// RanAt is when the undone run happened; nil when there was no run to undo
+	RanAt  *time.Time `json:"ran_at"`
+	DryRun bool       `json:"dry_run"`
+	// Planned are the moves that put the tasks back; a dry run makes none of them
+	Planned  []Move        `json:"planned_moves"`
+	Reverted []Move        `json:"reverted_moves"`
+	Skipped  []SkippedMove `json:"skipped_moves"`
+	Errors   []MoveError   `json:"errors"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// SkippedMove describes a recorded move that undo left alone
+type SkippedMove struct {
+	TaskGID  string `json:"task_gid"`
+	TaskName string `json:"task_name"`
+	// Section is the section the task would have been moved back to
+	Section string `json:"section"`
+	Reason  string `json:"reason"`
+}
+
+// NewUndoDocument converts the outcome of undoing the run from ranAt (and the
+// error it ended with, if any) into an UndoDocument
+func NewUndoDocument(ranAt time.Time, result *core.UndoResult, undoErr error) UndoDocument {
+	doc := UndoDocument{Planned: []Move{}, Reverted: []Move{}, Skipped: []SkippedMove{}, Errors: []MoveError{}}
+	if !ranAt.IsZero() {
+		doc.RanAt = &ranAt
+	}
+	if undoErr != nil {
+		doc.Error = undoErr.Error()
+	}
+	if result == nil {
+		return doc
+	}
+
+	doc.DryRun = result.DryRun
+	for _, move := range result.PlannedMoves {
+		doc.Planned = append(doc.Planned, newMove(move))
+	}
+	for _, moveResult := range result.MoveResults {
+		if moveResult.Err != nil {
+			doc.Errors = append(doc.Errors, MoveError{Move: newMove(moveResult.Move), Error: moveResult.Err.Error()})
+		} else {
+			doc.Reverted = append(doc.Reverted, newMove(moveResult.Move))
+		}
+	}
+	for _, skipped := range result.Skipped {
+		doc.Skipped = append(doc.Skipped, SkippedMove{
+			TaskGID:  skipped.Revert.TaskGID,
+			TaskName: skipped.Revert.TaskName,
+			Section:  skipped.Revert.From.Name,
+			Reason:   skipped.Reason.Error(),
+		})
+	}
+	return doc
+}
+
+// WriteUndoJSON writes the undo as a single indented JSON document
+func WriteUndoJSON(w io.Writer, ranAt time.Time, result *core.UndoResult, undoErr error) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(NewUndoDocument(ranAt, result, undoErr))
+}
+
+// WriteUndoNDJSON writes the undo as newline-delimited JSON, one record per
+// line. Every record carries a "type" field: planned_move, reverted_move,
+// skipped_move, move_error or undo.
+func WriteUndoNDJSON(w io.Writer, ranAt time.Time, result *core.UndoResult, undoErr error) error {
+	doc := NewUndoDocument(ranAt, result, undoErr)
+	encoder := json.NewEncoder(w)
+
+	write := func(recordType string, record interface{}) error {
+		fields, err := toFields(record)
+		if err != nil {
+			return err
+		}
+		fields["type"] = recordType
+		return encoder.Encode(fields)
+	}
+
+	for _, move := range doc.Planned {
+		if err := write("planned_move", move); err != nil {
+			return err
+		}
+	}
+	for _, move := range doc.Reverted {
+		if err := write("reverted_move", move); err != nil {
+			return err
+		}
+	}
+	for _, skipped := range doc.Skipped {
+		if err := write("skipped_move", skipped); err != nil {
+			return err
+		}
+	}
+	for _, moveErr := range doc.Errors {
+		if err := write("move_error", moveErr); err != nil {
+			return err
+		}
+	}
+
+	// Finish with a summary record so consumers know the undo is complete
+	summary := map[string]interface{}{
+		"ran_at":   doc.RanAt,
+		"dry_run":  doc.DryRun,
+		"planned":  len(doc.Planned),
+		"reverted": len(doc.Reverted),
+		"skipped":  len(doc.Skipped),
+		"errors":   len(doc.Errors),
+	}
+	if doc.Error != "" {
+		summary["error"] = doc.Error
+	}
+	return write("undo", summary)
+}
@@ -0,0 +1,152 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+)
+
+// Version is the current plan file format version
+const Version = 1
+
+// Plan is a saved set of task moves, together with the sections they were
+// computed against, that can be reviewed and applied later
+type Plan struct {
+	Version    int         `json:"version"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UserGID    string      `json:"user_gid"`
+	UserName   string      `json:"user_name"`
+	Workspaces []Workspace `json:"workspaces"`
+}
+
+// Workspace holds the planned moves for a single My Tasks list
+type Workspace struct {
+	GID         string          `json:"gid"`
+	Name        string          `json:"name"`
+	TaskListGID string          `json:"task_list_gid"`
+	Sections    []asana.Section `json:"sections"`
+	// SectionsToCreate are created when the plan is applied, before the moves
+	// into them, which have no ToSectionGID
+	SectionsToCreate []string `json:"sections_to_create,omitempty"`
+	Moves            []Move   `json:"moves"`
+}
+
+// Move is a planned task move
+type Move struct {
+	TaskGID         string `json:"task_gid"`
+	TaskName        string `json:"task_name"`
+	FromSectionGID  string `json:"from_section_gid"`
+	FromSectionName string `json:"from_section_name"`
+	ToSectionGID    string `json:"to_section_gid"`
+	ToSectionName   string `json:"to_section_name"`
+	Rule            string `json:"rule,omitempty"`
+	InsertBefore    string `json:"insert_before,omitempty"`
+	InsertAfter     string `json:"insert_after,omitempty"`
+	Reorder         bool   `json:"reorder,omitempty"`
+}
+
+// New creates a plan from the result of a dry run. Workspaces that failed are left out.
+func New(result *core.RunResult, createdAt time.Time) *Plan {
+	plan := &Plan{
+		Version:    Version,
+		CreatedAt:  createdAt.UTC(),
+		UserGID:    result.User.GID,
+		UserName:   result.User.Name,
+		Workspaces: []Workspace{},
+	}
+
+	for _, workspaceResult := range result.Workspaces {
+		if workspaceResult.Err != nil {
+			continue
+		}
+		workspace := Workspace{
+			GID:              workspaceResult.Workspace.GID,
+			Name:             workspaceResult.Workspace.Name,
+			TaskListGID:      workspaceResult.TaskListGID,
+			Sections:         workspaceResult.Sections,
+			SectionsToCreate: workspaceResult.SectionsToCreate,
+			Moves:            []Move{},
+		}
+		for _, move := range workspaceResult.PlannedMoves {
+			workspace.Moves = append(workspace.Moves, Move{
+				TaskGID:         move.Task.GID,
+				TaskName:        move.Task.Name,
+				FromSectionGID:  move.Task.AssigneeSection.GID,
+				FromSectionName: move.Task.AssigneeSection.Name,
+				ToSectionGID:    move.SectionGID,
+				ToSectionName:   move.SectionName,
+				Rule:            move.Rule,
+				InsertBefore:    move.InsertBefore,
+				InsertAfter:     move.InsertAfter,
+				Reorder:         move.Reorder,
+			})
+		}
+		plan.Workspaces = append(plan.Workspaces, workspace)
+	}
+
+	return plan
+}
+
+// MoveCount returns the number of moves in the plan
+func (p *Plan) MoveCount() int {
+	count := 0
+	for _, workspace := range p.Workspaces {
+		count += len(workspace.Moves)
+	}
+	return count
+}
+
+// TaskMoves converts the workspace's planned moves back into task moves
+func (w Workspace) TaskMoves() []core.TaskMove {
+	moves := make([]core.TaskMove, 0, len(w.Moves))
+	for _, move := range w.Moves {
+		moves = append(moves, core.TaskMove{
+			Task: asana.Task{
+				GID:             move.TaskGID,
+				Name:            move.TaskName,
+				AssigneeSection: asana.AssigneeSection{GID: move.FromSectionGID, Name: move.FromSectionName},
+			},
+			SectionGID:   move.ToSectionGID,
+			SectionName:  move.ToSectionName,
+			Rule:         move.Rule,
+			InsertBefore: move.InsertBefore,
+			InsertAfter:  move.InsertAfter,
+			Reorder:      move.Reorder,
+		})
+	}
+	return moves
+}
+
+// Save writes the plan to a file
+func Save(path string, plan *Plan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	return nil
+}
+
+// Load reads a plan from a file
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file: %w", err)
+	}
+
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan file: %w", err)
+	}
+	if plan.Version != Version {
+		return nil, fmt.Errorf("plan file has version %d, but this version of the sorter reads version %d", plan.Version, Version)
+	}
+
+	return &plan, nil
+}
@@ -0,0 +1,199 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinInterval is the shortest interval allowed between scheduled runs
+const MinInterval = time.Minute
+
+// Schedule decides when the next run is due
+type Schedule interface {
+	// Next returns the first run time strictly after the given time, in its location
+	Next(after time.Time) time.Time
+}
+
+// Interval runs at a fixed interval
+type Interval time.Duration
+
+// Next implements Schedule
+func (i Interval) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(i))
+}
+
+// Cron runs at the times matched by a standard five-field cron expression:
+// minute, hour, day of month, month and day of week
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// As in cron, when both days are restricted a day matching either runs
+	domAny, dowAny bool
+}
+
+// descriptors are the shorthand cron expressions
+var descriptors = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// Parse reads a schedule: either an interval such as "15m" or "1h", or a cron
+// expression such as "*/30 8-18 * * 1-5" or "@hourly"
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("schedule is empty")
+	}
+
+	if interval, err := time.ParseDuration(spec); err == nil {
+		if interval < MinInterval {
+			return nil, fmt.Errorf("schedule interval must be at least %v, got %v", MinInterval, interval)
+		}
+		return Interval(interval), nil
+	}
+
+	if expanded, ok := descriptors[spec]; ok {
+		spec = expanded
+	}
+	return parseCron(spec)
+}
+
+// parseCron reads a five-field cron expression
+func parseCron(spec string) (*Cron, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule '%s' is neither an interval like '15m' nor a cron expression with 5 fields", spec)
+	}
+
+	ranges := []struct {
+		name     string
+		min, max int
+	}{
+		{"minute", 0, 59},
+		{"hour", 0, 23},
+		{"day of month", 1, 31},
+		{"month", 1, 12},
+		{"day of week", 0, 7},
+	}
+
+	cron := &Cron{}
+	targets := []*uint64{&cron.minute, &cron.hour, &cron.dom, &cron.month, &cron.dow}
+	for i, field := range fields {
+		bits, err := parseField(field, ranges[i].min, ranges[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in schedule '%s': %w", ranges[i].name, spec, err)
+		}
+		*targets[i] = bits
+	}
+
+	// Sunday can be written as 0 or 7
+	if cron.dow&(1<<7) != 0 {
+		cron.dow |= 1
+	}
+	cron.domAny = fields[2] == "*"
+	cron.dowAny = fields[4] == "*"
+
+	return cron, nil
+}
+
+// parseField reads a comma-separated list of values, ranges and steps into a bit set
+func parseField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in '%s'", part)
+			}
+		}
+
+		var low, high int
+		switch {
+		case rangePart == "*":
+			low, high = min, max
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err1, err2 error
+			low, err1 = strconv.Atoi(bounds[0])
+			high, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range '%s'", rangePart)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value '%s'", rangePart)
+			}
+			low, high = value, value
+			// "5/15" means every 15 starting at 5
+			if step > 1 {
+				high = max
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("'%s' is outside %d-%d", rangePart, min, max)
+		}
+		for value := low; value <= high; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+// Next implements Schedule
+func (c *Cron) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+
+	// Every valid expression matches within a few years; stop looking after that
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the day of t matches the day-of-month and day-of-week fields
+func (c *Cron) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// NextMidnight returns the start of the day after t in loc, when the due-date buckets shift
+func NextMidnight(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc)
+}
+
+// NextRun returns when the next run is due: the next scheduled time in loc, or
+// the next midnight in loc if that comes first
+func NextRun(s Schedule, now time.Time, loc *time.Location) time.Time {
+	next := s.Next(now.In(loc))
+	if midnight := NextMidnight(now, loc); next.IsZero() || midnight.Before(next) {
+		return midnight
+	}
+	return next
+}
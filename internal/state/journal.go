@@ -0,0 +1,154 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+)
+
+// journalDir is the subdirectory of the state directory holding run journals
+const journalDir = "journal"
+
+// journalTimeFormat names journal files so they sort by the time of the run
+const journalTimeFormat = "20060102T150405.000000000Z"
+
+// ErrNoJournal is returned when there is no sorting run left to undo
+var ErrNoJournal = errors.New("no sorting run to undo")
+
+// Journal records the tasks a sorting run moved between sections
+type Journal struct {
+	RanAt    time.Time      `json:"ran_at"`
+	UserGID  string         `json:"user_gid"`
+	Entries  []JournalEntry `json:"entries"`
+	UndoneAt *time.Time     `json:"undone_at,omitempty"`
+
+	// path is the file the journal was loaded from or saved to
+	path string
+}
+
+// JournalEntry records a single task moved from one section to another
+type JournalEntry struct {
+	TaskGID         string    `json:"task_gid"`
+	TaskName        string    `json:"task_name"`
+	WorkspaceGID    string    `json:"workspace_gid"`
+	FromSectionGID  string    `json:"from_section_gid"`
+	FromSectionName string    `json:"from_section_name"`
+	ToSectionGID    string    `json:"to_section_gid"`
+	ToSectionName   string    `json:"to_section_name"`
+	MovedAt         time.Time `json:"moved_at"`
+}
+
+// NewJournal records the successful section changes of a run. Moves that
+// only reordered tasks within a section aren't recorded.
+func NewJournal(result *core.RunResult, ranAt time.Time) *Journal {
+	journal := &Journal{RanAt: ranAt.UTC(), UserGID: result.User.GID}
+	for _, workspace := range result.Workspaces {
+		for _, moveResult := range workspace.MoveResults {
+			move := moveResult.Move
+			if moveResult.Err != nil || move.Reorder {
+				continue
+			}
+			journal.Entries = append(journal.Entries, JournalEntry{
+				TaskGID:         move.Task.GID,
+				TaskName:        move.Task.Name,
+				WorkspaceGID:    workspace.Workspace.GID,
+				FromSectionGID:  move.Task.AssigneeSection.GID,
+				FromSectionName: move.Task.AssigneeSection.Name,
+				ToSectionGID:    move.SectionGID,
+				ToSectionName:   move.SectionName,
+				MovedAt:         moveResult.At.UTC(),
+			})
+		}
+	}
+	return journal
+}
+
+// Reverts returns the moves needed to undo the run, most recent first
+func (j *Journal) Reverts() []core.Revert {
+	reverts := make([]core.Revert, 0, len(j.Entries))
+	for i := len(j.Entries) - 1; i >= 0; i-- {
+		entry := j.Entries[i]
+		reverts = append(reverts, core.Revert{
+			TaskGID:  entry.TaskGID,
+			TaskName: entry.TaskName,
+			From:     asana.Section{GID: entry.FromSectionGID, Name: entry.FromSectionName},
+			To:       asana.Section{GID: entry.ToSectionGID, Name: entry.ToSectionName},
+		})
+	}
+	return reverts
+}
+
+// SaveJournal writes a new journal to the state directory
+func SaveJournal(stateDir string, journal *Journal) error {
+	dir := filepath.Join(stateDir, journalDir)
+	if err := ensureDir(dir); err != nil {
+		return err
+	}
+	journal.path = filepath.Join(dir, journal.RanAt.UTC().Format(journalTimeFormat)+".json")
+	return journal.save()
+}
+
+// MarkUndone records that the run has been undone, so the next undo goes back one run further
+func (j *Journal) MarkUndone(at time.Time) error {
+	undoneAt := at.UTC()
+	j.UndoneAt = &undoneAt
+	return j.save()
+}
+
+// save writes the journal to its file
+func (j *Journal) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode journal: %w", err)
+	}
+	if err := os.WriteFile(j.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// LatestJournal returns the journal of the most recent run that hasn't been undone yet
+func LatestJournal(stateDir string) (*Journal, error) {
+	dir := filepath.Join(stateDir, journalDir)
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoJournal
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal directory: %w", err)
+	}
+
+	var names []string
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".json") {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read journal: %w", err)
+		}
+		var journal Journal
+		if err := json.Unmarshal(data, &journal); err != nil {
+			return nil, fmt.Errorf("failed to parse journal %s: %w", name, err)
+		}
+		if journal.UndoneAt == nil {
+			journal.path = path
+			return &journal, nil
+		}
+	}
+
+	return nil, ErrNoJournal
+}
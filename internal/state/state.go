@@ -0,0 +1,31 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// appName names the sorter's directory within the user's state directory
+const appName = "asana-tasks-sorter"
+
+// DefaultDir returns the directory the sorter keeps its state in:
+// $XDG_STATE_HOME/asana-tasks-sorter, or ~/.local/state/asana-tasks-sorter
+func DefaultDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, appName)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), appName)
+	}
+	return filepath.Join(home, ".local", "state", appName)
+}
+
+// ensureDir creates a directory, readable only by the user, if it doesn't exist yet
+func ensureDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+)
+
+// syncFile is the file in the state directory holding the sync state of each My Tasks list
+const syncFile = "sync.json"
+
+// LoadSyncState returns the sync state saved for a My Tasks list, or the zero
+// state if there is none yet
+func LoadSyncState(stateDir, taskListGID string) (core.SyncState, error) {
+	states, err := loadSyncStates(stateDir)
+	if err != nil {
+		return core.SyncState{}, err
+	}
+	return states[taskListGID], nil
+}
+
+// SaveSyncState saves the sync state of a My Tasks list for the next run
+func SaveSyncState(stateDir, taskListGID string, syncState core.SyncState) error {
+	states, err := loadSyncStates(stateDir)
+	if err != nil {
+		return err
+	}
+	states[taskListGID] = syncState
+
+	if err := ensureDir(stateDir); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sync state: %w", err)
+	}
+	// Write a new file and rename it over the old one, so a crash can't leave a partial file
+	path := filepath.Join(stateDir, syncFile)
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return fmt.Errorf("failed to write sync state: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write sync state: %w", err)
+	}
+	return nil
+}
+
+// loadSyncStates reads the sync states of all My Tasks lists, keyed by task list GID
+func loadSyncStates(stateDir string) (map[string]core.SyncState, error) {
+	states := make(map[string]core.SyncState)
+	data, err := os.ReadFile(filepath.Join(stateDir, syncFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return states, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync state: %w", err)
+	}
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("failed to parse sync state: %w", err)
+	}
+	return states, nil
+}
@@ -2,8 +2,11 @@ package ui
 
 import (
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
 )
 
 // DisplayTasks prints out tasks organized by category with color formatting
@@ -29,6 +32,8 @@ func DisplayTasks(categorizedTasks map[asana.TaskCategory][]asana.Task,
 				taskColor = BrightRed
 			case asana.DueToday:
 				taskColor = BrightYellow
+			case asana.DueTomorrow:
+				taskColor = Yellow
 			case asana.DueThisWeek:
 				taskColor = BrightGreen
 			default:
@@ -69,6 +74,64 @@ func DisplayTasks(categorizedTasks map[asana.TaskCategory][]asana.Task,
 	}
 }
 
+// DisplayPlannedChanges prints the changes a dry run would make as a diff: the
+// sections that would be created, then the planned moves grouped by target section
+func DisplayPlannedChanges(result *core.RunResult) {
+	fmt.Printf("\n%s\n", Header("Planned changes:"))
+	fmt.Println(Bold + "================" + Reset)
+
+	for _, workspace := range result.Workspaces {
+		if workspace.Err != nil {
+			continue
+		}
+		if len(result.Workspaces) > 1 {
+			fmt.Printf("\n%s %s\n", Info("Workspace:"), Important(workspace.Workspace.Name))
+		}
+
+		if len(workspace.SectionsToCreate) == 0 && len(workspace.PlannedMoves) == 0 {
+			fmt.Println(Subtle("No changes"))
+			continue
+		}
+
+		for _, sectionName := range workspace.SectionsToCreate {
+			fmt.Printf("%s %s\n", Success("+ section"), SectionName(sectionName))
+		}
+
+		// Group the moves by target section, keeping the planned order
+		var targets []string
+		byTarget := make(map[string][]core.TaskMove)
+		for _, move := range workspace.PlannedMoves {
+			if _, seen := byTarget[move.SectionName]; !seen {
+				targets = append(targets, move.SectionName)
+			}
+			byTarget[move.SectionName] = append(byTarget[move.SectionName], move)
+		}
+
+		for _, target := range targets {
+			moves := byTarget[target]
+			fmt.Printf("\n%s %s\n", SectionName("## "+target), Subtle(fmt.Sprintf("(%d changes)", len(moves))))
+
+			width := 0
+			for _, move := range moves {
+				width = max(width, utf8.RuneCountInString(move.Task.Name))
+			}
+			for _, move := range moves {
+				padding := strings.Repeat(" ", width-utf8.RuneCountInString(move.Task.Name))
+				if move.Reorder {
+					fmt.Printf("%s %s%s  %s\n", Warning("~"), TaskName(move.Task.Name), padding, Subtle("reordered within section"))
+					continue
+				}
+				reason := ""
+				if move.Rule != "" {
+					reason = "  " + Subtle("(rule: "+move.Rule+")")
+				}
+				fmt.Printf("%s %s%s  %s %s %s%s\n", Success("+"), TaskName(move.Task.Name), padding,
+					SectionName(move.Task.AssigneeSection.Name), Subtle("→"), SectionName(move.SectionName), reason)
+			}
+		}
+	}
+}
+
 // FatalError prints an error message and exits the program
 func FatalError(format string, args ...interface{}) {
 	errorMsg := fmt.Sprintf(format, args...)
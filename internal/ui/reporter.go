@@ -0,0 +1,144 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+)
+
+// ConsoleReporter prints sorting progress as colored text
+type ConsoleReporter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewConsoleReporter creates a reporter that writes to out
+func NewConsoleReporter(out io.Writer) *ConsoleReporter {
+	return &ConsoleReporter{out: out}
+}
+
+// Report implements core.Reporter
+func (r *ConsoleReporter) Report(event core.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch event.Type {
+	case core.EventLoggedIn:
+		fmt.Fprintf(r.out, "%s %s\n", Info("Logged in as:"), Important(event.User.Name))
+
+	case core.EventTimeZoneChosen:
+		fmt.Fprintf(r.out, "%s %s\n", Info("Using time zone:"), Important(event.TimeZone))
+
+	case core.EventWorkspaceChosen:
+		fmt.Fprintf(r.out, "%s %s\n", Info("Using workspace:"), Important(event.Workspace.Name))
+
+	case core.EventSectionCreated:
+		fmt.Fprintf(r.out, "%s %s\n", Operation("Creating section:"), SectionName(event.Section))
+
+	case core.EventSectionMoved:
+		anchor, position := event.SectionReorder.After, "after"
+		if event.SectionReorder.Before.GID != "" {
+			anchor, position = event.SectionReorder.Before, "before"
+		}
+		fmt.Fprintf(r.out, "%s %s %s %s\n",
+			Operation("Moving section"),
+			SectionName(event.SectionReorder.Section.Name),
+			Subtle(position),
+			SectionName(anchor.Name))
+
+	case core.EventChangesFetched:
+		if event.Count < 0 {
+			fmt.Fprintln(r.out, Info("Sorting the whole list (first run today, or the last sync has expired)"))
+			return
+		}
+		fmt.Fprintf(r.out, "%s %s\n", Info("Tasks changed since the last run:"), Important(fmt.Sprint(event.Count)))
+
+	case core.EventFetchingTasks:
+		fmt.Fprintln(r.out, Header("Fetching all tasks from My Tasks list..."))
+
+	case core.EventTaskSkipped:
+		fmt.Fprintf(r.out, "%s %s %s %s%s\n",
+			Subtle("Skipping task in ignored section:"),
+			TaskName(event.Task.Name),
+			Subtle("(in section"),
+			SectionName(" '"+event.Section+"'"),
+			Subtle(")"))
+
+	case core.EventMovesStarted:
+		if event.Count == 0 {
+			fmt.Fprintln(r.out, "\n"+Info("No tasks need to be moved"))
+			return
+		}
+		fmt.Fprintln(r.out, "\n"+Header("Moving tasks to appropriate sections..."))
+
+	case core.EventBatchFailed:
+		fmt.Fprintf(r.out, "%s %v\n",
+			Subtle(fmt.Sprintf("Batch request for %d tasks failed, moving them one at a time:", event.Count)),
+			event.Err)
+
+	case core.EventMoveStarted:
+		if event.Move.Reorder {
+			fmt.Fprintf(r.out, "%s %s %s %s\n",
+				Operation("Reordering task"),
+				TaskName("'"+event.Move.Task.Name+"'"),
+				Subtle("in section:"),
+				SectionName(event.Move.SectionName))
+			return
+		}
+		fmt.Fprintf(r.out, "%s %s %s %s\n",
+			Operation("Moving task"),
+			TaskName("'"+event.Move.Task.Name+"'"),
+			Subtle("to section:"),
+			SectionName(event.Move.SectionName))
+
+	case core.EventMoveFailed:
+		fmt.Fprintf(r.out, "%s %s: %v\n",
+			Error("Error moving task"),
+			TaskName("'"+event.Move.Task.Name+"'"),
+			event.Err)
+
+	case core.EventMovesFinished:
+		if event.Err == nil && event.Count > 0 {
+			fmt.Fprintf(r.out, "\n%s\n", Success(fmt.Sprintf("Moved %d tasks to their appropriate sections", event.Count)))
+		}
+
+	case core.EventMoveDrifted:
+		fmt.Fprintf(r.out, "%s %s: %v\n",
+			Warning("Skipping planned move of task"),
+			TaskName("'"+event.Move.Task.Name+"'"),
+			event.Err)
+
+	case core.EventUndoSkipped:
+		fmt.Fprintf(r.out, "%s %s: %v\n",
+			Warning("Not moving back task"),
+			TaskName("'"+event.Task.Name+"'"),
+			event.Err)
+
+	case core.EventWorkspaceFailed:
+		fmt.Fprintf(r.out, "%s %v\n", Error("Error sorting workspace:"), event.Err)
+
+	case core.EventRunFinished:
+		r.printWorkspaceSummary(event.Result)
+	}
+}
+
+// printWorkspaceSummary prints the combined results of an all-workspaces run
+func (r *ConsoleReporter) printWorkspaceSummary(result *core.RunResult) {
+	fmt.Fprintf(r.out, "\n%s\n", Header("Workspace summary:"))
+
+	moveLabel := "moved"
+	if result.DryRun {
+		moveLabel = "would move"
+	}
+
+	for _, workspace := range result.Workspaces {
+		if workspace.Err != nil {
+			fmt.Fprintf(r.out, "  %s %s\n", Important(workspace.Workspace.Name), Error("failed: "+workspace.Err.Error()))
+			continue
+		}
+		fmt.Fprintf(r.out, "  %s %s\n", Important(workspace.Workspace.Name),
+			Subtle(fmt.Sprintf("%d tasks, %s %d", len(workspace.Tasks), moveLabel, len(workspace.PlannedMoves))))
+	}
+}
@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+)
+
+// Headers Asana uses to establish and authenticate webhook deliveries
+const (
+	HeaderSecret    = "X-Hook-Secret"
+	HeaderSignature = "X-Hook-Signature"
+)
+
+// maxBodySize bounds the size of an accepted event delivery
+const maxBodySize = 1 << 20
+
+// Payload is the body of an event delivery
+type Payload struct {
+	Events []asana.Event `json:"events"`
+}
+
+// Handler receives Asana webhook requests. The handshake carries the secret
+// that every later delivery is signed with, and is only accepted while the
+// handler expects one (see ExpectHandshake); deliveries with a missing or wrong
+// signature are rejected.
+type Handler struct {
+	mu       sync.Mutex
+	secret   []byte
+	armed    bool
+	onEvents func(events []asana.Event)
+}
+
+// NewHandler creates a handler that passes verified events to onEvents.
+// onEvents should return quickly, since Asana waits for the response.
+func NewHandler(onEvents func(events []asana.Event)) *Handler {
+	return &Handler{onEvents: onEvents}
+}
+
+// ExpectHandshake lets the handler accept a single handshake until the returned
+// function is called. Asana sends the handshake while the webhook is being
+// created, so arm the handler right before CreateWebhook and disarm it once the
+// call returns; a handshake at any other time could come from anyone.
+func (h *Handler) ExpectHandshake() (done func()) {
+	h.mu.Lock()
+	h.armed = true
+	h.mu.Unlock()
+	return func() {
+		h.mu.Lock()
+		h.armed = false
+		h.mu.Unlock()
+	}
+}
+
+// Sign returns the signature Asana sends for a body: the hex-encoded HMAC-SHA256 of it under the secret
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if secret := r.Header.Get(HeaderSecret); secret != "" {
+		h.handshake(w, secret)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "could not read body", http.StatusBadRequest)
+		return
+	}
+
+	h.mu.Lock()
+	secret := h.secret
+	h.mu.Unlock()
+	if secret == nil {
+		http.Error(w, "no handshake yet", http.StatusUnauthorized)
+		return
+	}
+
+	signature, err := hex.DecodeString(r.Header.Get(HeaderSignature))
+	expected, _ := hex.DecodeString(Sign(secret, body))
+	if err != nil || !hmac.Equal(signature, expected) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	// Asana sends heartbeats with no events
+	if len(payload.Events) > 0 {
+		h.onEvents(payload.Events)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handshake accepts the secret of a new webhook by echoing it back. Only one
+// handshake is accepted, while one is expected, so the secret can't be planted
+// or replaced by someone else.
+func (h *Handler) handshake(w http.ResponseWriter, secret string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.secret != nil {
+		http.Error(w, "handshake already done", http.StatusForbidden)
+		return
+	}
+	if !h.armed {
+		http.Error(w, "no webhook is being created", http.StatusForbidden)
+		return
+	}
+	h.armed = false
+	h.secret = []byte(secret)
+	w.Header().Set(HeaderSecret, secret)
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+)
+
+// StandIn plays Asana's side of a webhook against a local listener: it makes
+// the handshake and posts signed, synthetic events. It lets the listener be
+// tried out and tested without a public URL or a real subscription.
+type StandIn struct {
+	Target string
+	Client *http.Client
+	secret []byte
+}
+
+// NewStandIn creates a stand-in for Asana that delivers events to target
+func NewStandIn(target string) (*StandIn, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return &StandIn{
+		Target: target,
+		Client: http.DefaultClient,
+		secret: []byte(hex.EncodeToString(secret)),
+	}, nil
+}
+
+// Handshake sends the secret to the target and checks that it is echoed back
+func (s *StandIn) Handshake(ctx context.Context) error {
+	resp, err := s.post(ctx, nil, map[string]string{HeaderSecret: string(s.secret)})
+	if err != nil {
+		return err
+	}
+	if resp.Header.Get(HeaderSecret) != string(s.secret) {
+		return fmt.Errorf("webhook handshake failed: the target didn't echo the secret")
+	}
+	return nil
+}
+
+// Send delivers events to the target, signed with the handshake secret
+func (s *StandIn) Send(ctx context.Context, events ...asana.Event) error {
+	body, err := json.Marshal(Payload{Events: events})
+	if err != nil {
+		return fmt.Errorf("failed to encode events: %w", err)
+	}
+	_, err = s.post(ctx, body, map[string]string{HeaderSignature: Sign(s.secret, body)})
+	return err
+}
+
+// post sends a request to the target and fails unless it answers with a 2xx status
+func (s *StandIn) post(ctx context.Context, body []byte, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Target, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to post to webhook target: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, fmt.Errorf("webhook target answered %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// TaskChanged returns a synthetic event for a change to a field of a task
+func TaskChanged(taskGID, field string) asana.Event {
+	return asana.Event{
+		Action:    "changed",
+		Resource:  asana.EventResource{GID: taskGID, ResourceType: "task"},
+		Change:    &asana.EventChange{Field: field, Action: "changed"},
+		CreatedAt: time.Now().UTC(),
+	}
+}
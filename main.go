@@ -5,11 +5,15 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/auth"
 	"github.com/dackerman/asana-tasks-sorter/internal/config"
 	"github.com/dackerman/asana-tasks-sorter/internal/core"
+	"github.com/dackerman/asana-tasks-sorter/internal/output"
+	"github.com/dackerman/asana-tasks-sorter/internal/state"
 	"github.com/dackerman/asana-tasks-sorter/internal/ui"
 )
 
@@ -52,6 +56,29 @@ Examples:
   asana-tasks-sorter -timeout 60s
 `
 
+// commands are the subcommands accepted after the global flags; "" is a single sorting run
+var commands = map[string]bool{
+	"":        true,
+	"undo":    true,
+	"plan":    true,
+	"apply":   true,
+	"serve":   true,
+	"webhook": true,
+	"login":   true,
+	"auth":    true,
+	"config":  true,
+}
+
+// configFlags maps the flags that override a config setting to its key
+var configFlags = map[string]string{
+	"workspace":      "workspace",
+	"all-workspaces": "all_workspaces",
+	"timezone":       "timezone",
+	"order-sections": "order_sections",
+	"concurrency":    "concurrency",
+	"batch-size":     "batch_size",
+}
+
 func main() {
 	// Set custom usage text
 	flag.Usage = func() {
@@ -66,25 +93,48 @@ move the tasks to the appropriate sections in Asana.`
 
 		fmt.Println(ui.SectionTitle("Usage:"))
 		fmt.Println("  asana-tasks-sorter [flags]")
+		fmt.Println("  asana-tasks-sorter [flags] undo [--force]")
+		fmt.Println("  asana-tasks-sorter [flags] plan [--out plan.json]")
+		fmt.Println("  asana-tasks-sorter [flags] apply [--on-drift abort|skip] plan.json")
+		fmt.Println("  asana-tasks-sorter [flags] serve [--schedule 15m]")
+		fmt.Println("  asana-tasks-sorter [flags] webhook [--listen :8080] [--target-url https://...]")
+		fmt.Println("  asana-tasks-sorter [flags] login [--profile NAME] [--client-id ID] [--redirect-url http://localhost:8734/callback]")
+		fmt.Println("  asana-tasks-sorter [flags] auth add|list|remove [--profile NAME]")
+		fmt.Println("  asana-tasks-sorter [flags] config show")
+		fmt.Println("  asana-tasks-sorter config convert [--to json|yaml|toml] input [output]")
 		fmt.Println()
 
 		fmt.Println(ui.SectionTitle("Configuration:"))
-		configText := `  Create a JSON file with the following structure to customize section names:
+		configText := `  Create a JSON, YAML or TOML file (picked by its extension) to customize section names:
   {
     "overdue": "Overdue",
     "due_today": "Due today",
     "due_this_week": "Due within the next 7 days",
     "due_later": "Due later",
     "no_date": "Recently assigned",
-    "ignored_sections": ["Doing Now", "Waiting For"]
-  }`
+    "ignored_sections": ["Doing Now", "Waiting For"],
+    "workspace": "My Company"
+  }
+
+  Without --config, settings are merged from the built-in defaults, then
+  $ASANA_SORTER_CONFIG or $XDG_CONFIG_HOME/asana-tasks-sorter/config.*, then
+  ./.asana-sorter.*, then ASANA_SORTER_* environment variables, then flags.
+  Run 'config show' to see the result and where each setting came from.
+  Sorting needs a config file, or --config default for the built-in defaults.`
 		fmt.Println(configText)
 		fmt.Println()
 
 		fmt.Println(ui.SectionTitle("Authentication:"))
 		authText := `  Export your Asana personal access token as an environment variable:
   export ASANA_ACCESS_TOKEN="your_asana_personal_access_token"
-  Get your token from https://app.asana.com/0/developer-console`
+  Get your token from https://app.asana.com/0/developer-console
+
+  Or store credentials once, in the OS keyring or an encrypted file, under a profile:
+  asana-tasks-sorter auth add --profile work
+  asana-tasks-sorter login --profile personal --client-id your_app_client_id
+  asana-tasks-sorter --profile work --config default
+
+  ASANA_ACCESS_TOKEN takes precedence over stored profiles when it is set.`
 		fmt.Println(authText)
 		fmt.Println()
 
@@ -99,7 +149,30 @@ move the tasks to the appropriate sections in Asana.`
   asana-tasks-sorter --config default --dry-run
 
   # Set a custom timeout for API operations
-  asana-tasks-sorter --config default --timeout 60s`
+  asana-tasks-sorter --config default --timeout 60s
+
+  # Emit machine-readable results for other tools
+  asana-tasks-sorter --config default --dry-run --output json
+
+  # Sort a specific workspace, or every workspace in turn
+  asana-tasks-sorter --config default --workspace "My Company"
+  asana-tasks-sorter --config default --all-workspaces
+
+  # Move the tasks of the last run back where they came from
+  asana-tasks-sorter undo
+
+  # Save the planned moves for review, then make them later
+  asana-tasks-sorter --config default plan --out plan.json
+  asana-tasks-sorter apply --on-drift skip plan.json
+
+  # Keep sorting every 15 minutes, and right after midnight
+  asana-tasks-sorter --config default serve --schedule 15m
+
+  # Only sort the tasks that changed since the last run
+  asana-tasks-sorter --config default --incremental
+
+  # Sort tasks as soon as they change, using Asana webhooks
+  asana-tasks-sorter --config default webhook --target-url https://sorter.example.com/`
 		fmt.Println(examplesText)
 		fmt.Println()
 
@@ -108,46 +181,200 @@ move the tasks to the appropriate sections in Asana.`
 	}
 
 	// Parse command-line flags
-	configFile := flag.String("config", "", "Path to section configuration file or 'default' to use built-in defaults (required)")
+	configFile := flag.String("config", "", "Path to a JSON, YAML or TOML section configuration file to use instead of the discovered ones, or 'default' to use built-in defaults only")
 	dryRun := flag.Bool("dry-run", false, "Only display changes without moving tasks")
-	timeout := flag.Duration("timeout", 30*time.Second, "Timeout for API operations")
+	timeout := flag.Duration("timeout", 30*time.Second, "Timeout for API operations (per run with serve)")
+	maxAttempts := flag.Int("max-attempts", asana.DefaultMaxAttempts, "Maximum attempts per API request when rate limited or on server errors (1 disables retries)")
+	retryBaseDelay := flag.Duration("retry-base-delay", asana.DefaultBaseDelay, "Initial delay between retries, doubled after each attempt")
+	pageSize := flag.Int("page-size", asana.DefaultPageSize, "Number of items to request per page from the Asana API (1-100)")
+	// These override config settings, and are read through configFlags
+	flag.String("workspace", "", "Name or GID of the workspace to sort (overrides the config file)")
+	flag.Bool("all-workspaces", false, "Sort the My Tasks list in every workspace")
+	flag.String("timezone", "", "IANA time zone used to decide what 'today' is (default: your Asana profile's time zone)")
+	flag.Bool("order-sections", false, "Move the sorter's sections into the order declared in the config")
+	concurrency := flag.Int("concurrency", core.DefaultConcurrency, "Maximum number of tasks moved in parallel")
+	batchSize := flag.Int("batch-size", core.DefaultBatchSize, "Number of task moves sent in a single batch request (1-10, 1 disables batching)")
+	outputFormat := flag.String("output", output.FormatText, "Output format: text, json or ndjson")
+	incremental := flag.Bool("incremental", false, "Only sort tasks changed since the last incremental run, using the Asana events API")
+	stateDir := flag.String("state-dir", state.DefaultDir(), "Directory where run journals are kept for undo")
+	profile := flag.String("profile", auth.DefaultProfile, "Stored credentials to use when ASANA_ACCESS_TOKEN isn't set")
+	credentialsFile := flag.String("credentials", auth.DefaultStorePath(), "Encrypted file credentials are stored in when no OS keyring is available")
 	help := flag.Bool("help", false, "Show detailed help information")
 	flag.Parse()
 
-	// Show help if requested or if no config is provided
-	if *help || flag.NArg() > 0 || *configFile == "" {
+	// Show help if requested
+	if *help {
 		flag.Usage()
-		if !*help && *configFile == "" {
-			fmt.Println("\n" + ui.Error("Error: --config parameter is required"))
-			fmt.Println(ui.Info("Use --config default to use the built-in defaults"))
-		}
 		return
 	}
 
-	// Get access token from environment
-	accessToken := os.Getenv("ASANA_ACCESS_TOKEN")
-	if accessToken == "" {
-		fmt.Println("Error: ASANA_ACCESS_TOKEN environment variable is not set")
+	command := flag.Arg(0)
+	if command == "daemon" {
+		command = "serve"
+	}
+	if !commands[command] {
+		flag.Usage()
+		fmt.Println("\n" + ui.Error("Error: unknown command '"+command+"'"))
+		os.Exit(1)
+	}
+
+	if !output.ValidFormat(*outputFormat) {
+		fmt.Println(ui.Error("Error: --output must be one of text, json or ndjson"))
 		os.Exit(1)
 	}
+	machineOutput := *outputFormat != output.FormatText
+
+	if *pageSize < 1 || *pageSize > asana.MaxPageSize {
+		exitWithError(*outputFormat, nil, fmt.Errorf("--page-size must be between 1 and %d, got %d", asana.MaxPageSize, *pageSize))
+	}
+
+	// Settings given as flags override the config files and the environment
+	configOptions := config.Options{File: *configFile, Flags: make(map[string]interface{})}
+	flag.Visit(func(f *flag.Flag) {
+		if key, ok := configFlags[f.Name]; ok {
+			configOptions.Flags[key] = f.Value.(flag.Getter).Get()
+		}
+	})
+
+	if command == "config" {
+		runConfig(flag.Args()[1:], configOptions, *outputFormat)
+		return
+	}
+
+	if err := auth.ValidateProfile(*profile); err != nil {
+		exitWithError(*outputFormat, nil, err)
+	}
+	openStore := func() auth.Store { return auth.OpenStore(*credentialsFile) }
+	if command == "login" {
+		runLogin(flag.Args()[1:], openStore(), *profile, *timeout)
+		return
+	}
+	if command == "auth" {
+		runAuth(flag.Args()[1:], openStore(), *profile, *timeout)
+		return
+	}
+
+	// Get the access token from the environment, or from the stored profile
+	tokenSource, err := newTokenSource(openStore, *profile)
+	if err != nil {
+		exitWithError(*outputFormat, nil, err)
+	}
 
 	// Create Asana client
-	client := asana.NewClient(accessToken)
+	client := asana.NewClientWithTokenSource(tokenSource)
+	client.PageSize = *pageSize
+	client.Retry.MaxAttempts = *maxAttempts
+	client.Retry.BaseDelay = *retryBaseDelay
 
 	// Create a context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
 	defer cancel()
 
 	// Load configuration
-	conf := config.LoadConfiguration(*configFile)
+	effective, err := config.Load(configOptions)
+	if err != nil {
+		exitWithError(*outputFormat, nil, err)
+	}
+	conf := effective.Config
+	if conf.Concurrency == 0 {
+		conf.Concurrency = *concurrency
+	}
+	if conf.BatchSize == 0 {
+		conf.BatchSize = *batchSize
+	}
+
+	if command == "undo" {
+		runUndo(ctx, client, flag.Args()[1:], *stateDir, conf, *dryRun, *outputFormat)
+		return
+	}
+	if command == "apply" {
+		runApply(ctx, client, flag.Args()[1:], *stateDir, conf, *dryRun, *outputFormat)
+		return
+	}
+
+	// Sorting with the defaults has to be asked for, not fallen into
+	if len(effective.Files) == 0 && configOptions.File != "default" {
+		exitWithError(*outputFormat, nil, fmt.Errorf("no config file found: create %s or .asana-sorter.yaml, "+
+			"pass --config path/to/config.yaml, or pass --config default to use the built-in defaults",
+			filepath.Join(config.UserConfigDir(), "config.yaml")))
+	}
+
+	// Human-oriented progress output is suppressed in machine-readable modes
+	var reporter core.Reporter = ui.NewConsoleReporter(os.Stdout)
+	if machineOutput {
+		reporter = core.NopReporter{}
+	}
+
+	if command == "plan" {
+		runPlan(ctx, client, conf, flag.Args()[1:], *outputFormat, reporter)
+		return
+	}
+	if command == "serve" {
+		runServe(client, conf, flag.Args()[1:], *timeout, *stateDir, *incremental, *dryRun)
+		return
+	}
+	if command == "webhook" {
+		runWebhook(client, conf, flag.Args()[1:], *timeout, *stateDir, *dryRun)
+		return
+	}
 
 	// Run the main business logic
-	categorizedTasks, err := core.OrganizeTasks(ctx, client, conf, *dryRun)
+	var result *core.RunResult
+	if *incremental {
+		if *dryRun {
+			exitWithError(*outputFormat, nil, fmt.Errorf("--incremental can't be combined with --dry-run"))
+		}
+		result, err = runIncremental(ctx, client, conf, *stateDir, reporter)
+	} else {
+		result, err = core.OrganizeTasks(ctx, client, conf, *dryRun, reporter)
+	}
+
+	// Record the moves that were made, even if the run failed part way, so they can be undone
+	if !*dryRun {
+		if journalErr := recordJournal(*stateDir, result); journalErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", journalErr)
+		}
+	}
+
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		exitWithError(*outputFormat, result, err)
+	}
+
+	if machineOutput {
+		writeMachineOutput(*outputFormat, result, nil)
+		return
+	}
+
+	// Show what a dry run would change, then the tasks in a formatted way
+	if *dryRun {
+		ui.DisplayPlannedChanges(result)
+	}
+	ui.DisplayTasks(result.Categorized(), core.GetCategoryToSectionMap(conf), *dryRun)
+}
+
+// writeMachineOutput writes the run result to stdout in the given machine-readable format
+func writeMachineOutput(format string, result *core.RunResult, runErr error) {
+	var err error
+	if format == output.FormatNDJSON {
+		err = output.WriteNDJSON(os.Stdout, result, runErr)
+	} else {
+		err = output.WriteJSON(os.Stdout, result, runErr)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+	}
+}
+
+// exitWithError reports a fatal error in the requested output format and exits
+func exitWithError(format string, result *core.RunResult, err error) {
+	if format != output.FormatText {
+		writeMachineOutput(format, result, err)
 		os.Exit(1)
 	}
 
-	// Display the tasks in a formatted way
-	ui.DisplayTasks(categorizedTasks, core.GetCategoryToSectionMap(conf), *dryRun)
+	fmt.Printf("Error: %v\n", err)
+	if hint := core.ErrorHint(err); hint != "" {
+		fmt.Println(ui.Info(hint))
+	}
+	os.Exit(1)
 }
@@ -1,15 +1,22 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/dackerman/asana-tasks-sorter/internal/asana"
 	"github.com/dackerman/asana-tasks-sorter/internal/core"
+	"github.com/dackerman/asana-tasks-sorter/internal/output"
+	"github.com/dackerman/asana-tasks-sorter/internal/plan"
 	testing_util "github.com/dackerman/asana-tasks-sorter/internal/testing"
+	"github.com/dackerman/asana-tasks-sorter/internal/ui"
 )
 
 // TestMainWithSnapshots runs through the main workflow using recorded API responses
@@ -44,8 +51,10 @@ func TestMainWithSnapshots(t *testing.T) {
 		BaseURL: asana.BaseURL,
 	}
 
-	// Use default configuration and dry run mode for tests
+	// Use default configuration and dry run mode for tests. Each move is made
+	// with its own addTask request; TestBatchedMovesWithSnapshots covers batching.
 	config := core.DefaultSectionConfig()
+	config.BatchSize = 1
 	dryRun := false
 
 	// Create context with timeout
@@ -53,8 +62,200 @@ func TestMainWithSnapshots(t *testing.T) {
 	defer cancel()
 
 	// Run the core business logic
-	_, err := core.OrganizeTasks(ctx, client, config, dryRun)
+	_, err := core.OrganizeTasks(ctx, client, config, dryRun, ui.NewConsoleReporter(os.Stdout))
 	if err != nil {
 		t.Fatalf("Error in OrganizeTasks: %v", err)
 	}
 }
+
+// TestBatchedMovesWithSnapshots runs the main workflow with the default batch
+// size. A rule sends one of the moving tasks to another section, so the two
+// moves aren't placed next to each other and go out in one batch request.
+func TestBatchedMovesWithSnapshots(t *testing.T) {
+	client := &asana.Client{
+		Client:  &http.Client{Transport: testing_util.NewSnapshotRoundTripper(t, "snapshots", "replay")},
+		Token:   "dummy_token",
+		BaseURL: asana.BaseURL,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	config := core.DefaultSectionConfig()
+	config.Rules = []core.Rule{{Section: "Due today", NameMatches: "^Take out trash"}}
+	result, err := core.OrganizeTasks(ctx, client, config, false, core.NopReporter{})
+	if err != nil {
+		t.Fatalf("Error in OrganizeTasks: %v", err)
+	}
+
+	moved := make(map[string]string)
+	for _, moveResult := range result.Workspaces[0].MoveResults {
+		if moveResult.Err != nil {
+			t.Errorf("Move of '%s' failed: %v", moveResult.Move.Task.Name, moveResult.Err)
+		}
+		moved[moveResult.Move.Task.Name] = moveResult.Move.SectionName
+	}
+	expected := map[string]string{
+		"Write out 2024 priorities + plan": "Overdue",
+		"Take out trash & recycling":       "Due today",
+	}
+	if !reflect.DeepEqual(moved, expected) {
+		t.Errorf("Expected %v to be moved, got %v", expected, moved)
+	}
+}
+
+// TestJSONOutputWithSnapshots checks the machine-readable output of a dry run
+func TestJSONOutputWithSnapshots(t *testing.T) {
+	client := &asana.Client{
+		Client:  &http.Client{Transport: testing_util.NewSnapshotRoundTripper(t, "snapshots", "replay")},
+		Token:   "dummy_token",
+		BaseURL: asana.BaseURL,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := core.OrganizeTasks(ctx, client, core.DefaultSectionConfig(), true, core.NopReporter{})
+	if err != nil {
+		t.Fatalf("Error in OrganizeTasks: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := output.WriteJSON(&buf, result, nil); err != nil {
+		t.Fatalf("Error writing JSON: %v", err)
+	}
+
+	var doc output.Document
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	if doc.User == nil || doc.User.Name != "David Ackerman" {
+		t.Errorf("Expected user David Ackerman, got %+v", doc.User)
+	}
+	if !doc.DryRun {
+		t.Error("Expected dry_run to be true")
+	}
+	if len(doc.Workspaces) != 1 || doc.Workspaces[0].Name != "Ackerman Household" {
+		t.Fatalf("Expected the Ackerman Household workspace, got %+v", doc.Workspaces)
+	}
+
+	workspace := doc.Workspaces[0]
+	if len(workspace.Tasks) == 0 {
+		t.Error("Expected tasks in the output")
+	}
+	if len(workspace.ExecutedMoves) != 0 {
+		t.Errorf("Expected no executed moves in a dry run, got %d", len(workspace.ExecutedMoves))
+	}
+	for _, move := range workspace.PlannedMoves {
+		if move.TaskGID == "" || move.ToSection == "" || move.ToSectionGID == "" {
+			t.Errorf("Planned move is missing fields: %+v", move)
+		}
+	}
+
+	// The NDJSON form ends with a summary record
+	buf.Reset()
+	if err := output.WriteNDJSON(&buf, result, nil); err != nil {
+		t.Fatalf("Error writing NDJSON: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var last map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil {
+		t.Fatalf("Last NDJSON line is not valid JSON: %v", err)
+	}
+	if last["type"] != "run" {
+		t.Errorf("Expected the last record to be the run summary, got %v", last["type"])
+	}
+	if len(lines) != len(workspace.Tasks)+len(workspace.PlannedMoves)+1 {
+		t.Errorf("Expected one line per task and planned move plus a summary, got %d lines", len(lines))
+	}
+}
+
+// TestReporterEventsWithSnapshots checks the events sent during a dry run
+func TestReporterEventsWithSnapshots(t *testing.T) {
+	client := &asana.Client{
+		Client:  &http.Client{Transport: testing_util.NewSnapshotRoundTripper(t, "snapshots", "replay")},
+		Token:   "dummy_token",
+		BaseURL: asana.BaseURL,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	reporter := &core.RecordingReporter{}
+	result, err := core.OrganizeTasks(ctx, client, core.DefaultSectionConfig(), true, reporter)
+	if err != nil {
+		t.Fatalf("Error in OrganizeTasks: %v", err)
+	}
+
+	events := reporter.Events()
+	if len(events) < 3 {
+		t.Fatalf("Expected several events, got %d", len(events))
+	}
+	if events[0].Type != core.EventLoggedIn || events[0].User.Name != "David Ackerman" {
+		t.Errorf("Expected the first event to be logged_in for David Ackerman, got %+v", events[0])
+	}
+
+	chosen := reporter.EventsOfType(core.EventWorkspaceChosen)
+	if len(chosen) != 1 || chosen[0].Workspace.Name != "Ackerman Household" {
+		t.Errorf("Expected one workspace_chosen event for Ackerman Household, got %+v", chosen)
+	}
+
+	planned := reporter.EventsOfType(core.EventMovePlanned)
+	if len(planned) != len(result.Workspaces[0].PlannedMoves) {
+		t.Errorf("Expected %d move_planned events, got %d", len(result.Workspaces[0].PlannedMoves), len(planned))
+	}
+
+	// A dry run never creates sections or moves tasks
+	for _, eventType := range []core.EventType{core.EventSectionCreated, core.EventMoveStarted, core.EventMoveSucceeded, core.EventMoveFailed} {
+		if got := reporter.EventsOfType(eventType); len(got) != 0 {
+			t.Errorf("Expected no %s events in a dry run, got %d", eventType, len(got))
+		}
+	}
+}
+
+// TestDryRunPlansMovesIntoNewSections checks that a dry run lists the sections
+// it would create and the moves into them, which a saved plan keeps for apply
+func TestDryRunPlansMovesIntoNewSections(t *testing.T) {
+	client := &asana.Client{
+		Client:  &http.Client{Transport: testing_util.NewSnapshotRoundTripper(t, "snapshots", "replay")},
+		Token:   "dummy_token",
+		BaseURL: asana.BaseURL,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	config := core.DefaultSectionConfig()
+	config.Overdue = "Past due (new)"
+	result, err := core.OrganizeTasks(ctx, client, config, true, core.NopReporter{})
+	if err != nil {
+		t.Fatalf("Error in OrganizeTasks: %v", err)
+	}
+
+	workspace := result.Workspaces[0]
+	if len(workspace.SectionsToCreate) != 1 || workspace.SectionsToCreate[0] != "Past due (new)" {
+		t.Fatalf("Expected 'Past due (new)' to be created, got %v", workspace.SectionsToCreate)
+	}
+
+	intoNew := 0
+	for _, move := range workspace.PlannedMoves {
+		if move.SectionName == "Past due (new)" {
+			if move.SectionGID != "" {
+				t.Errorf("Expected no section GID for a section that doesn't exist, got %+v", move)
+			}
+			intoNew++
+		}
+	}
+	if intoNew == 0 {
+		t.Fatalf("Expected moves into the new section, got %+v", workspace.PlannedMoves)
+	}
+
+	saved := plan.New(result, time.Now())
+	if got := saved.MoveCount(); got != len(workspace.PlannedMoves) {
+		t.Errorf("Expected the plan to keep all %d moves, got %d moves", len(workspace.PlannedMoves), got)
+	}
+	if toCreate := saved.Workspaces[0].SectionsToCreate; len(toCreate) != 1 || toCreate[0] != "Past due (new)" {
+		t.Errorf("Expected the plan to create 'Past due (new)', got %v", toCreate)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+)
+
+// orderTestTask creates a task in the given section, due on the given day of April 2023
+func orderTestTask(gid, section string, dueDay int) asana.Task {
+	task := asana.Task{
+		GID:             gid,
+		Name:            gid,
+		AssigneeSection: asana.AssigneeSection{GID: "gid_" + section, Name: section},
+	}
+	if dueDay > 0 {
+		task.DueOn = asana.Date(time.Date(2023, 4, dueDay, 0, 0, 0, 0, time.UTC))
+	}
+	return task
+}
+
+// applyMoves replays moves against a model of section contents, as Asana would
+func applyMoves(t *testing.T, sections map[string][]string, moves []core.TaskMove) {
+	t.Helper()
+	for _, move := range moves {
+		for name, gids := range sections {
+			sections[name] = slices.DeleteFunc(gids, func(gid string) bool { return gid == move.Task.GID })
+		}
+		gids := sections[move.SectionName]
+		index := 0
+		switch {
+		case move.InsertAfter != "":
+			index = slices.Index(gids, move.InsertAfter) + 1
+			if index == 0 {
+				t.Fatalf("Move of %s anchors on %s, which isn't in %s", move.Task.GID, move.InsertAfter, move.SectionName)
+			}
+		case move.InsertBefore != "":
+			index = slices.Index(gids, move.InsertBefore)
+			if index < 0 {
+				t.Fatalf("Move of %s anchors on %s, which isn't in %s", move.Task.GID, move.InsertBefore, move.SectionName)
+			}
+		}
+		sections[move.SectionName] = slices.Insert(gids, index, move.Task.GID)
+	}
+}
+
+func TestOrderTaskMoves(t *testing.T) {
+	now := time.Date(2023, 4, 15, 12, 0, 0, 0, time.UTC)
+	sectionNameToGID := map[string]string{
+		"Overdue":                    "gid_Overdue",
+		"Due today":                  "gid_Due today",
+		"Due within the next 7 days": "gid_Due within the next 7 days",
+		"Due later":                  "gid_Due later",
+		"Recently assigned":          "gid_Recently assigned",
+	}
+
+	// Listed in their current order
+	tasks := []asana.Task{
+		orderTestTask("later_30", "Due later", 30),
+		orderTestTask("later_25", "Due later", 25),
+		orderTestTask("later_28", "Due later", 28),
+		orderTestTask("week_20", "Due later", 20),
+		orderTestTask("later_24", "Recently assigned", 24),
+		orderTestTask("later_none_1", "Due later", 0),
+		orderTestTask("later_29", "Recently assigned", 29),
+	}
+	sections := func() map[string][]string {
+		contents := make(map[string][]string)
+		for _, task := range tasks {
+			contents[task.AssigneeSection.Name] = append(contents[task.AssigneeSection.Name], task.GID)
+		}
+		return contents
+	}
+
+	testCases := []struct {
+		name          string
+		order         string
+		expectedLater []string
+	}{
+		{
+			name:          "Keep existing adds moved tasks at the bottom",
+			order:         core.TaskOrderKeepExisting,
+			expectedLater: []string{"later_30", "later_25", "later_28", "*", "*"},
+		},
+		{
+			name:          "Due date sorts the whole section",
+			order:         core.TaskOrderDueDate,
+			expectedLater: []string{"later_24", "later_25", "later_28", "later_29", "later_30"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := core.DefaultSectionConfig()
+			config.TaskOrder = tc.order
+
+			moves := core.CalculateTaskMoves(tasks, config, sectionNameToGID, map[string]bool{}, now)
+			ordered := core.OrderTaskMoves(tasks, moves, config, map[string]bool{}, now)
+
+			contents := sections()
+			applyMoves(t, contents, ordered)
+
+			// Tasks without a due date moving out of the section don't count
+			got := slices.DeleteFunc(contents["Due later"], func(gid string) bool { return gid == "later_none_1" })
+
+			// Tasks moved in with keep_existing may land in any order below the existing ones
+			for i, gid := range tc.expectedLater {
+				if gid == "*" && i < len(got) && (got[i] == "later_24" || got[i] == "later_29") {
+					got[i] = "*"
+				}
+			}
+			if !reflect.DeepEqual(got, tc.expectedLater) {
+				t.Errorf("Expected 'Due later' to be %v, got %v", tc.expectedLater, got)
+			}
+		})
+	}
+}
+
+func TestOrderTaskMovesLeavesSortedSectionsAlone(t *testing.T) {
+	now := time.Date(2023, 4, 15, 12, 0, 0, 0, time.UTC)
+	tasks := []asana.Task{
+		orderTestTask("a", "Due later", 25),
+		orderTestTask("b", "Due later", 28),
+		orderTestTask("c", "Due later", 30),
+	}
+	config := core.DefaultSectionConfig()
+	config.TaskOrder = core.TaskOrderDueDate
+
+	if moves := core.OrderTaskMoves(tasks, nil, config, map[string]bool{}, now); len(moves) != 0 {
+		t.Errorf("Expected no moves for an already sorted section, got %+v", moves)
+	}
+
+	// Moving one task to the top takes a single move
+	tasks[2].DueOn = asana.Date(time.Date(2023, 4, 24, 0, 0, 0, 0, time.UTC))
+	moves := core.OrderTaskMoves(tasks, nil, config, map[string]bool{}, now)
+	if len(moves) != 1 || moves[0].Task.GID != "c" || moves[0].InsertBefore != "a" || !moves[0].Reorder {
+		t.Errorf("Expected 'c' to be moved before 'a', got %+v", moves)
+	}
+}
+
+func TestPriorityTaskOrder(t *testing.T) {
+	now := time.Date(2023, 4, 15, 12, 0, 0, 0, time.UTC)
+	withPriority := func(task asana.Task, priority string) asana.Task {
+		task.CustomFields = []asana.CustomField{{Name: "Priority", DisplayValue: priority}}
+		return task
+	}
+	tasks := []asana.Task{
+		withPriority(orderTestTask("low", "Due later", 25), "Low"),
+		orderTestTask("unset", "Due later", 24),
+		withPriority(orderTestTask("high_late", "Due later", 30), "High"),
+		withPriority(orderTestTask("high_soon", "Due later", 26), "high"),
+	}
+	config := core.DefaultSectionConfig()
+	config.TaskOrder = core.TaskOrderPriority
+
+	contents := map[string][]string{"Due later": {"low", "unset", "high_late", "high_soon"}}
+	applyMoves(t, contents, core.OrderTaskMoves(tasks, nil, config, map[string]bool{}, now))
+
+	expected := []string{"high_soon", "high_late", "low", "unset"}
+	if !reflect.DeepEqual(contents["Due later"], expected) {
+		t.Errorf("Expected %v, got %v", expected, contents["Due later"])
+	}
+}
+
+// orderedMoveAPI records the order in which tasks were moved
+type orderedMoveAPI struct {
+	asana.API
+
+	mu    sync.Mutex
+	moves []asana.SectionMove
+}
+
+func (f *orderedMoveAPI) MoveTaskToSection(ctx context.Context, move asana.SectionMove) error {
+	// Let independent moves overtake each other
+	if move.InsertAfter == "" && move.InsertBefore == "" {
+		time.Sleep(5 * time.Millisecond)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.moves = append(f.moves, move)
+	return nil
+}
+
+func TestExecuteTaskMovesKeepsChainsInOrder(t *testing.T) {
+	move := func(gid, after string) core.TaskMove {
+		return core.TaskMove{Task: asana.Task{GID: gid}, SectionGID: "section_1", SectionName: "Section 1", InsertAfter: after}
+	}
+	moves := []core.TaskMove{
+		move("a", ""),
+		move("b", "a"),
+		move("x", ""),
+		move("c", "b"),
+		move("y", "stable"),
+	}
+
+	client := &orderedMoveAPI{}
+	results, err := core.ExecuteTaskMoves(context.Background(), client, moves, 4, 1, core.NopReporter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != len(moves) {
+		t.Fatalf("Expected %d results, got %d", len(moves), len(results))
+	}
+
+	position := make(map[string]int)
+	for i, move := range client.moves {
+		position[move.TaskGID] = i
+	}
+	if !(position["a"] < position["b"] && position["b"] < position["c"]) {
+		t.Errorf("Expected a, b and c to be moved in order, got %+v", client.moves)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+)
+
+// TestListEndpointsFollowPagination checks that list methods keep requesting
+// pages until Asana stops returning a next_page offset
+func TestListEndpointsFollowPagination(t *testing.T) {
+	const totalTasks = 7
+	const pageSize = 3
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if got := r.URL.Query().Get(asana.QueryLimit); got != strconv.Itoa(pageSize) {
+			t.Errorf("Expected limit=%d, got %q", pageSize, got)
+		}
+		if got := r.URL.Query().Get(asana.QueryCompletedSince); got != "now" {
+			t.Errorf("Expected completed_since=now to be kept on every page, got %q", got)
+		}
+
+		start := 0
+		if offset := r.URL.Query().Get(asana.QueryOffset); offset != "" {
+			start, _ = strconv.Atoi(offset)
+		}
+		end := min(start+pageSize, totalTasks)
+
+		var tasks []map[string]string
+		for i := start; i < end; i++ {
+			tasks = append(tasks, map[string]string{"gid": strconv.Itoa(i), "name": fmt.Sprintf("Task %d", i)})
+		}
+
+		response := map[string]interface{}{"data": tasks, "next_page": nil}
+		if end < totalTasks {
+			response["next_page"] = asana.NextPage{Offset: strconv.Itoa(end)}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client := &asana.Client{
+		Client:   server.Client(),
+		Token:    "dummy_token",
+		BaseURL:  server.URL,
+		PageSize: pageSize,
+	}
+
+	tasks, err := client.GetTasksFromUserTaskList(context.Background(), "list_1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(tasks) != totalTasks {
+		t.Fatalf("Expected %d tasks, got %d", totalTasks, len(tasks))
+	}
+	for i, task := range tasks {
+		if task.GID != strconv.Itoa(i) {
+			t.Errorf("Task %d: expected GID %d, got %s", i, i, task.GID)
+		}
+	}
+
+	if requests != 3 {
+		t.Errorf("Expected 3 page requests, got %d", requests)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+	"github.com/dackerman/asana-tasks-sorter/internal/output"
+	"github.com/dackerman/asana-tasks-sorter/internal/plan"
+	"github.com/dackerman/asana-tasks-sorter/internal/ui"
+)
+
+// Ways apply handles planned moves that no longer match Asana
+const (
+	onDriftAbort = "abort"
+	onDriftSkip  = "skip"
+)
+
+// runPlan works out the moves a sorting run would make and saves them to a plan
+// file. In machine-readable formats the dry run's result is written instead of
+// the summary.
+func runPlan(ctx context.Context, client asana.API, conf core.SectionConfig, args []string, format string,
+	reporter core.Reporter) {
+
+	planFlags := flag.NewFlagSet("plan", flag.ExitOnError)
+	out := planFlags.String("out", "plan.json", "File to write the plan to")
+	planFlags.Parse(args)
+
+	result, err := core.OrganizeTasks(ctx, client, conf, true, reporter)
+	if err != nil {
+		exitWithError(format, result, err)
+	}
+
+	p := plan.New(result, time.Now())
+	if err := plan.Save(*out, p); err != nil {
+		exitWithError(format, nil, err)
+	}
+	if format != output.FormatText {
+		writeMachineOutput(format, result, nil)
+		return
+	}
+	fmt.Printf("\n%s %s %s\n", ui.Success(fmt.Sprintf("Saved %d planned moves to", p.MoveCount())), ui.Important(*out),
+		ui.Info("(run 'apply "+*out+"' to make them)"))
+	for _, workspace := range p.Workspaces {
+		for _, sectionName := range workspace.SectionsToCreate {
+			fmt.Printf("%s %s\n", ui.Info("Applying will create the section"), ui.Important(sectionName))
+		}
+	}
+}
+
+// runApply makes the moves saved in a plan file, after checking them against the current state of Asana.
+// With dryRun it stops after the check and shows the moves it would make.
+func runApply(ctx context.Context, client asana.API, args []string, stateDir string, conf core.SectionConfig,
+	dryRun bool, format string) {
+
+	applyFlags := flag.NewFlagSet("apply", flag.ExitOnError)
+	onDrift := applyFlags.String("on-drift", onDriftAbort, "What to do when planned moves no longer match Asana: abort or skip")
+	applyFlags.Parse(args)
+
+	if applyFlags.NArg() != 1 {
+		exitWithError(format, nil, fmt.Errorf("apply needs exactly one plan file, e.g. 'apply plan.json'"))
+	}
+	if *onDrift != onDriftAbort && *onDrift != onDriftSkip {
+		exitWithError(format, nil, fmt.Errorf("--on-drift must be abort or skip"))
+	}
+
+	p, err := plan.Load(applyFlags.Arg(0))
+	if err != nil {
+		exitWithError(format, nil, err)
+	}
+
+	user, err := client.GetCurrentUser(ctx)
+	if err != nil {
+		exitWithError(format, nil, fmt.Errorf("error getting current user: %w", err))
+	}
+	if user.GID != p.UserGID {
+		exitWithError(format, nil, fmt.Errorf("plan was made for %s, but you are logged in as %s", p.UserName, user.Name))
+	}
+
+	// Human-oriented progress output is suppressed in machine-readable modes
+	var reporter core.Reporter = core.NopReporter{}
+	if format == output.FormatText {
+		reporter = ui.NewConsoleReporter(os.Stdout)
+		fmt.Printf("%s %s\n", ui.Info("Applying plan from"), ui.Important(p.CreatedAt.Local().Format("2006-01-02 15:04:05")))
+	}
+
+	// Check every workspace before moving anything, so an abort leaves Asana untouched
+	validMoves := make([][]core.TaskMove, len(p.Workspaces))
+	var allDrifts []core.Drift
+	for i, workspace := range p.Workspaces {
+		valid, drifts, err := core.DetectDrift(ctx, client, workspace.TaskListGID, workspace.Sections, workspace.TaskMoves())
+		if err != nil {
+			exitWithError(format, nil, fmt.Errorf("error checking plan for workspace '%s': %w", workspace.Name, err))
+		}
+		validMoves[i] = valid
+		allDrifts = append(allDrifts, drifts...)
+	}
+
+	if len(allDrifts) > 0 && *onDrift == onDriftAbort {
+		if format == output.FormatText {
+			fmt.Println(ui.Info("Nothing was moved. Make a new plan, or apply with --on-drift skip to make the remaining moves"))
+		}
+		exitWithError(format, nil, &core.DriftError{Drifts: allDrifts})
+	}
+	for i := range allDrifts {
+		reporter.Report(core.Event{Type: core.EventMoveDrifted, Move: &allDrifts[i].Move, Err: allDrifts[i].Reason})
+	}
+
+	if dryRun {
+		result := &core.RunResult{DryRun: true, User: *user}
+		for i, workspace := range p.Workspaces {
+			result.Workspaces = append(result.Workspaces, core.WorkspaceResult{
+				Workspace:        asana.Workspace{GID: workspace.GID, Name: workspace.Name},
+				TaskListGID:      workspace.TaskListGID,
+				Sections:         workspace.Sections,
+				SectionsToCreate: workspace.SectionsToCreate,
+				PlannedMoves:     validMoves[i],
+			})
+		}
+		if format != output.FormatText {
+			writeMachineOutput(format, result, nil)
+			return
+		}
+		ui.DisplayPlannedChanges(result)
+		return
+	}
+
+	result := &core.RunResult{User: *user}
+	var runErr error
+	for i, workspace := range p.Workspaces {
+		workspaceResult := core.WorkspaceResult{
+			Workspace:    asana.Workspace{GID: workspace.GID, Name: workspace.Name},
+			TaskListGID:  workspace.TaskListGID,
+			Sections:     workspace.Sections,
+			PlannedMoves: validMoves[i],
+		}
+		runErr = core.CreatePlannedSections(ctx, client, workspace.TaskListGID, workspace.SectionsToCreate, validMoves[i], reporter)
+		if runErr != nil {
+			result.Workspaces = append(result.Workspaces, workspaceResult)
+			break
+		}
+		workspaceResult.MoveResults, runErr = core.ExecuteTaskMoves(ctx, client, validMoves[i], conf.Concurrency, conf.BatchSize, reporter)
+		result.Workspaces = append(result.Workspaces, workspaceResult)
+		if runErr != nil {
+			break
+		}
+	}
+
+	// Record the moves that were made so the apply can be undone like a sorting run
+	if journalErr := recordJournal(stateDir, result); journalErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", journalErr)
+	}
+	if runErr != nil {
+		exitWithError(format, result, fmt.Errorf("error executing task moves: %w", runErr))
+	}
+	if format != output.FormatText {
+		writeMachineOutput(format, result, nil)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+	"github.com/dackerman/asana-tasks-sorter/internal/plan"
+)
+
+// fakeTaskListAPI is an asana.API serving a fixed task list
+type fakeTaskListAPI struct {
+	asana.API
+
+	sections []asana.Section
+	tasks    []asana.Task
+	created  []string
+}
+
+func (f *fakeTaskListAPI) GetSectionsForProject(ctx context.Context, projectGID string) ([]asana.Section, error) {
+	return f.sections, nil
+}
+
+func (f *fakeTaskListAPI) GetTasksFromUserTaskList(ctx context.Context, userTaskListGID string) ([]asana.Task, error) {
+	return f.tasks, nil
+}
+
+func (f *fakeTaskListAPI) CreateSection(ctx context.Context, projectGID, name string) (*asana.Section, error) {
+	f.created = append(f.created, name)
+	return &asana.Section{GID: "new_" + name, Name: name}, nil
+}
+
+func TestPlanRoundTrip(t *testing.T) {
+	sections := []asana.Section{{GID: "inbox", Name: "Inbox"}, {GID: "overdue", Name: "Overdue"}}
+	move := core.TaskMove{
+		Task:         asana.Task{GID: "task_1", Name: "Task 1", AssigneeSection: asana.AssigneeSection{GID: "inbox", Name: "Inbox"}},
+		SectionGID:   "overdue",
+		SectionName:  "Overdue",
+		Rule:         "overdue",
+		InsertBefore: "task_2",
+	}
+	result := &core.RunResult{
+		User: asana.User{GID: "user_1", Name: "User"},
+		Workspaces: []core.WorkspaceResult{
+			{
+				Workspace:    asana.Workspace{GID: "workspace_1", Name: "Workspace"},
+				TaskListGID:  "list_1",
+				Sections:     sections,
+				PlannedMoves: []core.TaskMove{move},
+			},
+			{Workspace: asana.Workspace{GID: "workspace_2"}, Err: errors.New("boom")},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := plan.Save(path, plan.New(result, time.Now())); err != nil {
+		t.Fatalf("Error saving plan: %v", err)
+	}
+	loaded, err := plan.Load(path)
+	if err != nil {
+		t.Fatalf("Error loading plan: %v", err)
+	}
+
+	if loaded.UserGID != "user_1" || len(loaded.Workspaces) != 1 {
+		t.Fatalf("Expected one workspace for user_1, got %+v", loaded)
+	}
+	workspace := loaded.Workspaces[0]
+	if workspace.TaskListGID != "list_1" || len(workspace.Sections) != 2 {
+		t.Errorf("Expected the task list and its sections to be saved, got %+v", workspace)
+	}
+	moves := workspace.TaskMoves()
+	if len(moves) != 1 || moves[0].Task.GID != move.Task.GID || moves[0].Task.AssigneeSection != move.Task.AssigneeSection ||
+		moves[0].SectionGID != move.SectionGID || moves[0].InsertBefore != move.InsertBefore || moves[0].Rule != move.Rule {
+		t.Errorf("Expected %+v after the round trip, got %+v", move, moves)
+	}
+}
+
+func TestCreatePlannedSections(t *testing.T) {
+	// 'Someday' was created by hand after the plan was made
+	client := &fakeTaskListAPI{sections: []asana.Section{{GID: "inbox", Name: "Inbox"}, {GID: "someday", Name: "Someday"}}}
+	moves := []core.TaskMove{
+		{Task: asana.Task{GID: "task_1"}, SectionGID: "inbox", SectionName: "Inbox"},
+		{Task: asana.Task{GID: "task_2"}, SectionName: "Overdue"},
+		{Task: asana.Task{GID: "task_3"}, SectionName: "Someday"},
+	}
+
+	err := core.CreatePlannedSections(context.Background(), client, "list_1", []string{"Overdue", "Someday"}, moves, core.NopReporter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(client.created) != 1 || client.created[0] != "Overdue" {
+		t.Errorf("Expected only 'Overdue' to be created, got %v", client.created)
+	}
+	for i, want := range []string{"inbox", "new_Overdue", "someday"} {
+		if moves[i].SectionGID != want {
+			t.Errorf("Expected %s to move into %s, got %s", moves[i].Task.GID, want, moves[i].SectionGID)
+		}
+	}
+}
+
+func TestDetectDrift(t *testing.T) {
+	planned := []asana.Section{
+		{GID: "inbox", Name: "Inbox"},
+		{GID: "overdue", Name: "Overdue"},
+		{GID: "later", Name: "Due later"},
+	}
+	inSection := func(gid, section string) asana.Task {
+		return asana.Task{GID: gid, Name: gid, AssigneeSection: asana.AssigneeSection{GID: section}}
+	}
+	client := &fakeTaskListAPI{
+		sections: []asana.Section{
+			{GID: "inbox", Name: "Inbox"},
+			{GID: "overdue", Name: "Overdue"},
+			{GID: "later", Name: "Someday"},
+		},
+		tasks: []asana.Task{
+			inSection("still_valid", "inbox"),
+			inSection("moved", "overdue"),
+			inSection("renamed_target", "inbox"),
+			inSection("anchored", "inbox"),
+			inSection("after_existing", "inbox"),
+			inSection("after_planned", "inbox"),
+			inSection("stale_anchor", "inbox"),
+			inSection("in_overdue", "overdue"),
+			inSection("left_overdue", "inbox"),
+		},
+	}
+	move := func(gid, to, anchor string) core.TaskMove {
+		return core.TaskMove{Task: inSection(gid, "inbox"), SectionGID: to, InsertAfter: anchor}
+	}
+	moves := []core.TaskMove{
+		move("still_valid", "overdue", ""),
+		move("moved", "overdue", ""),
+		move("completed", "overdue", ""),
+		move("renamed_target", "later", ""),
+		move("anchored", "overdue", "moved"),
+		move("after_existing", "overdue", "in_overdue"),
+		move("after_planned", "overdue", "still_valid"),
+		move("stale_anchor", "overdue", "left_overdue"),
+	}
+
+	valid, drifts, err := core.DetectDrift(context.Background(), client, "list_1", planned, moves)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var validGIDs []string
+	for _, move := range valid {
+		validGIDs = append(validGIDs, move.Task.GID)
+	}
+	if strings.Join(validGIDs, ",") != "still_valid,after_existing,after_planned" {
+		t.Errorf("Expected still_valid, after_existing and after_planned to be applied, got %v", validGIDs)
+	}
+
+	reasons := make(map[string]error)
+	for _, drift := range drifts {
+		reasons[drift.Move.Task.GID] = drift.Reason
+	}
+	expected := map[string]error{
+		"moved":          core.ErrTaskMoved,
+		"completed":      core.ErrTaskGone,
+		"renamed_target": core.ErrSectionRenamed,
+		"anchored":       core.ErrAnchorDrifted,
+		"stale_anchor":   core.ErrAnchorDrifted,
+	}
+	for gid, want := range expected {
+		if !errors.Is(reasons[gid], want) {
+			t.Errorf("Expected '%s' to drift with %v, got %v", gid, want, reasons[gid])
+		}
+	}
+}
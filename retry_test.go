@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+)
+
+// newRetryTestClient creates a client pointed at the given server with a fast retry policy
+func newRetryTestClient(server *httptest.Server, maxAttempts int) *asana.Client {
+	return &asana.Client{
+		Client:  server.Client(),
+		Token:   "dummy_token",
+		BaseURL: server.URL,
+		Retry: asana.RetryPolicy{
+			MaxAttempts: maxAttempts,
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    10 * time.Millisecond,
+		},
+	}
+}
+
+func TestRetryHonorsRetryAfterOnRateLimit(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"data":{"gid":"1","name":"Test User"}}`))
+	}))
+	defer server.Close()
+
+	user, err := newRetryTestClient(server, 3).GetCurrentUser(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if user.Name != "Test User" {
+		t.Errorf("Expected user 'Test User', got '%s'", user.Name)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	if _, err := newRetryTestClient(server, 3).GetWorkspaces(context.Background()); err == nil {
+		t.Fatal("Expected an error after exhausting retries")
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+}
+
+func TestRetryDoesNotResendPostOnServerError(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 && r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"data":{"gid":"section_1","name":"Errands"}}`))
+	}))
+	defer server.Close()
+
+	// The section may have been created before the server failed
+	if _, err := newRetryTestClient(server, 3).CreateSection(context.Background(), "list_1", "Errands"); err == nil {
+		t.Fatal("Expected the server error to be returned")
+	}
+	if requests != 1 {
+		t.Errorf("Expected the POST to be sent once, got %d requests", requests)
+	}
+}
+
+func TestRetryDoesNotRetryClientErrors(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	if _, err := newRetryTestClient(server, 3).GetWorkspaces(context.Background()); err == nil {
+		t.Fatal("Expected an error for a 404 response")
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request, got %d", requests)
+	}
+}
+
+func TestRetryRespectsContextDeadline(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := newRetryTestClient(server, 5)
+	client.Retry.MaxDelay = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := client.GetCurrentUser(ctx); err == nil {
+		t.Fatal("Expected an error when Retry-After exceeds the deadline")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected to give up immediately, waited %v", elapsed)
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request, got %d", requests)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/config"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestRulesFirstMatchWins(t *testing.T) {
+	// Saturday 2023-04-15
+	referenceTime := time.Date(2023, 4, 15, 12, 0, 0, 0, time.UTC)
+
+	date := func(s string) asana.Date {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			panic(err)
+		}
+		return asana.Date(d)
+	}
+
+	config := core.DefaultSectionConfig()
+	config.Rules = []core.Rule{
+		{Name: "Urgent", Section: "Urgent", Tag: "urgent"},
+		{Section: "Work", Project: "Acme Launch"},
+		{Section: "Meetings", NameMatches: "(?i)^meeting"},
+		{Section: "High priority", CustomField: &core.CustomFieldMatch{Name: "Priority", Value: "high"}},
+		{Section: "Scheduled", Start: &core.DayRange{From: intPtr(1)}},
+		{Section: "Soon", Due: &core.DayRange{From: intPtr(1), To: intPtr(3)}},
+	}
+
+	sectionNameToGID := map[string]string{}
+	for _, name := range core.RequiredSectionNames(core.EffectiveRules(config)) {
+		sectionNameToGID[name] = "section_" + name
+	}
+
+	testCases := []struct {
+		name            string
+		task            asana.Task
+		expectedSection string
+		expectedRule    string
+	}{
+		{
+			name:            "Tag rule beats due date",
+			task:            asana.Task{Name: "Fix prod", DueOn: date("2023-04-10"), Tags: []asana.Tag{{GID: "1", Name: "Urgent"}}},
+			expectedSection: "Urgent",
+			expectedRule:    "Urgent",
+		},
+		{
+			name:            "Project membership",
+			task:            asana.Task{Name: "Write docs", Projects: []asana.Project{{GID: "2", Name: "Acme Launch"}}},
+			expectedSection: "Work",
+		},
+		{
+			name:            "Name regex",
+			task:            asana.Task{Name: "Meeting notes", DueOn: date("2023-05-01")},
+			expectedSection: "Meetings",
+		},
+		{
+			name:            "Custom field value",
+			task:            asana.Task{Name: "Taxes", CustomFields: []asana.CustomField{{Name: "Priority", DisplayValue: "High"}}},
+			expectedSection: "High priority",
+		},
+		{
+			name:            "Future start date",
+			task:            asana.Task{Name: "Plan trip", StartOn: date("2023-04-20"), DueOn: date("2023-04-25")},
+			expectedSection: "Scheduled",
+		},
+		{
+			name:            "Relative due date range",
+			task:            asana.Task{Name: "Call plumber", DueOn: date("2023-04-17")},
+			expectedSection: "Soon",
+		},
+		{
+			name:            "Falls back to the due-date buckets",
+			task:            asana.Task{Name: "Renew passport", DueOn: date("2023-04-20")},
+			expectedSection: "Due within the next 7 days",
+			expectedRule:    "Due within the next 7 days",
+		},
+		{
+			name:            "No date falls back to the no-date bucket",
+			task:            asana.Task{Name: "Someday"},
+			expectedSection: "Recently assigned",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.task.GID = "task_1"
+			tc.task.AssigneeSection = asana.AssigneeSection{GID: "section_other", Name: "Other"}
+
+			moves := core.CalculateTaskMoves([]asana.Task{tc.task}, config, sectionNameToGID, map[string]bool{}, referenceTime)
+			if len(moves) != 1 {
+				t.Fatalf("Expected 1 move, got %d", len(moves))
+			}
+			if moves[0].SectionName != tc.expectedSection {
+				t.Errorf("Expected section %s, got %s", tc.expectedSection, moves[0].SectionName)
+			}
+			if tc.expectedRule != "" && moves[0].Rule != tc.expectedRule {
+				t.Errorf("Expected rule %s, got %s", tc.expectedRule, moves[0].Rule)
+			}
+		})
+	}
+}
+
+func TestRuleValidation(t *testing.T) {
+	testCases := []struct {
+		name  string
+		rule  core.Rule
+		valid bool
+	}{
+		{"Valid tag rule", core.Rule{Section: "Urgent", Tag: "urgent"}, true},
+		{"Missing section", core.Rule{Tag: "urgent"}, false},
+		{"Bad regex", core.Rule{Section: "X", NameMatches: "("}, false},
+		{"Unknown category", core.Rule{Section: "X", Category: "someday"}, false},
+		{"Empty range", core.Rule{Section: "X", Due: &core.DayRange{From: intPtr(5), To: intPtr(1)}}, false},
+		{"Custom field without name", core.Rule{Section: "X", CustomField: &core.CustomFieldMatch{Value: "High"}}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.rule.Validate()
+			if tc.valid && err != nil {
+				t.Errorf("Expected rule to be valid, got %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Error("Expected a validation error")
+			}
+		})
+	}
+}
+
+func TestRulePatternsAreCompiledOnLoad(t *testing.T) {
+	var rule core.Rule
+	if err := json.Unmarshal([]byte(`{"section": "Meetings", "name_matches": "(?i)^meeting"}`), &rule); err != nil {
+		t.Fatalf("Error loading rule: %v", err)
+	}
+	now := time.Now()
+	if !rule.Matches(asana.Task{Name: "Meeting notes"}, now, asana.CategoryOptions{}) ||
+		rule.Matches(asana.Task{Name: "Notes"}, now, asana.CategoryOptions{}) {
+		t.Errorf("Expected the loaded pattern to match task names")
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("rules:\n  - section: Meetings\n    name_matches: \"(\"\n"), 0644); err != nil {
+		t.Fatalf("Error writing config: %v", err)
+	}
+	_, err := config.Load(config.Options{File: path})
+	var validationErr *config.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Problems[0].Line != 2 ||
+		!strings.Contains(validationErr.Problems[0].Message, "invalid name_matches pattern") {
+		t.Errorf("Expected the bad pattern to be reported as a config problem, got %v", err)
+	}
+}
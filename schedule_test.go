@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+	"github.com/dackerman/asana-tasks-sorter/internal/schedule"
+	"github.com/dackerman/asana-tasks-sorter/internal/state"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2025, 3, 12, 10, 7, 30, 0, time.UTC)
+
+	testCases := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"15m", now.Add(15 * time.Minute)},
+		{"*/30 * * * *", time.Date(2025, 3, 12, 10, 30, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2025, 3, 13, 9, 0, 0, 0, time.UTC)},
+		{"5/20 10 * * *", time.Date(2025, 3, 12, 10, 25, 0, 0, time.UTC)},
+		{"0 8-18/2 * * 1-5", time.Date(2025, 3, 12, 12, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 3, 12, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		// With both days restricted, either one matching is enough: the 15th or a Friday
+		{"0 0 15 * 5", time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			sched, err := schedule.Parse(tc.spec)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := sched.Next(now); !got.Equal(tc.expected) {
+				t.Errorf("Expected next run at %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestScheduleValidation(t *testing.T) {
+	for _, spec := range []string{"", "10s", "every day", "* * * *", "60 * * * *", "* 5-2 * * *", "*/0 * * * *", "0 0 32 * *"} {
+		if _, err := schedule.Parse(spec); err == nil {
+			t.Errorf("Expected schedule '%s' to be rejected", spec)
+		}
+	}
+}
+
+func TestNextRunResortsAfterMidnight(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone data not available: %v", err)
+	}
+	hourly, _ := schedule.Parse("@hourly")
+	daily, _ := schedule.Parse("0 9 * * *")
+
+	// 23:40 in New York is 03:40 UTC the next day
+	now := time.Date(2025, 3, 13, 3, 40, 0, 0, time.UTC)
+	midnight := time.Date(2025, 3, 13, 0, 0, 0, 0, loc)
+
+	if got := schedule.NextRun(daily, now, loc); !got.Equal(midnight) {
+		t.Errorf("Expected a run at midnight in New York (%v), got %v", midnight, got)
+	}
+	if got, want := schedule.NextRun(hourly, now.Add(-time.Hour), loc), midnight.Add(-time.Hour); !got.Equal(want) {
+		t.Errorf("Expected the hourly run at %v first, got %v", want, got)
+	}
+}
+
+// fakeMyTasksAPI adds the user, workspace and My Tasks list a full run starts from to fakeEventsAPI
+type fakeMyTasksAPI struct {
+	*fakeEventsAPI
+}
+
+func (f *fakeMyTasksAPI) GetCurrentUser(ctx context.Context) (*asana.User, error) {
+	return &asana.User{GID: "user_1", Name: "Test User"}, nil
+}
+
+func (f *fakeMyTasksAPI) GetWorkspaces(ctx context.Context) ([]asana.Workspace, error) {
+	return []asana.Workspace{{GID: "workspace_1", Name: "Workspace"}}, nil
+}
+
+func (f *fakeMyTasksAPI) GetUserTaskList(ctx context.Context, userGID, workspaceGID string) (*asana.UserTaskList, error) {
+	return &asana.UserTaskList{GID: "list_1"}, nil
+}
+
+func TestScheduledRunHonorsDryRun(t *testing.T) {
+	config := core.DefaultSectionConfig()
+	config.Timezone = "UTC"
+	var sections []asana.Section
+	for _, name := range core.RequiredSectionNames(core.EffectiveRules(config)) {
+		sections = append(sections, asana.Section{GID: "gid_" + name, Name: name})
+	}
+	yesterday := asana.Date(time.Now().AddDate(0, 0, -1))
+	client := &fakeMyTasksAPI{&fakeEventsAPI{fakeSectionsAPI: &fakeSectionsAPI{
+		sections: sections,
+		contents: map[string][]string{"gid_Recently assigned": {"task_1"}},
+		tasks: map[string]asana.Task{"task_1": {GID: "task_1", Name: "Task 1", DueOn: yesterday,
+			AssigneeSection: asana.AssigneeSection{GID: "gid_Recently assigned"}}},
+	}}}
+	stateDir := t.TempDir()
+	var logs bytes.Buffer
+
+	runScheduled(client, config, time.Minute, stateDir, false, true, log.New(&logs, "", 0))
+
+	if overdue := client.contents["gid_Overdue"]; len(overdue) != 0 {
+		t.Errorf("Expected a dry run to leave tasks where they are, got %v", client.contents)
+	}
+	if !strings.Contains(logs.String(), "Would move task 'Task 1' from 'Recently assigned' to 'Overdue'") {
+		t.Errorf("Expected the planned move to be logged, got:\n%s", logs.String())
+	}
+	if journal, err := state.LatestJournal(stateDir); err == nil {
+		t.Errorf("Expected a dry run not to be journaled, got %+v", journal)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+)
+
+// applySectionReorders replays section moves against a list of section names, as Asana would
+func applySectionReorders(t *testing.T, names []string, moves []core.SectionReorder) []string {
+	t.Helper()
+	for _, move := range moves {
+		names = slices.DeleteFunc(names, func(name string) bool { return name == move.Section.Name })
+		anchor, offset := move.After.Name, 1
+		if move.Before.GID != "" {
+			anchor, offset = move.Before.Name, 0
+		}
+		index := slices.Index(names, anchor)
+		if index < 0 {
+			t.Fatalf("Move of %s anchors on missing section %s", move.Section.Name, anchor)
+		}
+		names = slices.Insert(names, index+offset, move.Section.Name)
+	}
+	return names
+}
+
+func TestCalculateSectionOrder(t *testing.T) {
+	config := core.DefaultSectionConfig()
+	config.IgnoredSections = []string{"Doing Now"}
+
+	testCases := []struct {
+		name     string
+		current  []string
+		expected []string
+		moves    int
+	}{
+		{
+			name:     "Sections already in order",
+			current:  []string{"Overdue", "Doing Now", "Due today", "Due later", "Recently assigned"},
+			expected: []string{"Overdue", "Doing Now", "Due today", "Due later", "Recently assigned"},
+			moves:    0,
+		},
+		{
+			name:     "Appended section moves up",
+			current:  []string{"Overdue", "Due within the next 7 days", "Due later", "Recently assigned", "Due today"},
+			expected: []string{"Overdue", "Due today", "Due within the next 7 days", "Due later", "Recently assigned"},
+			moves:    1,
+		},
+		{
+			name:     "Unmanaged and ignored sections keep their places",
+			current:  []string{"Due later", "Doing Now", "Someday", "Overdue", "Waiting"},
+			expected: []string{"Overdue", "Doing Now", "Someday", "Due later", "Waiting"},
+			moves:    2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sections := make([]asana.Section, len(tc.current))
+			for i, name := range tc.current {
+				sections[i] = asana.Section{GID: "gid_" + name, Name: name}
+			}
+
+			moves := core.CalculateSectionOrder(sections, config)
+			got := applySectionReorders(t, slices.Clone(tc.current), moves)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+			if len(moves) != tc.moves {
+				t.Errorf("Expected %d moves, got %d: %+v", tc.moves, len(moves), moves)
+			}
+		})
+	}
+}
+
+func TestInsertSectionRequest(t *testing.T) {
+	var path string
+	var body struct {
+		Data map[string]string `json:"data"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"data":{}}`))
+	}))
+	defer server.Close()
+
+	client := &asana.Client{Client: server.Client(), Token: "dummy_token", BaseURL: server.URL}
+	err := client.InsertSection(context.Background(), "project_1", asana.SectionInsert{SectionGID: "section_2", AfterSection: "section_1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if path != "/projects/project_1/sections/insert" {
+		t.Errorf("Unexpected path %s", path)
+	}
+	expected := map[string]string{"section": "section_2", "after_section": "section_1"}
+	if !reflect.DeepEqual(body.Data, expected) {
+		t.Errorf("Expected body %v, got %v", expected, body.Data)
+	}
+}
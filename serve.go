@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+	"github.com/dackerman/asana-tasks-sorter/internal/output"
+	"github.com/dackerman/asana-tasks-sorter/internal/schedule"
+)
+
+// runServe keeps sorting on a schedule, and right after midnight in the user's
+// time zone, until it receives SIGTERM or an interrupt. Each run gets its own
+// timeout and isn't cancelled by the signal, so moves in flight finish first.
+// With dryRun each run only logs the moves it would make.
+func runServe(client asana.API, conf core.SectionConfig, args []string, timeout time.Duration, stateDir string,
+	incremental, dryRun bool) {
+
+	serveFlags := flag.NewFlagSet("serve", flag.ExitOnError)
+	spec := serveFlags.String("schedule", conf.Schedule, "How often to sort: an interval like '15m' or a cron expression like '*/30 8-18 * * 1-5' (overrides the config file)")
+	serveFlags.Parse(args)
+
+	if incremental && dryRun {
+		exitWithError(output.FormatText, nil, fmt.Errorf("--incremental can't be combined with --dry-run"))
+	}
+	if *spec == "" {
+		exitWithError(output.FormatText, nil, fmt.Errorf("serve needs a schedule: set 'schedule' in the config file or pass --schedule"))
+	}
+	sched, err := schedule.Parse(*spec)
+	if err != nil {
+		exitWithError(output.FormatText, nil, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	logger := log.New(os.Stdout, "", log.LstdFlags)
+	logger.Printf("Sorting on schedule '%s' and after midnight", *spec)
+	if dryRun {
+		logger.Print("Dry run: tasks won't be moved")
+	}
+
+	loc := time.Local
+	for {
+		if runLoc := runScheduled(client, conf, timeout, stateDir, incremental, dryRun, logger); runLoc != nil {
+			loc = runLoc
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		next := schedule.NextRun(sched, time.Now(), loc)
+		logger.Printf("Next run at %s", next.Format("2006-01-02 15:04 MST"))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	logger.Print("Shutting down")
+}
+
+// runScheduled performs and logs a single scheduled sorting run. It returns the
+// time zone the run used for "today", or nil if the run didn't get that far.
+func runScheduled(client asana.API, conf core.SectionConfig, timeout time.Duration, stateDir string,
+	incremental, dryRun bool, logger *log.Logger) *time.Location {
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	logger.Print("Sorting run started")
+	started := time.Now()
+	var result *core.RunResult
+	var err error
+	if incremental {
+		result, err = runIncremental(ctx, client, conf, stateDir, newLogReporter(logger))
+	} else {
+		result, err = core.OrganizeTasks(ctx, client, conf, dryRun, newLogReporter(logger))
+	}
+
+	if dryRun {
+		logPlannedMoves(logger, result)
+	} else if journalErr := recordJournal(stateDir, result); journalErr != nil {
+		logger.Printf("Warning: %v", journalErr)
+	}
+
+	moved, failed := 0, 0
+	if result != nil {
+		for _, workspace := range result.Workspaces {
+			for _, moveResult := range workspace.MoveResults {
+				if moveResult.Err != nil {
+					failed++
+				} else {
+					moved++
+				}
+			}
+		}
+	}
+	elapsed := time.Since(started).Round(time.Millisecond)
+	if err != nil {
+		logger.Printf("Sorting run failed after %v (%d moved, %d failed): %v", elapsed, moved, failed, err)
+	} else {
+		logger.Printf("Sorting run finished in %v: %d moved, %d failed", elapsed, moved, failed)
+	}
+
+	if result == nil || result.TimeZone == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(result.TimeZone)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// logPlannedMoves logs the moves a dry run would have made, one line each
+func logPlannedMoves(logger *log.Logger, result *core.RunResult) {
+	if result == nil {
+		return
+	}
+	for _, workspace := range result.Workspaces {
+		for _, move := range workspace.PlannedMoves {
+			logger.Printf("Would move task '%s' from '%s' to '%s'", move.Task.Name, move.Task.AssigneeSection.Name, move.SectionName)
+		}
+	}
+}
+
+// newLogReporter logs the changes a run makes, one line each
+func newLogReporter(logger *log.Logger) core.Reporter {
+	return core.ReporterFunc(func(event core.Event) {
+		switch event.Type {
+		case core.EventSectionCreated:
+			logger.Printf("Created section '%s'", event.Section)
+		case core.EventSectionMoved:
+			logger.Printf("Moved section '%s'", event.SectionReorder.Section.Name)
+		case core.EventMoveSucceeded:
+			logger.Printf("Moved task '%s' from '%s' to '%s'", event.Move.Task.Name, event.Move.Task.AssigneeSection.Name, event.Move.SectionName)
+		case core.EventMoveFailed:
+			logger.Printf("Error moving task '%s': %v", event.Move.Task.Name, event.Err)
+		case core.EventBatchFailed:
+			logger.Printf("Batch request for %d tasks failed, moving them one at a time: %v", event.Count, event.Err)
+		case core.EventWorkspaceFailed:
+			logger.Printf("Error sorting workspace: %v", event.Err)
+		}
+	})
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+	"github.com/dackerman/asana-tasks-sorter/internal/state"
+)
+
+// runIncremental sorts the tasks that changed since the last incremental run,
+// and saves the events sync token for the next one
+func runIncremental(ctx context.Context, client asana.API, conf core.SectionConfig, stateDir string,
+	reporter core.Reporter) (*core.RunResult, error) {
+
+	taskList, err := core.ResolveTaskList(ctx, client, conf, reporter)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := state.LoadSyncState(stateDir, taskList.GID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, next, err := core.OrganizeTasksSince(ctx, client, taskList, conf, previous, reporter)
+	if saveErr := state.SaveSyncState(stateDir, taskList.GID, next); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	return result, err
+}
@@ -410,3 +410,298 @@ func TestDateCalculations(t *testing.T) {
 		})
 	}
 }
+
+// Test configurable bucket thresholds and week semantics
+func TestBucketThresholds(t *testing.T) {
+	// Saturday 2023-04-15
+	referenceTime := time.Date(2023, 4, 15, 12, 0, 0, 0, time.UTC)
+
+	baseConfig := core.DefaultSectionConfig()
+
+	withTomorrow := baseConfig
+	withTomorrow.DueTomorrow = "Due tomorrow"
+
+	threeDays := baseConfig
+	threeDays.LookAheadDays = 3
+
+	twoWeeks := baseConfig
+	twoWeeks.LookAheadDays = 14
+
+	calendarMonday := baseConfig
+	calendarMonday.WeekMode = core.WeekModeCalendar
+
+	calendarSunday := baseConfig
+	calendarSunday.WeekMode = core.WeekModeCalendar
+	calendarSunday.WeekStart = "sunday"
+
+	calendarWithTomorrow := calendarSunday
+	calendarWithTomorrow.DueTomorrow = "Due tomorrow"
+
+	testCases := []struct {
+		name            string
+		config          core.SectionConfig
+		dueDate         string
+		expectedSection string
+	}{
+		// Due tomorrow bucket
+		{"Tomorrow bucket takes tomorrow", withTomorrow, "2023-04-16", "Due tomorrow"},
+		{"Tomorrow bucket leaves today alone", withTomorrow, "2023-04-15", "Due today"},
+		{"Tomorrow bucket leaves the rest of the week alone", withTomorrow, "2023-04-17", "Due within the next 7 days"},
+		{"Without a tomorrow section tomorrow is this week", baseConfig, "2023-04-16", "Due within the next 7 days"},
+
+		// Rolling look-ahead window
+		{"3-day window includes day 3", threeDays, "2023-04-18", "Due within the next 7 days"},
+		{"3-day window excludes day 4", threeDays, "2023-04-19", "Due later"},
+		{"14-day window includes day 14", twoWeeks, "2023-04-29", "Due within the next 7 days"},
+		{"14-day window excludes day 15", twoWeeks, "2023-04-30", "Due later"},
+
+		// Calendar weeks starting Monday end on Sunday 2023-04-16
+		{"Monday weeks: Sunday is this week", calendarMonday, "2023-04-16", "Due within the next 7 days"},
+		{"Monday weeks: next Monday is later", calendarMonday, "2023-04-17", "Due later"},
+
+		// Calendar weeks starting Sunday end today (Saturday), so nothing else is this week
+		{"Sunday weeks: tomorrow starts a new week", calendarSunday, "2023-04-16", "Due later"},
+		{"Sunday weeks: today is still today", calendarSunday, "2023-04-15", "Due today"},
+		{"Sunday weeks with tomorrow bucket", calendarWithTomorrow, "2023-04-16", "Due tomorrow"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dueDate, _ := time.Parse("2006-01-02", tc.dueDate)
+			task := asana.Task{
+				GID:   "task_test",
+				Name:  "Test Task",
+				DueOn: asana.Date(dueDate),
+				AssigneeSection: asana.AssigneeSection{
+					GID:  "section_Wrong Section",
+					Name: "Wrong Section",
+				},
+			}
+
+			sectionNameToGID := map[string]string{}
+			for _, name := range core.RequiredSectionNames(core.EffectiveRules(tc.config)) {
+				sectionNameToGID[name] = "section_" + name
+			}
+
+			moves := core.CalculateTaskMoves([]asana.Task{task}, tc.config, sectionNameToGID, map[string]bool{}, referenceTime)
+			if len(moves) != 1 {
+				t.Fatalf("Expected 1 move, got %d", len(moves))
+			}
+			if moves[0].SectionName != tc.expectedSection {
+				t.Errorf("Expected section %s for %s, got %s", tc.expectedSection, tc.dueDate, moves[0].SectionName)
+			}
+		})
+	}
+}
+
+// Test that invalid week settings are rejected
+func TestBucketThresholdValidation(t *testing.T) {
+	badWeekMode := core.DefaultSectionConfig()
+	badWeekMode.WeekMode = "fortnight"
+
+	badWeekStart := core.DefaultSectionConfig()
+	badWeekStart.WeekStart = "someday"
+
+	negativeWindow := core.DefaultSectionConfig()
+	negativeWindow.LookAheadDays = -1
+
+	for name, config := range map[string]core.SectionConfig{
+		"week mode":  badWeekMode,
+		"week start": badWeekStart,
+		"look ahead": negativeWindow,
+	} {
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected invalid %s to be rejected", name)
+		}
+	}
+
+	if err := core.DefaultSectionConfig().Validate(); err != nil {
+		t.Errorf("Expected default config to be valid, got %v", err)
+	}
+}
+
+// Test that due times and time zones are honored, including across DST changes
+func TestDueTimesAndTimeZones(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	mustParse := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			panic(err)
+		}
+		return parsed
+	}
+	dateOnly := func(value string) asana.Date {
+		parsed, _ := time.Parse("2006-01-02", value)
+		return asana.Date(parsed)
+	}
+
+	startOfDay := asana.CategoryOptions{}
+	asOfNow := asana.CategoryOptions{OverdueAsOfNow: true}
+
+	testCases := []struct {
+		name     string
+		now      time.Time
+		task     asana.Task
+		opts     asana.CategoryOptions
+		expected asana.TaskCategory
+	}{
+		{
+			name:     "Due time already passed today stays due today by default",
+			now:      time.Date(2023, 4, 15, 12, 0, 0, 0, newYork),
+			task:     asana.Task{DueOn: dateOnly("2023-04-15"), DueAt: mustParse("2023-04-15T13:00:00Z")}, // 9am EDT
+			opts:     startOfDay,
+			expected: asana.DueToday,
+		},
+		{
+			name:     "Due time already passed today is overdue as of now",
+			now:      time.Date(2023, 4, 15, 12, 0, 0, 0, newYork),
+			task:     asana.Task{DueOn: dateOnly("2023-04-15"), DueAt: mustParse("2023-04-15T13:00:00Z")},
+			opts:     asOfNow,
+			expected: asana.Overdue,
+		},
+		{
+			name:     "Due time later today is not overdue as of now",
+			now:      time.Date(2023, 4, 15, 8, 0, 0, 0, newYork),
+			task:     asana.Task{DueOn: dateOnly("2023-04-15"), DueAt: mustParse("2023-04-15T13:00:00Z")},
+			opts:     asOfNow,
+			expected: asana.DueToday,
+		},
+		{
+			name:     "Late evening UTC due time is today in New York",
+			now:      time.Date(2023, 4, 15, 12, 0, 0, 0, newYork),
+			task:     asana.Task{DueOn: dateOnly("2023-04-16"), DueAt: mustParse("2023-04-16T02:00:00Z")}, // 10pm EDT on the 15th
+			opts:     startOfDay,
+			expected: asana.DueToday,
+		},
+		{
+			name:     "Late evening UTC due time is tomorrow in Tokyo",
+			now:      time.Date(2023, 4, 15, 12, 0, 0, 0, tokyo),
+			task:     asana.Task{DueOn: dateOnly("2023-04-15"), DueAt: mustParse("2023-04-15T16:00:00Z")}, // 1am JST on the 16th
+			opts:     startOfDay,
+			expected: asana.DueThisWeek,
+		},
+		{
+			name:     "Date-only task due a week after the spring-forward change",
+			now:      time.Date(2023, 3, 11, 23, 30, 0, 0, newYork), // DST starts 2023-03-12
+			task:     asana.Task{DueOn: dateOnly("2023-03-18")},
+			opts:     startOfDay,
+			expected: asana.DueThisWeek,
+		},
+		{
+			name:     "Date-only task due eight days after the spring-forward change",
+			now:      time.Date(2023, 3, 11, 23, 30, 0, 0, newYork),
+			task:     asana.Task{DueOn: dateOnly("2023-03-19")},
+			opts:     startOfDay,
+			expected: asana.DueLater,
+		},
+		{
+			name:     "Due time on the fall-back day is still today",
+			now:      time.Date(2023, 11, 5, 0, 30, 0, 0, newYork),                                        // DST ends 2023-11-05
+			task:     asana.Task{DueOn: dateOnly("2023-11-06"), DueAt: mustParse("2023-11-06T04:30:00Z")}, // 11:30pm EST on the 5th
+			opts:     startOfDay,
+			expected: asana.DueToday,
+		},
+		{
+			name:     "Yesterday across the fall-back change is overdue",
+			now:      time.Date(2023, 11, 5, 0, 30, 0, 0, newYork),
+			task:     asana.Task{DueOn: dateOnly("2023-11-04")},
+			opts:     startOfDay,
+			expected: asana.Overdue,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if category := tc.task.Categorize(tc.now, tc.opts); category != tc.expected {
+				t.Errorf("Expected category %v, got %v", tc.expected, category)
+			}
+		})
+	}
+}
+
+// Test the choice of time zone for categorization
+func TestResolveLocation(t *testing.T) {
+	loc, err := core.ResolveLocation("Asia/Tokyo", "America/New_York")
+	if err != nil || loc.String() != "Asia/Tokyo" {
+		t.Errorf("Expected configured time zone to win, got %v (%v)", loc, err)
+	}
+
+	loc, err = core.ResolveLocation("", "America/New_York")
+	if err != nil || loc.String() != "America/New_York" {
+		t.Errorf("Expected profile time zone as the default, got %v (%v)", loc, err)
+	}
+
+	loc, err = core.ResolveLocation("", "")
+	if err != nil || loc != time.Local {
+		t.Errorf("Expected local time zone without any setting, got %v (%v)", loc, err)
+	}
+
+	if _, err := core.ResolveLocation("Mars/Olympus_Mons", ""); err == nil {
+		t.Error("Expected an error for an unknown configured time zone")
+	}
+}
+
+// Test start-date aware categorization
+func TestStartDateCategorization(t *testing.T) {
+	// Saturday 2023-04-15
+	referenceTime := time.Date(2023, 4, 15, 12, 0, 0, 0, time.UTC)
+
+	config := core.DefaultSectionConfig()
+	config.NotStarted = "Scheduled"
+	config.StartsToday = "Starting today"
+
+	sectionNameToGID := map[string]string{}
+	for _, name := range core.RequiredSectionNames(core.EffectiveRules(config)) {
+		sectionNameToGID[name] = "section_" + name
+	}
+
+	date := func(value string) asana.Date {
+		parsed, _ := time.Parse("2006-01-02", value)
+		return asana.Date(parsed)
+	}
+
+	testCases := []struct {
+		name            string
+		task            asana.Task
+		expectedSection string
+	}{
+		{"Future start date is scheduled even if due soon", asana.Task{StartOn: date("2023-04-17"), DueOn: date("2023-04-18")}, "Scheduled"},
+		{"Future start date without a due date is scheduled", asana.Task{StartOn: date("2023-05-01")}, "Scheduled"},
+		{"Future start time is scheduled", asana.Task{StartAt: time.Date(2023, 4, 16, 9, 0, 0, 0, time.UTC), DueOn: date("2023-04-20")}, "Scheduled"},
+		{"Starting today is surfaced", asana.Task{StartOn: date("2023-04-15"), DueOn: date("2023-04-20")}, "Starting today"},
+		{"Starting today without a due date is surfaced", asana.Task{StartOn: date("2023-04-15")}, "Starting today"},
+		{"Starting today but due today stays due today", asana.Task{StartOn: date("2023-04-15"), DueOn: date("2023-04-15")}, "Due today"},
+		{"Started earlier falls back to its due date", asana.Task{StartOn: date("2023-04-10"), DueOn: date("2023-05-15")}, "Due later"},
+		{"Started earlier and overdue is overdue", asana.Task{StartOn: date("2023-04-01"), DueOn: date("2023-04-10")}, "Overdue"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.task.GID = "task_test"
+			tc.task.Name = tc.name
+			tc.task.AssigneeSection = asana.AssigneeSection{GID: "section_Wrong Section", Name: "Wrong Section"}
+
+			moves := core.CalculateTaskMoves([]asana.Task{tc.task}, config, sectionNameToGID, map[string]bool{}, referenceTime)
+			if len(moves) != 1 {
+				t.Fatalf("Expected 1 move, got %d", len(moves))
+			}
+			if moves[0].SectionName != tc.expectedSection {
+				t.Errorf("Expected section %s, got %s", tc.expectedSection, moves[0].SectionName)
+			}
+		})
+	}
+
+	// Without the optional sections, start dates don't affect the buckets
+	task := asana.Task{StartOn: date("2023-04-17"), DueOn: date("2023-04-18")}
+	if category := task.GetTaskCategory(referenceTime); category != asana.DueThisWeek {
+		t.Errorf("Expected start date to be ignored by default, got %v", category)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+	"github.com/dackerman/asana-tasks-sorter/internal/output"
+	"github.com/dackerman/asana-tasks-sorter/internal/state"
+	"github.com/dackerman/asana-tasks-sorter/internal/ui"
+)
+
+// recordJournal saves the moves a run made so the undo command can reverse them
+func recordJournal(stateDir string, result *core.RunResult) error {
+	if result == nil {
+		return nil
+	}
+	journal := state.NewJournal(result, time.Now())
+	if len(journal.Entries) == 0 {
+		return nil
+	}
+	if err := state.SaveJournal(stateDir, journal); err != nil {
+		return fmt.Errorf("could not record this run for undo: %w", err)
+	}
+	return nil
+}
+
+// runUndo moves the tasks of the most recent sorting run back to their previous sections.
+// With dryRun it only shows the moves it would make, and the run stays undoable.
+func runUndo(ctx context.Context, client asana.API, args []string, stateDir string, conf core.SectionConfig,
+	dryRun bool, format string) {
+
+	undoFlags := flag.NewFlagSet("undo", flag.ExitOnError)
+	force := undoFlags.Bool("force", false, "Also move back tasks that were moved to another section since the run")
+	undoFlags.Parse(args)
+
+	machineOutput := format != output.FormatText
+	var ranAt time.Time
+	fail := func(result *core.UndoResult, err error) {
+		if !machineOutput {
+			exitWithError(format, nil, err)
+		}
+		writeUndoOutput(format, ranAt, result, err)
+		os.Exit(1)
+	}
+
+	journal, err := state.LatestJournal(stateDir)
+	if errors.Is(err, state.ErrNoJournal) {
+		if machineOutput {
+			writeUndoOutput(format, ranAt, nil, nil)
+			return
+		}
+		fmt.Println(ui.Info("There is no sorting run to undo"))
+		return
+	}
+	if err != nil {
+		fail(nil, err)
+	}
+	ranAt = journal.RanAt
+
+	// Human-oriented progress output is suppressed in machine-readable modes
+	var reporter core.Reporter = core.NopReporter{}
+	if !machineOutput {
+		reporter = ui.NewConsoleReporter(os.Stdout)
+		fmt.Printf("%s %s\n", ui.Info("Undoing sorting run from"), ui.Important(ranAt.Local().Format("2006-01-02 15:04:05")))
+	}
+
+	result, err := core.UndoMoves(ctx, client, journal.Reverts(), *force, dryRun, conf.Concurrency, conf.BatchSize, reporter)
+	if err != nil {
+		fail(result, err)
+	}
+
+	if dryRun {
+		if machineOutput {
+			writeUndoOutput(format, ranAt, result, nil)
+			return
+		}
+		ui.DisplayPlannedChanges(&core.RunResult{
+			DryRun:     true,
+			Workspaces: []core.WorkspaceResult{{PlannedMoves: result.PlannedMoves}},
+		})
+		return
+	}
+
+	// Keep the journal for another attempt if tasks were left alone because they moved on
+	movedOn := false
+	for _, skipped := range result.Skipped {
+		movedOn = movedOn || errors.Is(skipped.Reason, core.ErrTaskMovedOn)
+	}
+	if !movedOn {
+		if err := journal.MarkUndone(time.Now()); err != nil {
+			fail(result, err)
+		}
+	}
+
+	if machineOutput {
+		writeUndoOutput(format, ranAt, result, nil)
+	} else if movedOn {
+		fmt.Println(ui.Info("Run undo again with --force to move back tasks that were moved since the run"))
+	}
+}
+
+// writeUndoOutput writes the outcome of an undo to stdout in the given machine-readable format
+func writeUndoOutput(format string, ranAt time.Time, result *core.UndoResult, undoErr error) {
+	var err error
+	if format == output.FormatNDJSON {
+		err = output.WriteUndoNDJSON(os.Stdout, ranAt, result, undoErr)
+	} else {
+		err = output.WriteUndoJSON(os.Stdout, ranAt, result, undoErr)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+	"github.com/dackerman/asana-tasks-sorter/internal/output"
+	"github.com/dackerman/asana-tasks-sorter/internal/state"
+)
+
+// fakeUndoAPI is an asana.API holding tasks in memory
+type fakeUndoAPI struct {
+	asana.API
+
+	mu    sync.Mutex
+	tasks map[string]asana.Task
+}
+
+func (f *fakeUndoAPI) GetTask(ctx context.Context, taskGID string) (*asana.Task, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	task, ok := f.tasks[taskGID]
+	if !ok {
+		return nil, &asana.APIError{StatusCode: http.StatusNotFound, Method: http.MethodGet, Path: "/tasks/" + taskGID}
+	}
+	return &task, nil
+}
+
+func (f *fakeUndoAPI) MoveTaskToSection(ctx context.Context, move asana.SectionMove) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	task := f.tasks[move.TaskGID]
+	task.AssigneeSection = asana.AssigneeSection{GID: move.SectionGID}
+	f.tasks[move.TaskGID] = task
+	return nil
+}
+
+func TestJournalRoundTrip(t *testing.T) {
+	stateDir := t.TempDir()
+
+	if _, err := state.LatestJournal(stateDir); !errors.Is(err, state.ErrNoJournal) {
+		t.Fatalf("Expected ErrNoJournal before any run, got %v", err)
+	}
+
+	move := core.TaskMove{
+		Task:        asana.Task{GID: "task_1", Name: "Task 1", AssigneeSection: asana.AssigneeSection{GID: "inbox", Name: "Inbox"}},
+		SectionGID:  "overdue",
+		SectionName: "Overdue",
+	}
+	reorder := move
+	reorder.Task.GID, reorder.Reorder = "task_2", true
+	failed := move
+	failed.Task.GID = "task_3"
+
+	result := &core.RunResult{
+		User: asana.User{GID: "user_1"},
+		Workspaces: []core.WorkspaceResult{{
+			Workspace: asana.Workspace{GID: "workspace_1"},
+			MoveResults: []core.MoveResult{
+				{Move: move, At: time.Now()},
+				{Move: reorder, At: time.Now()},
+				{Move: failed, Err: errors.New("boom"), At: time.Now()},
+			},
+		}},
+	}
+
+	older := state.NewJournal(result, time.Now().Add(-time.Hour))
+	latest := state.NewJournal(result, time.Now())
+	if len(latest.Entries) != 1 {
+		t.Fatalf("Expected only the successful section change to be journaled, got %+v", latest.Entries)
+	}
+	for _, journal := range []*state.Journal{older, latest} {
+		if err := state.SaveJournal(stateDir, journal); err != nil {
+			t.Fatalf("Error saving journal: %v", err)
+		}
+	}
+
+	loaded, err := state.LatestJournal(stateDir)
+	if err != nil {
+		t.Fatalf("Error loading journal: %v", err)
+	}
+	if !loaded.RanAt.Equal(latest.RanAt) {
+		t.Errorf("Expected the latest journal, got the one from %v", loaded.RanAt)
+	}
+	reverts := loaded.Reverts()
+	if len(reverts) != 1 || reverts[0].From.GID != "inbox" || reverts[0].To.GID != "overdue" {
+		t.Errorf("Unexpected reverts: %+v", reverts)
+	}
+
+	// Once undone, the next undo goes back to the previous run
+	if err := loaded.MarkUndone(time.Now()); err != nil {
+		t.Fatalf("Error marking journal undone: %v", err)
+	}
+	loaded, err = state.LatestJournal(stateDir)
+	if err != nil || !loaded.RanAt.Equal(older.RanAt) {
+		t.Errorf("Expected the older journal next, got %+v, %v", loaded, err)
+	}
+}
+
+func TestUndoMoves(t *testing.T) {
+	inSection := func(gid, section string, completed bool) asana.Task {
+		return asana.Task{GID: gid, Name: gid, Completed: completed, AssigneeSection: asana.AssigneeSection{GID: section}}
+	}
+	client := &fakeUndoAPI{tasks: map[string]asana.Task{
+		"moved":     inSection("moved", "overdue", false),
+		"completed": inSection("completed", "overdue", true),
+		"changed":   inSection("changed", "someday", false),
+	}}
+	revert := func(gid string) core.Revert {
+		return core.Revert{TaskGID: gid, TaskName: gid, From: asana.Section{GID: "inbox"}, To: asana.Section{GID: "overdue"}}
+	}
+	reverts := []core.Revert{revert("moved"), revert("completed"), revert("changed"), revert("deleted")}
+
+	// A dry run only plans the moves back
+	result, err := core.UndoMoves(context.Background(), client, reverts, false, true, 2, 1, core.NopReporter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.PlannedMoves) != 1 || len(result.MoveResults) != 0 || client.tasks["moved"].AssigneeSection.GID != "overdue" {
+		t.Errorf("Expected a dry run to only plan moving 'moved' back, got %+v", result)
+	}
+
+	reporter := &core.RecordingReporter{}
+	result, err = core.UndoMoves(context.Background(), client, reverts, false, false, 2, 1, reporter)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.MoveResults) != 1 || client.tasks["moved"].AssigneeSection.GID != "inbox" {
+		t.Errorf("Expected only 'moved' to be moved back, got %+v", result.MoveResults)
+	}
+
+	reasons := make(map[string]error)
+	for _, skipped := range result.Skipped {
+		reasons[skipped.Revert.TaskGID] = skipped.Reason
+	}
+	if !errors.Is(reasons["completed"], core.ErrTaskCompleted) || !errors.Is(reasons["changed"], core.ErrTaskMovedOn) ||
+		!errors.Is(reasons["deleted"], core.ErrTaskDeleted) {
+		t.Errorf("Unexpected skip reasons: %v", reasons)
+	}
+	if got := len(reporter.EventsOfType(core.EventUndoSkipped)); got != 3 {
+		t.Errorf("Expected 3 undo_skipped events, got %d", got)
+	}
+
+	// Forcing moves back tasks that were moved on, and skips tasks that are already back
+	result, err = core.UndoMoves(context.Background(), client, reverts, true, false, 2, 1, core.NopReporter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.MoveResults) != 1 || client.tasks["changed"].AssigneeSection.GID != "inbox" {
+		t.Errorf("Expected only 'changed' to be moved back, got %+v", result.MoveResults)
+	}
+}
+
+func TestUndoMachineOutput(t *testing.T) {
+	back := core.TaskMove{Task: asana.Task{GID: "task_1", Name: "Task 1"}, SectionGID: "inbox", SectionName: "Inbox"}
+	failed := back
+	failed.Task.GID = "task_2"
+	result := &core.UndoResult{
+		MoveResults: []core.MoveResult{{Move: back}, {Move: failed, Err: errors.New("boom")}},
+		Skipped: []core.SkippedRevert{{
+			Revert: core.Revert{TaskGID: "task_3", TaskName: "Task 3", From: asana.Section{GID: "inbox", Name: "Inbox"}},
+			Reason: core.ErrTaskCompleted,
+		}},
+	}
+	ranAt := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	if err := output.WriteUndoJSON(&buf, ranAt, result, nil); err != nil {
+		t.Fatalf("Error writing JSON: %v", err)
+	}
+	var doc output.UndoDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if doc.RanAt == nil || !doc.RanAt.Equal(ranAt) || len(doc.Reverted) != 1 || doc.Reverted[0].TaskGID != "task_1" ||
+		len(doc.Errors) != 1 || len(doc.Skipped) != 1 || doc.Skipped[0].Reason != core.ErrTaskCompleted.Error() {
+		t.Errorf("Unexpected undo document: %+v", doc)
+	}
+
+	buf.Reset()
+	if err := output.WriteUndoNDJSON(&buf, ranAt, result, nil); err != nil {
+		t.Fatalf("Error writing NDJSON: %v", err)
+	}
+	var types []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Line is not valid JSON: %v\n%s", err, line)
+		}
+		types = append(types, record["type"].(string))
+	}
+	if strings.Join(types, ",") != "reverted_move,skipped_move,move_error,undo" {
+		t.Errorf("Unexpected NDJSON records: %v", types)
+	}
+}
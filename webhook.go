@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+	"github.com/dackerman/asana-tasks-sorter/internal/output"
+	"github.com/dackerman/asana-tasks-sorter/internal/webhook"
+)
+
+// webhookFilters are the task events the sorter subscribes to: changes to a
+// task, and tasks newly added to My Tasks
+var webhookFilters = []asana.WebhookFilter{
+	{ResourceType: "task", Action: "changed"},
+	{ResourceType: "task", Action: "added"},
+}
+
+// taskQueue collects the GIDs of changed tasks until they are sorted
+type taskQueue struct {
+	mu    sync.Mutex
+	gids  []string
+	seen  map[string]bool
+	ready chan struct{}
+}
+
+func newTaskQueue() *taskQueue {
+	return &taskQueue{seen: make(map[string]bool), ready: make(chan struct{}, 1)}
+}
+
+// add queues tasks that aren't queued yet
+func (q *taskQueue) add(gids []string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, gid := range gids {
+		if !q.seen[gid] {
+			q.seen[gid] = true
+			q.gids = append(q.gids, gid)
+		}
+	}
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// take empties the queue
+func (q *taskQueue) take() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	gids := q.gids
+	q.gids = nil
+	q.seen = make(map[string]bool)
+	return gids
+}
+
+// runWebhook listens for Asana webhook events about the user's My Tasks list and
+// sorts just the tasks that changed. With --target-url it subscribes to the
+// list, reachable at that public URL, and unsubscribes on shutdown; without it
+// the listener only waits for deliveries, e.g. from webhook.StandIn. With dryRun
+// the changed tasks' moves are only logged.
+func runWebhook(client asana.API, conf core.SectionConfig, args []string, timeout time.Duration, stateDir string,
+	dryRun bool) {
+
+	webhookFlags := flag.NewFlagSet("webhook", flag.ExitOnError)
+	listen := webhookFlags.String("listen", ":8080", "Address to listen on for webhook deliveries")
+	targetURL := webhookFlags.String("target-url", "", "Public HTTPS URL that reaches the listener; subscribes to My Tasks when set")
+	debounce := webhookFlags.Duration("debounce", 2*time.Second, "How long to collect events before sorting the changed tasks")
+	webhookFlags.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	logger := log.New(os.Stdout, "", log.LstdFlags)
+	reporter := newLogReporter(logger)
+
+	resolveCtx, cancel := context.WithTimeout(ctx, timeout)
+	taskList, err := core.ResolveTaskList(resolveCtx, client, conf, core.NopReporter{})
+	cancel()
+	if err != nil {
+		exitWithError(output.FormatText, nil, err)
+	}
+
+	queue := newTaskQueue()
+	handler := webhook.NewHandler(func(events []asana.Event) {
+		if gids := core.ChangedTaskGIDs(events); len(gids) > 0 {
+			logger.Printf("Received events for %d tasks", len(gids))
+			queue.add(gids)
+		}
+	})
+
+	// Without a subscription the handshake can come at any time, so only take it from this machine
+	if *targetURL == "" {
+		if !isLoopback(*listen) {
+			exitWithError(output.FormatText, nil, fmt.Errorf("without --target-url, --listen must be a loopback address such as 127.0.0.1:8080"))
+		}
+		handler.ExpectHandshake()
+	}
+
+	// Listen before subscribing: Asana sends the handshake while the webhook is created
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		exitWithError(output.FormatText, nil, fmt.Errorf("failed to listen on %s: %w", *listen, err))
+	}
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Printf("Webhook listener stopped: %v", err)
+			stop()
+		}
+	}()
+	logger.Printf("Listening for webhook events on %s for My Tasks in '%s'", listener.Addr(), taskList.Workspace.Name)
+	if dryRun {
+		logger.Print("Dry run: tasks won't be moved")
+	}
+
+	var subscription *asana.Webhook
+	if *targetURL != "" {
+		subscribeCtx, cancel := context.WithTimeout(ctx, timeout)
+		handshakeDone := handler.ExpectHandshake()
+		subscription, err = client.CreateWebhook(subscribeCtx, taskList.GID, *targetURL, webhookFilters)
+		handshakeDone()
+		cancel()
+		if err != nil {
+			server.Close()
+			exitWithError(output.FormatText, nil, err)
+		}
+		logger.Printf("Subscribed to My Tasks (webhook %s)", subscription.GID)
+	}
+
+	for ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+			continue
+		case <-queue.ready:
+		}
+
+		// Let a burst of events settle, so each task is fetched once
+		select {
+		case <-ctx.Done():
+		case <-time.After(*debounce):
+		}
+
+		sortChangedTasks(client, taskList, queue.take(), conf, timeout, stateDir, dryRun, logger, reporter)
+	}
+
+	logger.Print("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if subscription != nil {
+		if err := client.DeleteWebhook(shutdownCtx, subscription.GID); err != nil {
+			logger.Printf("Warning: %v", err)
+		}
+	}
+	server.Shutdown(shutdownCtx)
+}
+
+// isLoopback reports whether a listen address only accepts connections from this machine
+func isLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// sortChangedTasks sorts a set of changed tasks and logs the outcome. The run
+// isn't cancelled by a shutdown signal, so moves in flight finish.
+func sortChangedTasks(client asana.API, taskList *core.TaskList, gids []string, conf core.SectionConfig,
+	timeout time.Duration, stateDir string, dryRun bool, logger *log.Logger, reporter core.Reporter) {
+
+	if len(gids) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result, err := core.OrganizeChangedTasks(ctx, client, taskList, gids, conf, dryRun, reporter)
+	if dryRun {
+		logPlannedMoves(logger, result)
+	} else if journalErr := recordJournal(stateDir, result); journalErr != nil {
+		logger.Printf("Warning: %v", journalErr)
+	}
+	if err != nil {
+		logger.Printf("Error sorting %d changed tasks: %v", len(gids), err)
+		return
+	}
+	if dryRun {
+		logger.Printf("Checked %d changed tasks: %d would move", len(gids), len(result.Workspaces[0].PlannedMoves))
+		return
+	}
+
+	moved := 0
+	for _, moveResult := range result.Workspaces[0].MoveResults {
+		if moveResult.Err == nil {
+			moved++
+		}
+	}
+	logger.Printf("Sorted %d changed tasks: %d moved", len(gids), moved)
+}
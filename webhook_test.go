@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+	"github.com/dackerman/asana-tasks-sorter/internal/webhook"
+)
+
+// fakeSectionsAPI is an asana.API holding a My Tasks list in memory, as sections of task GIDs
+type fakeSectionsAPI struct {
+	asana.API
+
+	mu       sync.Mutex
+	sections []asana.Section
+	contents map[string][]string
+	tasks    map[string]asana.Task
+	fetched  []string
+}
+
+func (f *fakeSectionsAPI) GetSectionsForProject(ctx context.Context, projectGID string) ([]asana.Section, error) {
+	return f.sections, nil
+}
+
+func (f *fakeSectionsAPI) GetTask(ctx context.Context, taskGID string) (*asana.Task, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fetched = append(f.fetched, taskGID)
+	task, ok := f.tasks[taskGID]
+	if !ok {
+		return nil, &asana.APIError{StatusCode: http.StatusNotFound, Method: http.MethodGet, Path: "/tasks/" + taskGID}
+	}
+	return &task, nil
+}
+
+func (f *fakeSectionsAPI) GetTasksInSection(ctx context.Context, sectionGID string) ([]asana.Task, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var tasks []asana.Task
+	for _, gid := range f.contents[sectionGID] {
+		tasks = append(tasks, f.tasks[gid])
+	}
+	return tasks, nil
+}
+
+func (f *fakeSectionsAPI) MoveTaskToSection(ctx context.Context, move asana.SectionMove) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for gid, contents := range f.contents {
+		for i, taskGID := range contents {
+			if taskGID == move.TaskGID {
+				f.contents[gid] = append(contents[:i:i], contents[i+1:]...)
+				break
+			}
+		}
+	}
+	contents := f.contents[move.SectionGID]
+	position := 0
+	for i, taskGID := range contents {
+		if taskGID == move.InsertAfter {
+			position = i + 1
+		}
+		if taskGID == move.InsertBefore {
+			position = i
+		}
+	}
+	f.contents[move.SectionGID] = append(contents[:position:position], append([]string{move.TaskGID}, contents[position:]...)...)
+	task := f.tasks[move.TaskGID]
+	task.AssigneeSection = asana.AssigneeSection{GID: move.SectionGID}
+	f.tasks[move.TaskGID] = task
+	return nil
+}
+
+func TestWebhookHandlerVerifiesDeliveries(t *testing.T) {
+	var mu sync.Mutex
+	var received []asana.Event
+	handler := webhook.NewHandler(func(events []asana.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, events...)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx := context.Background()
+	standIn, err := webhook.NewStandIn(server.URL)
+	if err != nil {
+		t.Fatalf("Error creating stand-in: %v", err)
+	}
+
+	// Deliveries before the handshake can't be verified
+	if err := standIn.Send(ctx, webhook.TaskChanged("task_1", "due_on")); err == nil {
+		t.Errorf("Expected a delivery before the handshake to be rejected")
+	}
+
+	// Handshakes are only accepted while a webhook is being created
+	if err := standIn.Handshake(ctx); err == nil {
+		t.Fatalf("Expected a handshake nobody asked for to be rejected")
+	}
+	handshakeDone := handler.ExpectHandshake()
+	if err := standIn.Handshake(ctx); err != nil {
+		t.Fatalf("Handshake failed: %v", err)
+	}
+	handshakeDone()
+	if err := standIn.Send(ctx, webhook.TaskChanged("task_1", "due_on"), webhook.TaskChanged("task_2", "name")); err != nil {
+		t.Fatalf("Error sending events: %v", err)
+	}
+	mu.Lock()
+	if len(received) != 2 || received[0].Resource.GID != "task_1" {
+		t.Errorf("Expected the two events to be delivered, got %+v", received)
+	}
+	mu.Unlock()
+
+	// Deliveries signed with another secret are rejected, and so is a second
+	// handshake, even while another webhook is being created
+	impostor, _ := webhook.NewStandIn(server.URL)
+	handshakeDone = handler.ExpectHandshake()
+	if err := impostor.Handshake(ctx); err == nil {
+		t.Errorf("Expected a second handshake to be rejected")
+	}
+	handshakeDone()
+	if err := impostor.Send(ctx, webhook.TaskChanged("task_3", "due_on")); err == nil {
+		t.Errorf("Expected a delivery with a wrong signature to be rejected")
+	}
+	resp, err := http.Post(server.URL, "application/json", bytes.NewReader([]byte(`{"events":[]}`)))
+	if err != nil {
+		t.Fatalf("Error posting unsigned delivery: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected an unsigned delivery to be rejected with 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestChangedTaskGIDs(t *testing.T) {
+	events := []asana.Event{
+		webhook.TaskChanged("task_1", "due_on"),
+		webhook.TaskChanged("task_1", "name"),
+		{Action: "added", Resource: asana.EventResource{GID: "task_2", ResourceType: "task"}},
+		{Action: "removed", Resource: asana.EventResource{GID: "task_3", ResourceType: "task"}},
+		{Action: "added", Resource: asana.EventResource{GID: "story_1", ResourceType: "story"}},
+	}
+	gids := core.ChangedTaskGIDs(events)
+	if len(gids) != 2 || gids[0] != "task_1" || gids[1] != "task_2" {
+		t.Errorf("Expected task_1 and task_2, got %v", gids)
+	}
+}
+
+func TestOrganizeChangedTasks(t *testing.T) {
+	now := time.Now()
+	yesterday := asana.Date(now.AddDate(0, 0, -1))
+	config := core.DefaultSectionConfig()
+	config.BatchSize = 1
+
+	var sections []asana.Section
+	for _, name := range core.RequiredSectionNames(core.EffectiveRules(config)) {
+		sections = append(sections, asana.Section{GID: "gid_" + name, Name: name})
+	}
+	inSection := func(gid, section string, dueOn asana.Date) asana.Task {
+		return asana.Task{GID: gid, Name: gid, DueOn: dueOn, AssigneeSection: asana.AssigneeSection{GID: "gid_" + section, Name: section}}
+	}
+	client := &fakeSectionsAPI{
+		sections: sections,
+		contents: map[string][]string{
+			"gid_Overdue":           {"overdue_1", "overdue_2"},
+			"gid_Recently assigned": {"changed", "unchanged"},
+		},
+		tasks: map[string]asana.Task{
+			"overdue_1": inSection("overdue_1", "Overdue", yesterday),
+			"overdue_2": inSection("overdue_2", "Overdue", yesterday),
+			"changed":   inSection("changed", "Recently assigned", yesterday),
+			"unchanged": inSection("unchanged", "Recently assigned", yesterday),
+			"elsewhere": inSection("elsewhere", "Someone else's section", yesterday),
+		},
+	}
+	completed := inSection("completed", "Recently assigned", yesterday)
+	completed.Completed = true
+	client.tasks["completed"] = completed
+
+	taskList := &core.TaskList{GID: "list_1", Location: time.Local}
+	gids := []string{"changed", "completed", "deleted", "elsewhere"}
+	result, err := core.OrganizeChangedTasks(context.Background(), client, taskList, gids, config, false, core.NopReporter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(client.fetched) != len(gids) {
+		t.Errorf("Expected only the changed tasks to be fetched, got %v", client.fetched)
+	}
+	moves := result.Workspaces[0].MoveResults
+	if len(moves) != 1 || moves[0].Move.Task.GID != "changed" {
+		t.Fatalf("Expected only 'changed' to move, got %+v", moves)
+	}
+	overdue := client.contents["gid_Overdue"]
+	if len(overdue) != 3 || overdue[2] != "changed" {
+		t.Errorf("Expected 'changed' below the tasks already overdue, got %v", overdue)
+	}
+	if remaining := client.contents["gid_Recently assigned"]; len(remaining) != 1 || remaining[0] != "unchanged" {
+		t.Errorf("Expected the task that didn't change to stay, got %v", remaining)
+	}
+}
+
+func TestOrganizeChangedTasksDryRun(t *testing.T) {
+	yesterday := asana.Date(time.Now().AddDate(0, 0, -1))
+	config := core.DefaultSectionConfig()
+
+	// Overdue is missing, so a real run would create it
+	var sections []asana.Section
+	for _, name := range core.RequiredSectionNames(core.EffectiveRules(config)) {
+		if name != "Overdue" {
+			sections = append(sections, asana.Section{GID: "gid_" + name, Name: name})
+		}
+	}
+	changed := asana.Task{GID: "changed", Name: "changed", DueOn: yesterday,
+		AssigneeSection: asana.AssigneeSection{GID: "gid_Recently assigned", Name: "Recently assigned"}}
+	client := &fakeSectionsAPI{
+		sections: sections,
+		contents: map[string][]string{"gid_Recently assigned": {"changed"}},
+		tasks:    map[string]asana.Task{"changed": changed},
+	}
+
+	taskList := &core.TaskList{GID: "list_1", Location: time.Local}
+	result, err := core.OrganizeChangedTasks(context.Background(), client, taskList, []string{"changed"}, config, true, core.NopReporter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	workspace := result.Workspaces[0]
+	if len(workspace.PlannedMoves) != 1 || workspace.PlannedMoves[0].Task.GID != "changed" {
+		t.Errorf("Expected a move planned for 'changed', got %+v", workspace.PlannedMoves)
+	}
+	if len(workspace.SectionsToCreate) != 1 || workspace.SectionsToCreate[0] != "Overdue" {
+		t.Errorf("Expected Overdue to be listed as a section to create, got %v", workspace.SectionsToCreate)
+	}
+	if len(workspace.MoveResults) != 0 {
+		t.Errorf("Expected no moves in a dry run, got %+v", workspace.MoveResults)
+	}
+	if contents := client.contents["gid_Recently assigned"]; len(contents) != 1 {
+		t.Errorf("Expected the task to stay put, got %v", contents)
+	}
+}
+
+func TestWebhookHandshakeIsOneShot(t *testing.T) {
+	handler := webhook.NewHandler(func(events []asana.Event) {})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	ctx := context.Background()
+
+	// A handshake after the webhook was created is too late
+	handler.ExpectHandshake()()
+	late, _ := webhook.NewStandIn(server.URL)
+	if err := late.Handshake(ctx); err == nil {
+		t.Errorf("Expected a handshake after CreateWebhook returned to be rejected")
+	}
+
+	// Only the first of two handshakes racing for one expected is accepted
+	handler.ExpectHandshake()
+	first, _ := webhook.NewStandIn(server.URL)
+	second, _ := webhook.NewStandIn(server.URL)
+	if err := first.Handshake(ctx); err != nil {
+		t.Fatalf("Handshake failed: %v", err)
+	}
+	if err := second.Handshake(ctx); err == nil {
+		t.Errorf("Expected the second handshake to be rejected")
+	}
+	if err := first.Send(ctx, webhook.TaskChanged("task_1", "due_on")); err != nil {
+		t.Errorf("Expected deliveries signed with the first secret to be accepted, got %v", err)
+	}
+}
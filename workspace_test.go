@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dackerman/asana-tasks-sorter/internal/asana"
+	"github.com/dackerman/asana-tasks-sorter/internal/core"
+)
+
+func TestSelectWorkspace(t *testing.T) {
+	workspaces := []asana.Workspace{
+		{GID: "111", Name: "Acme Corp"},
+		{GID: "222", Name: "Personal"},
+		{GID: "333", Name: "personal"},
+	}
+
+	testCases := []struct {
+		name          string
+		workspaces    []asana.Workspace
+		selector      string
+		expectedGID   string
+		errorContains string
+	}{
+		{name: "Single workspace needs no selector", workspaces: workspaces[:1], expectedGID: "111"},
+		{name: "Select by GID", workspaces: workspaces, selector: "333", expectedGID: "333"},
+		{name: "Select by name ignoring case", workspaces: workspaces, selector: "acme corp", expectedGID: "111"},
+		{name: "Missing selector with several workspaces", workspaces: workspaces, errorContains: "Acme Corp (111)"},
+		{name: "Ambiguous name", workspaces: workspaces, selector: "Personal", errorContains: "ambiguous"},
+		{name: "Unknown workspace", workspaces: workspaces, selector: "Other", errorContains: "no workspace matches"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			workspace, err := core.SelectWorkspace(tc.workspaces, tc.selector)
+			if tc.errorContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errorContains) {
+					t.Fatalf("Expected error containing %q, got %v", tc.errorContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if workspace.GID != tc.expectedGID {
+				t.Errorf("Expected workspace %s, got %s", tc.expectedGID, workspace.GID)
+			}
+		})
+	}
+}